@@ -0,0 +1,47 @@
+package app
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Create
+	metaphorTemplateBranchFlag  string
+	metaphorTemplatePathFlag    string
+	metaphorTemplateURLFlag     string
+	skipTemplateCompatCheckFlag bool
+)
+
+func NewCommand() *cobra.Command {
+
+	appCmd := &cobra.Command{
+		Use:   "app",
+		Short: "kubefirst application management",
+		Long:  "kubefirst app",
+	}
+
+	// on error, doesnt show helper/usage
+	appCmd.SilenceUsage = true
+
+	// wire up new commands
+	appCmd.AddCommand(Create())
+
+	return appCmd
+}
+
+func Create() *cobra.Command {
+	createCmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "create a new application repository from a metaphor template",
+		Long:  "creates a new repository from a metaphor template with the app name substituted, adds its development, staging and production argocd applications to the gitops registry in a pull request (github) or merge request (gitlab) and creates the registry pull secrets the git provider needs",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runCreate,
+	}
+
+	createCmd.Flags().StringVar(&metaphorTemplateBranchFlag, "metaphor-template-branch", "main", "the branch to clone for the metaphor-template repository")
+	createCmd.Flags().StringVar(&metaphorTemplatePathFlag, "metaphor-template-path", "", "a local metaphor template directory or .tar.gz archive to use instead of cloning --metaphor-template-url")
+	createCmd.Flags().StringVar(&metaphorTemplateURLFlag, "metaphor-template-url", "https://github.com/kubefirst/metaphor-frontend-template.git", "the fully qualified url to the metaphor-template repository to clone")
+	createCmd.Flags().BoolVar(&skipTemplateCompatCheckFlag, "skip-template-compat-check", false, "skip checking the metaphor template compatibility manifest against this kubefirst version")
+
+	return createCmd
+}
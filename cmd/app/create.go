@@ -0,0 +1,293 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/kubefirst/kubefirst/configs"
+	"github.com/kubefirst/kubefirst/internal/civo"
+	"github.com/kubefirst/kubefirst/internal/gitClient"
+	"github.com/kubefirst/kubefirst/internal/githubWrapper"
+	gitlab "github.com/kubefirst/kubefirst/internal/gitlabcloud"
+	"github.com/kubefirst/kubefirst/internal/gitopsTemplate"
+	"github.com/kubefirst/kubefirst/internal/k3d"
+	"github.com/kubefirst/kubefirst/internal/metaphor"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func runCreate(cmd *cobra.Command, args []string) error {
+	appName := args[0]
+	err := metaphor.ValidateAppName(appName)
+	if err != nil {
+		return err
+	}
+
+	metaphorTemplateBranch, err := cmd.Flags().GetString("metaphor-template-branch")
+	if err != nil {
+		return err
+	}
+
+	metaphorTemplatePath, err := cmd.Flags().GetString("metaphor-template-path")
+	if err != nil {
+		return err
+	}
+
+	metaphorTemplateURL, err := cmd.Flags().GetString("metaphor-template-url")
+	if err != nil {
+		return err
+	}
+
+	skipTemplateCompatCheck, err := cmd.Flags().GetBool("skip-template-compat-check")
+	if err != nil {
+		return err
+	}
+
+	cloudProvider := viper.GetString("kubefirst.cloud-provider")
+	if cloudProvider == "" {
+		return errors.New("no cluster found in the kubefirst config, app create requires a cluster created by this version of kubefirst")
+	}
+	clusterName := viper.GetString("flags.cluster-name")
+	cloudRegion := viper.GetString("flags.cloud-region")
+	domainName := viper.GetString("flags.domain-name")
+
+	extraTokens, err := gitopsTemplate.ExtraTokensFromConfig(viper.GetStringSlice(gitopsTemplate.ExtraTokensConfigKey))
+	if err != nil {
+		return err
+	}
+
+	var gitProvider, gitHost, gitOwner, gitTokenEnv, containerRegistryHost string
+	switch cloudProvider {
+	case civo.CloudProvider:
+		gitProvider = civo.GitProvider
+	default:
+		gitProvider = viper.GetString("flags.git-provider")
+	}
+	switch gitProvider {
+	case "github":
+		gitHost = k3d.GithubHost
+		gitOwner = viper.GetString("flags.github-owner")
+		gitTokenEnv = "GITHUB_TOKEN"
+		containerRegistryHost = "ghcr.io"
+	case "gitlab":
+		gitHost = k3d.GitlabHost
+		gitOwner = viper.GetString("flags.gitlab-owner")
+		gitTokenEnv = "GITLAB_TOKEN"
+		containerRegistryHost = "registry.gitlab.com"
+	default:
+		return fmt.Errorf("git provider %q is not supported", gitProvider)
+	}
+	gitToken := os.Getenv(gitTokenEnv)
+	if gitToken == "" {
+		return fmt.Errorf("your %s is not set, it is required to create the app repository", gitTokenEnv)
+	}
+
+	// this branch flag value is overridden with a tag when running from a
+	// kubefirst binary for version compatibility
+	if metaphorTemplateBranch == "main" && configs.K1Version != "development" {
+		metaphorTemplateBranch = configs.K1Version
+	}
+
+	appRepoGitURL := fmt.Sprintf("git@%s:%s/%s.git", gitHost, gitOwner, appName)
+	appRepoHttpsURL := fmt.Sprintf("https://%s/%s/%s.git", gitHost, gitOwner, appName)
+	description := fmt.Sprintf("%s application created by kubefirst", appName)
+
+	//* check the app repository can be created, it is only created once the template is prepared
+	var groupID int
+	switch gitProvider {
+	case "github":
+		if githubWrapper.New().CheckRepoExists(gitOwner, appName) == 200 {
+			return fmt.Errorf("repository https://github.com/%s/%s already exists", gitOwner, appName)
+		}
+	case "gitlab":
+		gl := gitlab.GitLabWrapper{
+			Client: gitlab.NewGitLabClient(gitToken),
+		}
+		projects, err := gl.GetProjects()
+		if err != nil {
+			return err
+		}
+		found, err := gl.FindProjectInGroup(projects, appName)
+		if err != nil {
+			log.Info().Msg(err.Error())
+		}
+		if found {
+			return fmt.Errorf("project %s already exists", appName)
+		}
+		groups, err := gl.GetGroups()
+		if err != nil {
+			return err
+		}
+		groupID, err = gl.GetGroupID(groups, gitOwner)
+		if err != nil {
+			return err
+		}
+	}
+
+	workDir, err := os.MkdirTemp("", "kubefirst-app-create-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+	appDir := filepath.Join(workDir, appName)
+	gitopsDir := filepath.Join(workDir, "gitops")
+
+	//* git clone and detokenize the metaphor template as the app repository
+	log.Info().Msgf("generating your new %s repository from %s at %s", appName, metaphorTemplateURL, metaphorTemplateBranch)
+	switch cloudProvider {
+	case k3d.CloudProvider:
+		config := k3d.GetConfig(gitProvider, gitOwner)
+		err = k3d.PrepareMetaphorRepository(
+			gitProvider,
+			appRepoHttpsURL,
+			config.K1Dir,
+			appDir,
+			metaphorTemplateBranch,
+			metaphorTemplateURL,
+			metaphorTemplatePath,
+			skipTemplateCompatCheck,
+			&k3d.MetaphorTokenValues{
+				ClusterName:                   clusterName,
+				CloudRegion:                   cloudRegion,
+				ContainerRegistryURL:          fmt.Sprintf("%s/%s/%s", containerRegistryHost, gitOwner, appName),
+				DomainName:                    domainName,
+				MetaphorDevelopmentIngressURL: fmt.Sprintf("%s-development.%s", appName, domainName),
+				MetaphorStagingIngressURL:     fmt.Sprintf("%s-staging.%s", appName, domainName),
+				MetaphorProductionIngressURL:  fmt.Sprintf("%s-production.%s", appName, domainName),
+				ExtraTokens:                   extraTokens,
+			},
+		)
+	case civo.CloudProvider:
+		config := civo.GetConfig(clusterName, domainName, gitOwner)
+		err = civo.PrepareMetaphorRepository(
+			appRepoHttpsURL,
+			config.K1Dir,
+			appDir,
+			metaphorTemplateBranch,
+			metaphorTemplateURL,
+			metaphorTemplatePath,
+			skipTemplateCompatCheck,
+			&civo.MetaphorTokenValues{
+				ExtraTokens:                           extraTokens,
+				CheckoutCWFTTemplate:                  "git-checkout-with-gitops-ssh",
+				CloudRegion:                           cloudRegion,
+				ClusterName:                           clusterName,
+				CommitCWFTTemplate:                    "git-commit-ssh",
+				ContainerRegistryURL:                  fmt.Sprintf("%s/%s/%s", containerRegistryHost, gitOwner, appName),
+				DomainName:                            domainName,
+				MetaphorFrontendDevelopmentIngressURL: fmt.Sprintf("%s-development.%s", appName, domainName),
+				MetaphorFrontendProductionIngressURL:  fmt.Sprintf("%s-production.%s", appName, domainName),
+				MetaphorFrontendStagingIngressURL:     fmt.Sprintf("%s-staging.%s", appName, domainName),
+			},
+		)
+	default:
+		return fmt.Errorf("cloud provider %q is not supported", cloudProvider)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = metaphor.RenameTemplateApp(appDir, metaphor.TemplateAppName, appName)
+	if err != nil {
+		return err
+	}
+	appRepo, err := git.PlainOpen(appDir)
+	if err != nil {
+		log.Info().Msgf("error opening repo at: %s", appDir)
+		return err
+	}
+	err = gitClient.Commit(appRepo, fmt.Sprintf("renaming %s to %s", metaphor.TemplateAppName, appName))
+	if err != nil {
+		return err
+	}
+
+	//* create the app repository
+	switch gitProvider {
+	case "github":
+		err = githubWrapper.New().CreatePrivateRepo(gitOwner, appName, description, false)
+	case "gitlab":
+		gl := gitlab.GitLabWrapper{
+			Client: gitlab.NewGitLabClient(gitToken),
+		}
+		err = gl.CreateProject(appName, description, groupID)
+	}
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("created repository %s", appRepoHttpsURL)
+
+	err = gitClient.PushBranch(appRepo, gitProvider, "main", gitToken)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("pushed detokenized %s repository to %s", appName, appRepoHttpsURL)
+
+	//* registry pull secrets for the app container images
+	if gitProvider == "gitlab" {
+		gl := gitlab.GitLabWrapper{
+			Client: gitlab.NewGitLabClient(gitToken),
+		}
+		err = k3d.CreateProjectDeployTokenSecrets(&gl, k3d.GetConfig(gitProvider, gitOwner).Kubeconfig, appName)
+		if err != nil {
+			return fmt.Errorf("error creating project deploy token for project %s: %s", appName, err)
+		}
+	}
+
+	//* add the app to the gitops registry
+	gitopsRepoURL := fmt.Sprintf("https://%s/%s/gitops.git", gitHost, gitOwner)
+	gitopsRepo, err := gitClient.CloneWithToken(gitopsRepoURL, gitopsDir, gitToken)
+	if err != nil {
+		return fmt.Errorf("error cloning gitops repository %s: %s", gitopsRepoURL, err)
+	}
+	branch := fmt.Sprintf("kubefirst-app-%s", appName)
+	err = gitClient.CreateBranch(gitopsRepo, branch)
+	if err != nil {
+		return err
+	}
+	_, err = gitClient.CheckoutBranch(gitopsRepo, branch)
+	if err != nil {
+		return err
+	}
+
+	err = metaphor.WriteArgoApplications(gitopsDir, appName, clusterName, appRepoGitURL)
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("Add %s to the %s registry", appName, clusterName)
+	err = gitClient.Commit(gitopsRepo, title)
+	if err != nil {
+		return err
+	}
+	err = gitClient.PushBranch(gitopsRepo, "origin", branch, gitToken)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Deploys %s from %s to the %s namespaces.", appName, appRepoHttpsURL, strings.Join(metaphor.Environments, ", "))
+	switch gitProvider {
+	case "github":
+		pullRequest, err := githubWrapper.New().CreatePR(branch, "gitops", gitOwner, "main", title, body)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("opened pull request %s\n", pullRequest.GetHTMLURL())
+	case "gitlab":
+		gl := gitlab.GitLabWrapper{
+			Client: gitlab.NewGitLabClient(gitToken),
+		}
+		mergeRequestURL, err := gl.CreateMergeRequest("gitops", branch, "main", title, body)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("opened merge request %s\n", mergeRequestURL)
+	}
+
+	fmt.Printf("created %s, merge the gitops change to deploy it to %s\n", appRepoHttpsURL, strings.Join(metaphor.Environments, ", "))
+	return nil
+}
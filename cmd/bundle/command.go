@@ -0,0 +1,52 @@
+package bundle
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Create
+	gitopsTemplateBranchFlag   string
+	gitopsTemplateURLFlag      string
+	imagesFlag                 []string
+	includeImagesFlag          bool
+	metaphorTemplateBranchFlag string
+	metaphorTemplateURLFlag    string
+	outputFlag                 string
+)
+
+func NewCommand() *cobra.Command {
+
+	bundleCmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "offline install bundles",
+		Long:  "kubefirst bundle",
+	}
+
+	// on error, doesnt show helper/usage
+	bundleCmd.SilenceUsage = true
+
+	// wire up new commands
+	bundleCmd.AddCommand(Create())
+
+	return bundleCmd
+}
+
+func Create() *cobra.Command {
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "write everything a k3d install downloads to a single archive",
+		Long:  "downloads the tool binaries, the gitops and metaphor templates at the pinned tag, the argo-cd helm chart and optionally the platform container images into a single archive with a manifest, install from it without internet access using `kubefirst k3d create --bundle`",
+		RunE:  runCreate,
+	}
+
+	createCmd.Flags().StringVar(&gitopsTemplateBranchFlag, "gitops-template-branch", "main", "the branch or tag of the gitops-template repository to bundle")
+	createCmd.Flags().StringVar(&gitopsTemplateURLFlag, "gitops-template-url", "https://github.com/kubefirst/gitops-template.git", "the fully qualified url to the gitops-template repository to bundle")
+	createCmd.Flags().StringSliceVar(&imagesFlag, "image", []string{}, "an extra container image to bundle with --include-images (can be repeated)")
+	createCmd.Flags().BoolVar(&includeImagesFlag, "include-images", false, "save the k3d and argo-cd container images into the bundle, requires docker")
+	createCmd.Flags().StringVar(&metaphorTemplateBranchFlag, "metaphor-template-branch", "main", "the branch or tag of the metaphor-template repository to bundle")
+	createCmd.Flags().StringVar(&metaphorTemplateURLFlag, "metaphor-template-url", "https://github.com/kubefirst/metaphor-frontend-template.git", "the fully qualified url to the metaphor-template repository to bundle")
+	createCmd.Flags().StringVar(&outputFlag, "output", "kubefirst-bundle.tgz", "the path of the bundle archive to write")
+
+	return createCmd
+}
@@ -0,0 +1,108 @@
+package bundle
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kubefirst/kubefirst/configs"
+	"github.com/kubefirst/kubefirst/internal/bundle"
+	"github.com/kubefirst/kubefirst/internal/helm"
+	"github.com/kubefirst/kubefirst/internal/k3d"
+	"github.com/kubefirst/kubefirst/internal/reports"
+	"github.com/spf13/cobra"
+)
+
+func runCreate(cmd *cobra.Command, args []string) error {
+	gitopsTemplateBranchFlag, err := cmd.Flags().GetString("gitops-template-branch")
+	if err != nil {
+		return err
+	}
+
+	gitopsTemplateURLFlag, err := cmd.Flags().GetString("gitops-template-url")
+	if err != nil {
+		return err
+	}
+
+	imagesFlag, err := cmd.Flags().GetStringSlice("image")
+	if err != nil {
+		return err
+	}
+
+	includeImagesFlag, err := cmd.Flags().GetBool("include-images")
+	if err != nil {
+		return err
+	}
+
+	metaphorTemplateBranchFlag, err := cmd.Flags().GetString("metaphor-template-branch")
+	if err != nil {
+		return err
+	}
+
+	metaphorTemplateURLFlag, err := cmd.Flags().GetString("metaphor-template-url")
+	if err != nil {
+		return err
+	}
+
+	outputFlag, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+
+	if len(imagesFlag) > 0 && !includeImagesFlag {
+		return fmt.Errorf("--image requires --include-images")
+	}
+
+	// the same tags k3d create clones when running from a kubefirst binary
+	if gitopsTemplateBranchFlag == "main" && configs.K1Version != "development" {
+		gitopsTemplateBranchFlag = configs.K1Version
+	}
+	if metaphorTemplateBranchFlag == "main" && configs.K1Version != "development" {
+		metaphorTemplateBranchFlag = configs.K1Version
+	}
+
+	config := k3d.GetConfig("github", "")
+
+	manifest, err := bundle.Create(bundle.Options{
+		Output:            outputFlag,
+		LocalOs:           k3d.LocalhostOS,
+		LocalArchitecture: k3d.LocalhostARCH,
+		Tools:             k3d.Tools(config),
+		Templates: []bundle.Template{
+			{Name: "gitops", URL: gitopsTemplateURLFlag, Ref: gitopsTemplateBranchFlag},
+			{Name: "metaphor", URL: metaphorTemplateURLFlag, Ref: metaphorTemplateBranchFlag},
+		},
+		Charts: []helm.HelmRepo{
+			{
+				RepoName:     "argo",
+				RepoURL:      k3d.ArgocdHelmRepoURL,
+				ChartName:    "argo-cd",
+				Namespace:    "argocd",
+				ChartVersion: k3d.ArgocdHelmChartVersion,
+			},
+		},
+		IncludeImages: includeImagesFlag,
+		Images:        imagesFlag,
+	})
+	if err != nil {
+		return err
+	}
+
+	var summary bytes.Buffer
+	summary.WriteString(fmt.Sprintf("bundle written to %s for %s/%s\n\n", outputFlag, manifest.OS, manifest.Arch))
+	for _, tool := range manifest.Tools {
+		summary.WriteString(fmt.Sprintf("tool:     %s %s\n", tool.Name, tool.Version))
+	}
+	for _, template := range manifest.Templates {
+		summary.WriteString(fmt.Sprintf("template: %s %s@%s\n", template.Name, template.URL, template.Ref))
+	}
+	for _, chart := range manifest.Charts {
+		summary.WriteString(fmt.Sprintf("chart:    %s %s\n", chart.Name, chart.Version))
+	}
+	for _, archive := range manifest.Images {
+		summary.WriteString(fmt.Sprintf("images:   %d %s images\n", len(archive.Images), archive.Name))
+	}
+	summary.WriteString(fmt.Sprintf("\ninstall with `kubefirst k3d create --bundle %s`", outputFlag))
+
+	fmt.Println(reports.StyleMessage(summary.String()))
+	return nil
+}
@@ -1,24 +1,34 @@
 package civo
 
 import (
+	"fmt"
+
+	"github.com/kubefirst/kubefirst/internal/terraform"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Create
-	alertsEmailFlag            string
-	cloudRegionFlag            string
-	clusterNameFlag            string
-	clusterTypeFlag            string
-	dryRun                     bool
-	githubOwnerFlag            string
-	gitopsTemplateURLFlag      string
-	gitopsTemplateBranchFlag   string
-	metaphorTemplateBranchFlag string
-	metaphorTemplateURLFlag    string
-	domainNameFlag             string
-	kbotPasswordFlag           string
-	useTelemetryFlag           bool
+	alertsEmailFlag             string
+	cloudRegionFlag             string
+	clusterNameFlag             string
+	clusterTypeFlag             string
+	dryRun                      bool
+	githubOwnerFlag             string
+	gitopsTemplateURLFlag       string
+	gitopsTemplateBranchFlag    string
+	gitopsTemplatePathFlag      string
+	metaphorTemplateBranchFlag  string
+	metaphorTemplatePathFlag    string
+	metaphorTemplateURLFlag     string
+	domainNameFlag              string
+	kbotPasswordFlag            string
+	setFlag                     []string
+	skipTemplateCompatCheckFlag bool
+	terraformApprovalFlag       string
+	tokenValuesFlag             string
+	useSystemToolsFlag          bool
+	useTelemetryFlag            bool
 
 	// Quota
 	quotaShowAllFlag bool
@@ -73,9 +83,16 @@ func Create() *cobra.Command {
 	createCmd.MarkFlagRequired("github-owner")
 	createCmd.Flags().StringVar(&gitopsTemplateBranchFlag, "gitops-template-branch", "main", "the branch to clone for the gitops-template repository")
 	createCmd.Flags().StringVar(&gitopsTemplateURLFlag, "gitops-template-url", "https://github.com/kubefirst/gitops-template.git", "the fully qualified url to the gitops-template repository to clone")
+	createCmd.Flags().StringVar(&gitopsTemplatePathFlag, "gitops-template-path", "", "a local gitops-template directory or .tar.gz archive to use instead of cloning --gitops-template-url")
 	createCmd.Flags().StringVar(&kbotPasswordFlag, "kbot-password", "", "the default password to use for the kbot user")
 	createCmd.Flags().StringVar(&metaphorTemplateBranchFlag, "metaphor-template-branch", "main", "the branch to clone for the metaphor-template repository")
 	createCmd.Flags().StringVar(&metaphorTemplateURLFlag, "metaphor-template-url", "https://github.com/kubefirst/metaphor-frontend-template.git", "the fully qualified url to the metaphor-template repository to clone")
+	createCmd.Flags().StringVar(&metaphorTemplatePathFlag, "metaphor-template-path", "", "a local metaphor-template directory or .tar.gz archive to use instead of cloning --metaphor-template-url")
+	createCmd.Flags().BoolVar(&skipTemplateCompatCheckFlag, "skip-template-compat-check", false, "skip checking the gitops template layout and compatibility manifest against this kubefirst version")
+	createCmd.Flags().StringArrayVar(&setFlag, "set", []string{}, "an extra template token to detokenize as KEY=VALUE, replaces <KEY> in the gitops and metaphor templates (can be repeated)")
+	createCmd.Flags().StringVar(&terraformApprovalFlag, "terraform-approval", terraform.ApprovalAuto, fmt.Sprintf("how terraform changes are approved - one of: %s. plan-only saves the plan under ~/.k1/terraform-plans and stops, a later prompt run applies the saved plan and an auto run discards it", terraform.ApprovalModes))
+	createCmd.Flags().StringVar(&tokenValuesFlag, "token-values", "", "a yaml file of extra template tokens to detokenize as KEY: VALUE pairs")
+	createCmd.Flags().BoolVar(&useSystemToolsFlag, "use-system-tools", false, "use helm, kubectl, terraform and other tools found on PATH when their version is supported instead of downloading them")
 	createCmd.Flags().BoolVar(&useTelemetryFlag, "use-telemetry", true, "whether to emit telemetry")
 
 	return createCmd
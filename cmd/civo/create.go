@@ -1,10 +1,10 @@
 package civo
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"time"
 
@@ -18,9 +18,11 @@ import (
 	"github.com/kubefirst/kubefirst/internal/civo"
 	"github.com/kubefirst/kubefirst/internal/downloadManager"
 	"github.com/kubefirst/kubefirst/internal/gitClient"
+	"github.com/kubefirst/kubefirst/internal/gitopsTemplate"
 	"github.com/kubefirst/kubefirst/internal/githubWrapper"
 	"github.com/kubefirst/kubefirst/internal/handlers"
 	"github.com/kubefirst/kubefirst/internal/helm"
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/internal/k8s"
 	"github.com/kubefirst/kubefirst/internal/progressPrinter"
 	"github.com/kubefirst/kubefirst/internal/reports"
@@ -86,6 +88,11 @@ func createCivo(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	gitopsTemplatePathFlag, err := cmd.Flags().GetString("gitops-template-path")
+	if err != nil {
+		return err
+	}
+
 	kbotPasswordFlag, err := cmd.Flags().GetString("kbot-password")
 	if err != nil {
 		return err
@@ -101,12 +108,59 @@ func createCivo(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	metaphorTemplatePathFlag, err := cmd.Flags().GetString("metaphor-template-path")
+	if err != nil {
+		return err
+	}
+
+	skipTemplateCompatCheckFlag, err := cmd.Flags().GetBool("skip-template-compat-check")
+	if err != nil {
+		return err
+	}
+
+	setFlag, err := cmd.Flags().GetStringArray("set")
+	if err != nil {
+		return err
+	}
+
+	terraformApprovalFlag, err := cmd.Flags().GetString("terraform-approval")
+	if err != nil {
+		return err
+	}
+	err = terraform.ValidateApprovalMode(terraformApprovalFlag)
+	if err != nil {
+		return err
+	}
+
+	tokenValuesFlag, err := cmd.Flags().GetString("token-values")
+	if err != nil {
+		return err
+	}
+
+	useSystemToolsFlag, err := cmd.Flags().GetBool("use-system-tools")
+	if err != nil {
+		return err
+	}
+
 	useTelemetryFlag, err := cmd.Flags().GetBool("use-telemetry")
 	if err != nil {
 		return err
 	}
 
+	// user defined tokens from a previous run are reused when none are provided
+	extraTokens, err := gitopsTemplate.ParseExtraTokens(setFlag, tokenValuesFlag)
+	if err != nil {
+		return err
+	}
+	if len(extraTokens) == 0 {
+		extraTokens, err = gitopsTemplate.ExtraTokensFromConfig(viper.GetStringSlice(gitopsTemplate.ExtraTokensConfigKey))
+		if err != nil {
+			return err
+		}
+	}
+
 	// required for destroy command
+	viper.Set(gitopsTemplate.ExtraTokensConfigKey, gitopsTemplate.ExtraTokensToConfig(extraTokens))
 	viper.Set("flags.alerts-email", alertsEmailFlag)
 	viper.Set("flags.cluster-name", clusterNameFlag)
 	viper.Set("flags.domain-name", domainNameFlag)
@@ -139,6 +193,7 @@ func createCivo(cmd *cobra.Command, args []string) error {
 	}
 
 	gitopsDirectoryTokens := civo.GitOpsDirectoryValues{
+		ExtraTokens:                    extraTokens,
 		AlertsEmail:                    alertsEmailFlag,
 		AtlantisAllowList:              fmt.Sprintf("github.com/%s/gitops", githubOwnerFlag),
 		CloudProvider:                  civo.CloudProvider,
@@ -199,6 +254,12 @@ func createCivo(cmd *cobra.Command, args []string) error {
 	log.Info().Msgf("kubefirst version configs.K1Version: %s ", configs.K1Version)
 	log.Info().Msgf("cloning gitops-template repo url: %s ", gitopsTemplateURLFlag)
 	log.Info().Msgf("cloning gitops-template repo branch: %s ", gitopsTemplateBranchFlag)
+	if gitopsTemplatePathFlag != "" {
+		log.Info().Msgf("using local gitops-template instead of cloning: %s ", gitopsTemplatePathFlag)
+		if _, err := os.Stat(gitopsTemplatePathFlag); err != nil {
+			return fmt.Errorf("unable to read local gitops-template %s: %s", gitopsTemplatePathFlag, err)
+		}
+	}
 	// this branch flag value is overridden with a tag when running from a
 	// kubefirst binary for version compatibility
 	if metaphorTemplateBranchFlag == "main" && configs.K1Version != "development" {
@@ -207,10 +268,17 @@ func createCivo(cmd *cobra.Command, args []string) error {
 
 	log.Info().Msgf("cloning metaphor template url: %s ", metaphorTemplateURLFlag)
 	log.Info().Msgf("cloning metaphor template branch: %s ", metaphorTemplateBranchFlag)
+	if metaphorTemplatePathFlag != "" {
+		log.Info().Msgf("using local metaphor template instead of cloning: %s ", metaphorTemplatePathFlag)
+		if _, err := os.Stat(metaphorTemplatePathFlag); err != nil {
+			return fmt.Errorf("unable to read local metaphor template %s: %s", metaphorTemplatePathFlag, err)
+		}
+	}
 
 	atlantisWebhookSecret := viper.GetString("secrets.atlantis-webhook")
 	if atlantisWebhookSecret == "" {
 		atlantisWebhookSecret = pkg.Random(20)
+		pkg.RegisterSecret(atlantisWebhookSecret)
 		viper.Set("secrets.atlantis-webhook", atlantisWebhookSecret)
 		viper.WriteConfig()
 	}
@@ -245,6 +313,7 @@ func createCivo(cmd *cobra.Command, args []string) error {
 			log.Info().Msg(err.Error())
 		}
 		viper.Set("kubefirst.state-store-creds.access-key-id", creds.AccessKeyID)
+		pkg.RegisterSecret(creds.SecretAccessKeyID)
 		viper.Set("kubefirst.state-store-creds.secret-access-key-id", creds.SecretAccessKeyID)
 		viper.Set("kubefirst.state-store-creds.name", creds.Name)
 		viper.Set("kubefirst.state-store-creds.id", creds.ID)
@@ -328,7 +397,7 @@ func createCivo(cmd *cobra.Command, args []string) error {
 	executionControl = viper.GetBool("kubefirst-checks.github-credentials")
 	if !executionControl {
 
-		httpClient := http.DefaultClient
+		httpClient := httpCommon.NewClient()
 		githubToken := os.Getenv("GITHUB_TOKEN")
 		if len(githubToken) == 0 {
 			return errors.New("please set a GITHUB_TOKEN environment variable to continue\n https://docs.kubefirst.io/kubefirst/github/install.html#step-3-kubefirst-init")
@@ -432,6 +501,7 @@ func createCivo(cmd *cobra.Command, args []string) error {
 		}
 		log.Info().Msg("ssh key pair creation complete")
 
+		pkg.RegisterSecret(kbotPasswordFlag, sshPrivateKey)
 		viper.Set("kbot.password", kbotPasswordFlag)
 		viper.Set("kbot.private-key", sshPrivateKey)
 		viper.Set("kbot.public-key", sshPublicKey)
@@ -478,16 +548,7 @@ func createCivo(cmd *cobra.Command, args []string) error {
 	if !viper.GetBool("kubefirst-checks.tools-downloaded") {
 		log.Info().Msg("installing kubefirst dependencies")
 
-		err := downloadManager.CivoDownloadTools(
-			config.HelmClient,
-			civo.HelmClientVersion,
-			config.KubectlClient,
-			civo.KubectlClientVersion,
-			civo.LocalhostOS,
-			civo.LocalhostArch,
-			civo.TerraformClientVersion,
-			config.ToolsDir,
-		)
+		err := downloadManager.InstallTools(config.ToolsDir, civo.Tools(config), civo.LocalhostOS, civo.LocalhostArch, useSystemToolsFlag)
 		if err != nil {
 			return err
 		}
@@ -501,18 +562,46 @@ func createCivo(cmd *cobra.Command, args []string) error {
 		progressPrinter.IncrementTracker("platform-create", 1)
 	}
 
+	// required for gitops upgrade and app create commands
+	gitopsTemplateTokensJSON, err := json.Marshal(gitopsDirectoryTokens)
+	if err != nil {
+		return err
+	}
+	viper.Set("kubefirst.cloud-provider", civo.CloudProvider)
+	viper.Set("kubefirst.gitops-template-tokens", string(gitopsTemplateTokensJSON))
+	viper.Set("flags.cloud-region", cloudRegionFlag)
+	viper.Set("flags.cluster-type", clusterTypeFlag)
+	viper.Set("flags.gitops-template-branch", gitopsTemplateBranchFlag)
+	viper.Set("flags.gitops-template-path", gitopsTemplatePathFlag)
+	viper.Set("flags.gitops-template-url", gitopsTemplateURLFlag)
+	viper.WriteConfig()
+
 	//* git clone and detokenize the gitops repository
 	// todo improve this logic for removing `kubefirst clean`
 	// if !viper.GetBool("template-repo.gitops.cloned") || viper.GetBool("template-repo.gitops.removed") {
 	if !viper.GetBool("kubefirst-checks.gitops-ready-to-push") {
 
 		log.Info().Msg("generating your new gitops repository")
-		gitopsRepo, err := gitClient.CloneRefSetMain(gitopsTemplateBranchFlag, config.GitopsDir, gitopsTemplateURLFlag)
+		gitopsRepo, err := gitClient.CloneOrInitRepo(gitopsTemplatePathFlag, gitopsTemplateBranchFlag, config.GitopsDir, gitopsTemplateURLFlag)
 		if err != nil {
 			log.Info().Msgf("error opening repo at: %s", config.GitopsDir)
+			return err
 		}
 		log.Info().Msg("gitops repository clone complete")
 
+		if !skipTemplateCompatCheckFlag {
+			err = gitopsTemplate.CheckTemplateCompatibility(config.GitopsDir, gitopsTemplate.LintOptions{
+				CloudProvider:    civo.CloudProvider,
+				GitProvider:      civo.GitProvider,
+				ClusterType:      clusterTypeFlag,
+				KubefirstVersion: configs.K1Version,
+			}, extraTokens)
+			if err != nil {
+				os.RemoveAll(config.GitopsDir)
+				return err
+			}
+		}
+
 		err = civo.CivoGithubAdjustGitopsTemplateContent(civo.CloudProvider, clusterNameFlag, clusterTypeFlag, civo.GitProvider, config.K1Dir, config.GitopsDir)
 		if err != nil {
 			return err
@@ -549,9 +638,14 @@ func createCivo(cmd *cobra.Command, args []string) error {
 		tfEntrypoint := config.GitopsDir + "/terraform/github"
 		tfEnvs := map[string]string{}
 		tfEnvs = civo.GetGithubTerraformEnvs(tfEnvs)
-		err := terraform.InitApplyAutoApprove(dryRunFlag, tfEntrypoint, tfEnvs)
+		err := terraform.InitApplyWithApproval(dryRunFlag, tfEntrypoint, tfEnvs, terraformApprovalFlag)
+		if errors.Is(err, terraform.ErrPlanSaved) {
+			fmt.Println(err.Error())
+			return nil
+		}
 		if err != nil {
-			return errors.New(fmt.Sprintf("error creating github resources with terraform %s : %s", tfEntrypoint, err))
+			reports.TerraformErrorSummary(err)
+			return fmt.Errorf("error creating github resources with terraform %s : %w", tfEntrypoint, err)
 		}
 
 		log.Info().Msgf("Created git repositories and teams in github.com/%s", githubOwnerFlag)
@@ -592,6 +686,7 @@ func createCivo(cmd *cobra.Command, args []string) error {
 	}
 
 	metaphorTemplateTokens := civo.MetaphorTokenValues{
+		ExtraTokens:                           extraTokens,
 		CheckoutCWFTTemplate:                  "git-checkout-with-gitops-ssh",
 		CloudRegion:                           cloudRegionFlag,
 		ClusterName:                           clusterNameFlag,
@@ -611,29 +706,23 @@ func createCivo(cmd *cobra.Command, args []string) error {
 		}
 
 		log.Info().Msg("generating your new metaphor-frontend repository")
-		metaphorRepo, err := gitClient.CloneRefSetMain(metaphorTemplateBranchFlag, config.MetaphorDir, metaphorTemplateURLFlag)
-		if err != nil {
-			log.Info().Msgf("error opening repo at: %s", config.MetaphorDir)
-		}
-
-		log.Info().Msg("metaphor repository clone complete")
-
-		err = civo.CivoGithubAdjustMetaphorTemplateContent(civo.GitProvider, config.K1Dir, config.MetaphorDir)
-		if err != nil {
-			return err
-		}
-
-		err = civo.DetokenizeCivoGithubMetaphor(config.MetaphorDir, &metaphorTemplateTokens)
-		if err != nil {
-			return err
-		}
-		err = gitClient.AddRemote(config.DestinationMetaphorRepoGitURL, civo.GitProvider, metaphorRepo)
+		err := civo.PrepareMetaphorRepository(
+			config.DestinationMetaphorRepoGitURL,
+			config.K1Dir,
+			config.MetaphorDir,
+			metaphorTemplateBranchFlag,
+			metaphorTemplateURLFlag,
+			metaphorTemplatePathFlag,
+			skipTemplateCompatCheckFlag,
+			&metaphorTemplateTokens,
+		)
 		if err != nil {
 			return err
 		}
 
-		err = gitClient.Commit(metaphorRepo, "committing detokenized metaphor-frontend-template repo content")
+		metaphorRepo, err := git.PlainOpen(config.MetaphorDir)
 		if err != nil {
+			log.Info().Msgf("error opening repo at: %s", config.MetaphorDir)
 			return err
 		}
 
@@ -665,9 +754,14 @@ func createCivo(cmd *cobra.Command, args []string) error {
 		tfEntrypoint := config.GitopsDir + "/terraform/civo"
 		tfEnvs := map[string]string{}
 		tfEnvs = civo.GetCivoTerraformEnvs(tfEnvs)
-		err := terraform.InitApplyAutoApprove(dryRunFlag, tfEntrypoint, tfEnvs)
+		err := terraform.InitApplyWithApproval(dryRunFlag, tfEntrypoint, tfEnvs, terraformApprovalFlag)
+		if errors.Is(err, terraform.ErrPlanSaved) {
+			fmt.Println(err.Error())
+			return nil
+		}
 		if err != nil {
-			return errors.New(fmt.Sprintf("error creating civo resources with terraform %s : %s", tfEntrypoint, err))
+			reports.TerraformErrorSummary(err)
+			return fmt.Errorf("error creating civo resources with terraform %s : %w", tfEntrypoint, err)
 		}
 
 		log.Info().Msg("Created civo cloud resources")
@@ -804,6 +898,7 @@ func createCivo(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
+		pkg.RegisterSecret(argocdPassword)
 		viper.Set("components.argocd.password", argocdPassword)
 		viper.Set("components.argocd.username", "admin")
 		viper.WriteConfig()
@@ -818,6 +913,7 @@ func createCivo(cmd *cobra.Command, args []string) error {
 
 		log.Info().Msg("argocd admin auth token set")
 
+		pkg.RegisterSecret(token)
 		viper.Set("components.argocd.auth-token", token)
 		viper.Set("kubefirst-checks.argocd-credentials-set", true)
 		viper.WriteConfig()
@@ -909,8 +1005,13 @@ func createCivo(cmd *cobra.Command, args []string) error {
 		tfEnvs = civo.GetVaultTerraformEnvs(config, tfEnvs)
 		tfEnvs = civo.GetCivoTerraformEnvs(tfEnvs)
 		tfEntrypoint := config.GitopsDir + "/terraform/vault"
-		err := terraform.InitApplyAutoApprove(dryRunFlag, tfEntrypoint, tfEnvs)
+		err := terraform.InitApplyWithApproval(dryRunFlag, tfEntrypoint, tfEnvs, terraformApprovalFlag)
+		if errors.Is(err, terraform.ErrPlanSaved) {
+			fmt.Println(err.Error())
+			return nil
+		}
 		if err != nil {
+			reports.TerraformErrorSummary(err)
 			return err
 		}
 
@@ -932,8 +1033,13 @@ func createCivo(cmd *cobra.Command, args []string) error {
 		tfEnvs = civo.GetCivoTerraformEnvs(tfEnvs)
 		tfEnvs = civo.GetUsersTerraformEnvs(config, tfEnvs)
 		tfEntrypoint := config.GitopsDir + "/terraform/users"
-		err := terraform.InitApplyAutoApprove(dryRunFlag, tfEntrypoint, tfEnvs)
+		err := terraform.InitApplyWithApproval(dryRunFlag, tfEntrypoint, tfEnvs, terraformApprovalFlag)
+		if errors.Is(err, terraform.ErrPlanSaved) {
+			fmt.Println(err.Error())
+			return nil
+		}
 		if err != nil {
+			reports.TerraformErrorSummary(err)
 			return err
 		}
 		log.Info().Msg("executed users terraform successfully")
@@ -1,7 +1,6 @@
 package civo
 
 import (
-	"crypto/tls"
 	"errors"
 	"net/http"
 	"os"
@@ -9,6 +8,7 @@ import (
 	"github.com/civo/civogo"
 	"github.com/kubefirst/kubefirst/internal/argocd"
 	"github.com/kubefirst/kubefirst/internal/civo"
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/internal/k8s"
 	"github.com/kubefirst/kubefirst/internal/terraform"
 	"github.com/kubefirst/kubefirst/pkg"
@@ -103,9 +103,7 @@ func destroyCivo(cmd *cobra.Command, args []string) error {
 
 		log.Info().Msgf("port-forward to argocd is available at %s", civo.ArgocdPortForwardURL)
 
-		customTransport := http.DefaultTransport.(*http.Transport).Clone()
-		customTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-		argocdHttpClient := http.Client{Transport: customTransport}
+		argocdHttpClient := http.Client{Transport: httpCommon.NewTransport(true)}
 		log.Info().Msg("deleting the registry application")
 		httpCode, _, err := argocd.DeleteApplication(&argocdHttpClient, config.RegistryAppName, argocdAuthToken, "true")
 		if err != nil {
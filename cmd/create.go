@@ -3,9 +3,9 @@ package cmd
 import (
 	"errors"
 	"fmt"
-	"net/http"
 	"os"
 
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/internal/wrappers"
 
 	"time"
@@ -82,7 +82,7 @@ cluster provisioning process spinning up the services, and validates the livenes
 			}
 		}
 
-		httpClient := http.DefaultClient
+		httpClient := httpCommon.NewClient()
 		gitHubService := services.NewGitHubService(httpClient)
 		gitHubHandler := handlers.NewGitHubHandler(gitHubService)
 
@@ -4,7 +4,6 @@ Copyright © 2022 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"crypto/tls"
 	"fmt"
 	"net/http"
 	"os"
@@ -18,6 +17,7 @@ import (
 	"github.com/kubefirst/kubefirst/internal/flagset"
 	"github.com/kubefirst/kubefirst/internal/gitClient"
 	"github.com/kubefirst/kubefirst/internal/helm"
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/internal/k8s"
 	"github.com/kubefirst/kubefirst/internal/progressPrinter"
 	"github.com/kubefirst/kubefirst/internal/terraform"
@@ -143,9 +143,7 @@ var createGithubCmd = &cobra.Command{
 			log.Printf("[#99] Dry-run mode, Sync ArgoCD skipped")
 		} else {
 			// todo: create ArgoCD struct, and host dependencies (like http client)
-			customTransport := http.DefaultTransport.(*http.Transport).Clone()
-			customTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-			httpClient := http.Client{Transport: customTransport}
+			httpClient := http.Client{Transport: httpCommon.NewTransport(true)}
 
 			// retry to sync ArgoCD application until reaches the maximum attempts
 			argoCDIsReady, err := argocd.SyncRetry(&httpClient, 20, 5, "registry", token)
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"crypto/tls"
 	"fmt"
 	"net/http"
 	"os/exec"
@@ -15,6 +14,7 @@ import (
 	"github.com/kubefirst/kubefirst/internal/flagset"
 	"github.com/kubefirst/kubefirst/internal/gitlab"
 	"github.com/kubefirst/kubefirst/internal/helm"
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/internal/k8s"
 	"github.com/kubefirst/kubefirst/internal/progressPrinter"
 	"github.com/kubefirst/kubefirst/internal/softserve"
@@ -321,9 +321,7 @@ var createGitlabCmd = &cobra.Command{
 				log.Info().Msg("[#99] Dry-run mode, Sync ArgoCD skipped")
 			} else {
 				// todo: create ArgoCD struct, and host dependencies (like http client)
-				customTransport := http.DefaultTransport.(*http.Transport).Clone()
-				customTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-				httpClient := http.Client{Transport: customTransport}
+				httpClient := http.Client{Transport: httpCommon.NewTransport(true)}
 
 				// retry to sync ArgoCD application until reaches the maximum attempts
 				argoCDIsReady, err := argocd.SyncRetry(&httpClient, 120, 5, "registry", token)
@@ -13,6 +13,7 @@ import (
 
 	"github.com/kubefirst/kubefirst/configs"
 	"github.com/kubefirst/kubefirst/internal/argocd"
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/internal/k8s"
 	"github.com/kubefirst/kubefirst/internal/progressPrinter"
 	"github.com/kubefirst/kubefirst/pkg"
@@ -149,7 +150,7 @@ func loopUntilPodIsReady(dryRun bool, kubeconfigPath, kubectlClientPath string)
 
 			req.Header.Add("Content-Type", "application/json")
 
-			res, err := http.DefaultClient.Do(req)
+			res, err := httpCommon.NewClient().Do(req)
 			if err != nil {
 				log.Error().Err(err).Msg("error with http request Do, vault is not available")
 				// todo: temporary code
@@ -237,7 +238,7 @@ func initializeVaultAndAutoUnseal(dryRun bool) {
 
 		req.Header.Add("Content-Type", "application/json")
 
-		res, err := http.DefaultClient.Do(req)
+		res, err := httpCommon.NewClient().Do(req)
 		if err != nil {
 			log.Error().Err(err).Msg("error in Do http client request")
 		}
@@ -4,7 +4,6 @@ Copyright © 2022 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/http"
@@ -20,6 +19,7 @@ import (
 	"github.com/kubefirst/kubefirst/internal/gitClient"
 	"github.com/kubefirst/kubefirst/internal/githubWrapper"
 	"github.com/kubefirst/kubefirst/internal/handlers"
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/internal/k8s"
 	"github.com/kubefirst/kubefirst/internal/progressPrinter"
 	"github.com/kubefirst/kubefirst/internal/services"
@@ -58,7 +58,7 @@ var destroyAwsGithubCmd = &cobra.Command{
 		}
 
 		gitHubAccessToken := config.GithubToken
-		httpClient := http.DefaultClient
+		httpClient := httpCommon.NewClient()
 		gitHubService := services.NewGitHubService(httpClient)
 		gitHubHandler := handlers.NewGitHubHandler(gitHubService)
 
@@ -125,9 +125,7 @@ var destroyAwsGithubCmd = &cobra.Command{
 			}
 
 			log.Info().Msg("syncing argocd registry application")
-			customTransport := http.DefaultTransport.(*http.Transport).Clone()
-			customTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-			argocdHttpClient := http.Client{Transport: customTransport}
+			argocdHttpClient := http.Client{Transport: httpCommon.NewTransport(true)}
 			log.Info().Msg("refreshing the registry application")
 			argocd.RefreshApplication(&argocdHttpClient, "registry", token)
 			log.Info().Msg("listing the applications after refresh, sleeping 15 seconds")
@@ -0,0 +1,25 @@
+package drift
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	entrypointsFlag []string
+)
+
+func NewCommand() *cobra.Command {
+	driftCmd := &cobra.Command{
+		Use:   "drift",
+		Short: "detect changes between the gitops terraform and the real infrastructure",
+		Long:  "runs terraform plan -detailed-exitcode for every terraform entrypoint applied by create, with the same port-forwards and environment, prints a drift report per entrypoint and exits non-zero when drift is found or an entrypoint could not be checked",
+		RunE:  runDrift,
+	}
+
+	// on error, doesnt show helper/usage
+	driftCmd.SilenceUsage = true
+
+	driftCmd.Flags().StringSliceVar(&entrypointsFlag, "entrypoint", []string{}, "only check these entrypoints (i.e. github,vault), defaults to every installed entrypoint")
+
+	return driftCmd
+}
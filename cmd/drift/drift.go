@@ -0,0 +1,209 @@
+package drift
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/kubefirst/kubefirst/internal/civo"
+	gitlab "github.com/kubefirst/kubefirst/internal/gitlabcloud"
+	"github.com/kubefirst/kubefirst/internal/k3d"
+	"github.com/kubefirst/kubefirst/internal/k8s"
+	"github.com/kubefirst/kubefirst/internal/reports"
+	"github.com/kubefirst/kubefirst/internal/terraform"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// driftEntrypoint is a terraform entrypoint applied by create and how to build its environment
+type driftEntrypoint struct {
+	name       string
+	needsVault bool
+	envs       func() (map[string]string, error)
+}
+
+func runDrift(cmd *cobra.Command, args []string) error {
+	entrypointNames, err := cmd.Flags().GetStringSlice("entrypoint")
+	if err != nil {
+		return err
+	}
+
+	cloudProvider := viper.GetString("kubefirst.cloud-provider")
+	clusterName := viper.GetString("flags.cluster-name")
+	gitProvider := viper.GetString("flags.git-provider")
+
+	var entrypoints []driftEntrypoint
+	var gitopsDir, kubeconfig string
+	switch cloudProvider {
+	case "k3d":
+		gitOwner := viper.GetString(fmt.Sprintf("flags.%s-owner", gitProvider))
+		config := k3d.GetConfig(gitProvider, gitOwner)
+		gitopsDir, kubeconfig = config.GitopsDir, config.Kubeconfig
+		entrypoints = k3dEntrypoints(config, gitOwner)
+	case "civo":
+		config := civo.GetConfig(clusterName, viper.GetString("flags.domain-name"), viper.GetString("flags.github-owner"))
+		gitopsDir, kubeconfig = config.GitopsDir, config.Kubeconfig
+		entrypoints = civoEntrypoints(config)
+	case "":
+		return errors.New("no cloud provider found in the kubefirst config, drift requires a cluster created by this version of kubefirst")
+	default:
+		return fmt.Errorf("drift is not supported for cloud provider %s", cloudProvider)
+	}
+
+	entrypoints, err = installedEntrypoints(entrypoints, entrypointNames)
+	if err != nil {
+		return err
+	}
+
+	// the port-forwards use the local ports the cluster was created with
+	portForwards := k3d.ReadPortForwards()
+
+	// the k3d terraform state is stored in the in-cluster minio
+	if cloudProvider == "k3d" {
+		minioStopChannel := make(chan struct{}, 1)
+		defer func() {
+			close(minioStopChannel)
+		}()
+		k8s.OpenPortForwardPodWrapper(kubeconfig, "minio", "minio", 9000, portForwards.Minio, minioStopChannel)
+	}
+
+	needsVault := false
+	for _, entrypoint := range entrypoints {
+		needsVault = needsVault || entrypoint.needsVault
+	}
+	if needsVault {
+		vaultStopChannel := make(chan struct{}, 1)
+		defer func() {
+			close(vaultStopChannel)
+		}()
+		k8s.OpenPortForwardPodWrapper(kubeconfig, "vault-0", "vault", 8200, portForwards.Vault, vaultStopChannel)
+	}
+
+	results := []terraform.DriftResult{}
+	for _, entrypoint := range entrypoints {
+		tfEntrypoint := fmt.Sprintf("%s/terraform/%s", gitopsDir, entrypoint.name)
+		log.Info().Msgf("checking %s for drift", tfEntrypoint)
+
+		tfEnvs, err := entrypoint.envs()
+		if err != nil {
+			results = append(results, terraform.DriftResult{Entrypoint: tfEntrypoint, Err: err})
+			continue
+		}
+		results = append(results, terraform.PlanDrift(tfEntrypoint, tfEnvs))
+	}
+
+	report := reports.BuildDriftReport(results)
+	fmt.Println(reports.StyleMessage(report.String()))
+
+	drifted, failed := 0, 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		} else if result.Drifted {
+			drifted++
+		}
+	}
+	if drifted > 0 || failed > 0 {
+		return fmt.Errorf("drift detected in %d of %d entrypoints, %d could not be checked", drifted, len(results), failed)
+	}
+
+	return nil
+}
+
+// installedEntrypoints keeps the entrypoints create has applied, limited to names when provided
+func installedEntrypoints(entrypoints []driftEntrypoint, names []string) ([]driftEntrypoint, error) {
+	requested := map[string]bool{}
+	for _, name := range names {
+		requested[name] = true
+	}
+
+	installed := []driftEntrypoint{}
+	for _, entrypoint := range entrypoints {
+		if len(requested) > 0 && !requested[entrypoint.name] {
+			continue
+		}
+		delete(requested, entrypoint.name)
+		if !viper.GetBool(fmt.Sprintf("kubefirst-checks.terraform-apply-%s", entrypoint.name)) {
+			log.Info().Msgf("skipping terraform entrypoint %s, it has not been applied", entrypoint.name)
+			continue
+		}
+		installed = append(installed, entrypoint)
+	}
+
+	for name := range requested {
+		return nil, fmt.Errorf("unknown terraform entrypoint %s", name)
+	}
+	if len(installed) == 0 {
+		return nil, errors.New("no applied terraform entrypoints found in the kubefirst config")
+	}
+
+	return installed, nil
+}
+
+func k3dEntrypoints(config *k3d.K3dConfig, gitOwner string) []driftEntrypoint {
+	// gitlab entrypoints need the id of the owner group
+	gitlabGroupID := func() (int, error) {
+		gl := gitlab.GitLabWrapper{
+			Client: gitlab.NewGitLabClient(os.Getenv("GITLAB_TOKEN")),
+		}
+		allgroups, err := gl.GetGroups()
+		if err != nil {
+			return 0, fmt.Errorf("could not read gitlab groups: %s", err)
+		}
+		return gl.GetGroupID(allgroups, gitOwner)
+	}
+
+	entrypoints := []driftEntrypoint{}
+	switch config.GitProvider {
+	case "github":
+		entrypoints = append(entrypoints, driftEntrypoint{"github", false, func() (map[string]string, error) {
+			return k3d.GetGithubTerraformEnvs(map[string]string{}), nil
+		}})
+	case "gitlab":
+		entrypoints = append(entrypoints, driftEntrypoint{"gitlab", false, func() (map[string]string, error) {
+			gid, err := gitlabGroupID()
+			if err != nil {
+				return nil, err
+			}
+			return k3d.GetGitlabTerraformEnvs(map[string]string{}, gid), nil
+		}})
+	}
+
+	return append(entrypoints,
+		driftEntrypoint{"vault", true, func() (map[string]string, error) {
+			tfEnvs := k3d.GetVaultTerraformEnvs(config, map[string]string{})
+			if config.GitProvider == "gitlab" {
+				gid, err := gitlabGroupID()
+				if err != nil {
+					return nil, err
+				}
+				tfEnvs["TF_VAR_owner_group_id"] = strconv.Itoa(gid)
+			}
+			return tfEnvs, nil
+		}},
+		driftEntrypoint{"users", true, func() (map[string]string, error) {
+			return k3d.GetUsersTerraformEnvs(config, map[string]string{}), nil
+		}},
+	)
+}
+
+func civoEntrypoints(config *civo.CivoConfig) []driftEntrypoint {
+	return []driftEntrypoint{
+		{"github", false, func() (map[string]string, error) {
+			return civo.GetGithubTerraformEnvs(map[string]string{}), nil
+		}},
+		{"civo", false, func() (map[string]string, error) {
+			return civo.GetCivoTerraformEnvs(map[string]string{}), nil
+		}},
+		{"vault", true, func() (map[string]string, error) {
+			tfEnvs := civo.GetVaultTerraformEnvs(config, map[string]string{})
+			return civo.GetCivoTerraformEnvs(tfEnvs), nil
+		}},
+		{"users", true, func() (map[string]string, error) {
+			tfEnvs := civo.GetCivoTerraformEnvs(map[string]string{})
+			return civo.GetUsersTerraformEnvs(config, tfEnvs), nil
+		}},
+	}
+}
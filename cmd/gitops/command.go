@@ -0,0 +1,53 @@
+package gitops
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Upgrade
+	branchFlag                  string
+	dryRunFlag                  bool
+	fromVersionFlag             string
+	gitopsTemplatePathFlag      string
+	gitopsTemplateURLFlag       string
+	skipTemplateCompatCheckFlag bool
+	versionFlag                 string
+)
+
+func NewCommand() *cobra.Command {
+
+	gitopsCmd := &cobra.Command{
+		Use:   "gitops",
+		Short: "kubefirst gitops repository management",
+		Long:  "kubefirst gitops",
+	}
+
+	// on error, doesnt show helper/usage
+	gitopsCmd.SilenceUsage = true
+
+	// wire up new commands
+	gitopsCmd.AddCommand(Upgrade())
+
+	return gitopsCmd
+}
+
+func Upgrade() *cobra.Command {
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "merge a newer gitops-template release into your gitops repository",
+		Long:  "renders the gitops-template release the cluster was created from and the requested release with the tokens stored at install time, three-way merges the difference into your gitops repository on a new branch and opens a pull request (github) or merge request (gitlab) listing any conflicts",
+		RunE:  runUpgrade,
+	}
+
+	upgradeCmd.Flags().StringVar(&branchFlag, "branch", "", "the branch to push the upgrade to (default kubefirst-gitops-upgrade-<version>)")
+	upgradeCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "print the upgrade summary without pushing a branch or opening a pull request")
+	upgradeCmd.Flags().StringVar(&fromVersionFlag, "from-version", "", "the gitops-template git ref the gitops repository was generated from (default the ref used by create)")
+	upgradeCmd.Flags().StringVar(&gitopsTemplatePathFlag, "gitops-template-path", "", "a local gitops-template directory or .tar.gz archive of the target release to use instead of cloning --gitops-template-url")
+	upgradeCmd.Flags().StringVar(&gitopsTemplateURLFlag, "gitops-template-url", "", "the fully qualified url to the gitops-template repository to clone (default the url used by create)")
+	upgradeCmd.Flags().BoolVar(&skipTemplateCompatCheckFlag, "skip-template-compat-check", false, "skip checking the target gitops-template layout and compatibility manifest against this kubefirst version")
+	upgradeCmd.Flags().StringVar(&versionFlag, "version", "", "the gitops-template git ref (tag or branch) to upgrade to (required)")
+	upgradeCmd.MarkFlagRequired("version")
+
+	return upgradeCmd
+}
@@ -0,0 +1,232 @@
+package gitops
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kubefirst/kubefirst/configs"
+	"github.com/kubefirst/kubefirst/internal/civo"
+	"github.com/kubefirst/kubefirst/internal/gitClient"
+	"github.com/kubefirst/kubefirst/internal/githubWrapper"
+	gitlab "github.com/kubefirst/kubefirst/internal/gitlabcloud"
+	"github.com/kubefirst/kubefirst/internal/gitopsTemplate"
+	"github.com/kubefirst/kubefirst/internal/k3d"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func runUpgrade(cmd *cobra.Command, args []string) error {
+	branch, err := cmd.Flags().GetString("branch")
+	if err != nil {
+		return err
+	}
+
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+
+	fromVersion, err := cmd.Flags().GetString("from-version")
+	if err != nil {
+		return err
+	}
+
+	gitopsTemplatePath, err := cmd.Flags().GetString("gitops-template-path")
+	if err != nil {
+		return err
+	}
+
+	gitopsTemplateURL, err := cmd.Flags().GetString("gitops-template-url")
+	if err != nil {
+		return err
+	}
+
+	skipTemplateCompatCheck, err := cmd.Flags().GetBool("skip-template-compat-check")
+	if err != nil {
+		return err
+	}
+
+	version, err := cmd.Flags().GetString("version")
+	if err != nil {
+		return err
+	}
+
+	cloudProvider := viper.GetString("kubefirst.cloud-provider")
+	gitopsTemplateTokens := viper.GetString("kubefirst.gitops-template-tokens")
+	if cloudProvider == "" || gitopsTemplateTokens == "" {
+		return errors.New("no gitops template tokens found in the kubefirst config, gitops upgrade requires a cluster created by this version of kubefirst")
+	}
+
+	clusterName := viper.GetString("flags.cluster-name")
+	clusterType := viper.GetString("flags.cluster-type")
+
+	// the base release is rendered from the local template used by create unless a different ref is requested
+	baseTemplatePath := viper.GetString("flags.gitops-template-path")
+	if fromVersion == "" {
+		fromVersion = viper.GetString("flags.gitops-template-branch")
+	} else {
+		baseTemplatePath = ""
+	}
+	if gitopsTemplateURL == "" {
+		gitopsTemplateURL = viper.GetString("flags.gitops-template-url")
+	}
+	if branch == "" {
+		branch = fmt.Sprintf("kubefirst-gitops-upgrade-%s", version)
+	}
+
+	var gitProvider, gitHost, gitOwner, gitTokenEnv string
+	switch cloudProvider {
+	case civo.CloudProvider:
+		gitProvider = civo.GitProvider
+	default:
+		gitProvider = viper.GetString("flags.git-provider")
+	}
+	switch gitProvider {
+	case "github":
+		gitHost = k3d.GithubHost
+		gitOwner = viper.GetString("flags.github-owner")
+		gitTokenEnv = "GITHUB_TOKEN"
+	case "gitlab":
+		gitHost = k3d.GitlabHost
+		gitOwner = viper.GetString("flags.gitlab-owner")
+		gitTokenEnv = "GITLAB_TOKEN"
+	default:
+		return fmt.Errorf("git provider %q is not supported", gitProvider)
+	}
+	gitToken := os.Getenv(gitTokenEnv)
+	if gitToken == "" {
+		return fmt.Errorf("your %s is not set, it is required to push the upgrade branch", gitTokenEnv)
+	}
+
+	workDir, err := os.MkdirTemp("", "kubefirst-gitops-upgrade-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+	baseDir := filepath.Join(workDir, "base")
+	targetDir := filepath.Join(workDir, "target")
+	gitopsDir := filepath.Join(workDir, "gitops")
+
+	log.Info().Msgf("cloning gitops-template repo url: %s - base ref: %s - target ref: %s", gitopsTemplateURL, fromVersion, version)
+	_, err = gitClient.CloneOrInitRepo(baseTemplatePath, fromVersion, baseDir, gitopsTemplateURL)
+	if err != nil {
+		return fmt.Errorf("error cloning gitops-template %s at %s: %s", gitopsTemplateURL, fromVersion, err)
+	}
+	_, err = gitClient.CloneOrInitRepo(gitopsTemplatePath, version, targetDir, gitopsTemplateURL)
+	if err != nil {
+		return fmt.Errorf("error cloning gitops-template %s at %s: %s", gitopsTemplateURL, version, err)
+	}
+
+	extraTokens, err := gitopsTemplate.ExtraTokensFromConfig(viper.GetStringSlice(gitopsTemplate.ExtraTokensConfigKey))
+	if err != nil {
+		return err
+	}
+	if !skipTemplateCompatCheck {
+		err = gitopsTemplate.CheckTemplateCompatibility(targetDir, gitopsTemplate.LintOptions{
+			CloudProvider:    cloudProvider,
+			GitProvider:      gitProvider,
+			ClusterType:      clusterType,
+			KubefirstVersion: configs.K1Version,
+		}, extraTokens)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, templateDir := range []string{baseDir, targetDir} {
+		err = renderGitopsTemplate(cloudProvider, gitProvider, clusterName, clusterType, workDir, templateDir, gitopsTemplateTokens)
+		if err != nil {
+			return fmt.Errorf("error rendering gitops-template at %s: %s", templateDir, err)
+		}
+	}
+
+	gitopsRepoURL := fmt.Sprintf("https://%s/%s/gitops.git", gitHost, gitOwner)
+	log.Info().Msgf("cloning gitops repository %s", gitopsRepoURL)
+	gitopsRepo, err := gitClient.CloneWithToken(gitopsRepoURL, gitopsDir, gitToken)
+	if err != nil {
+		return fmt.Errorf("error cloning gitops repository %s: %s", gitopsRepoURL, err)
+	}
+	err = gitClient.CreateBranch(gitopsRepo, branch)
+	if err != nil {
+		return err
+	}
+	_, err = gitClient.CheckoutBranch(gitopsRepo, branch)
+	if err != nil {
+		return err
+	}
+
+	result, err := gitopsTemplate.MergeTrees(baseDir, gitopsDir, targetDir, fmt.Sprintf("gitops-template %s", version))
+	if err != nil {
+		return err
+	}
+	if !result.HasChanges() {
+		fmt.Printf("gitops repository is already up to date with gitops-template %s\n", version)
+		return nil
+	}
+
+	title := fmt.Sprintf("Upgrade gitops-template to %s", version)
+	body := result.Summary(fromVersion, version)
+	if dryRun {
+		fmt.Print(body)
+		return nil
+	}
+
+	err = gitClient.Commit(gitopsRepo, title)
+	if err != nil {
+		return err
+	}
+	err = gitClient.PushBranch(gitopsRepo, "origin", branch, gitToken)
+	if err != nil {
+		return err
+	}
+
+	switch gitProvider {
+	case "github":
+		pullRequest, err := githubWrapper.New().CreatePR(branch, "gitops", gitOwner, "main", title, body)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("opened pull request %s\n", pullRequest.GetHTMLURL())
+	case "gitlab":
+		gl := gitlab.GitLabWrapper{
+			Client: gitlab.NewGitLabClient(gitToken),
+		}
+		mergeRequestURL, err := gl.CreateMergeRequest("gitops", branch, "main", title, body)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("opened merge request %s\n", mergeRequestURL)
+	}
+
+	if len(result.Conflicts) > 0 {
+		fmt.Printf("%d file(s) have conflicts that need to be resolved before merging\n", len(result.Conflicts))
+	}
+
+	return nil
+}
+
+// renderGitopsTemplate detokenizes a cloned gitops-template with the tokens stored by create
+func renderGitopsTemplate(cloudProvider, gitProvider, clusterName, clusterType, k1Dir, templateDir, tokens string) error {
+	switch cloudProvider {
+	case k3d.CloudProvider:
+		gitopsTokens := k3d.GitopsTokenValues{}
+		err := json.Unmarshal([]byte(tokens), &gitopsTokens)
+		if err != nil {
+			return err
+		}
+		return k3d.RenderGitopsTemplate(gitProvider, clusterName, clusterType, k1Dir, templateDir, &gitopsTokens)
+	case civo.CloudProvider:
+		gitopsTokens := civo.GitOpsDirectoryValues{}
+		err := json.Unmarshal([]byte(tokens), &gitopsTokens)
+		if err != nil {
+			return err
+		}
+		return civo.RenderGitopsTemplate(clusterName, clusterType, templateDir, &gitopsTokens)
+	default:
+		return fmt.Errorf("cloud provider %q is not supported", cloudProvider)
+	}
+}
@@ -3,11 +3,11 @@ package cmd
 import (
 	"errors"
 	"fmt"
-	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/internal/wrappers"
 
 	"github.com/rs/zerolog/log"
@@ -59,7 +59,7 @@ validated and configured.`,
 		}
 
 		gitHubAccessToken := config.GithubToken
-		httpClient := http.DefaultClient
+		httpClient := httpCommon.NewClient()
 		gitHubService := services.NewGitHubService(httpClient)
 		gitHubHandler := handlers.NewGitHubHandler(gitHubService)
 		if providerValue == pkg.GitHubProviderName && gitHubAccessToken == "" {
@@ -3,25 +3,53 @@ package k3d
 import (
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/kubefirst/kubefirst/internal/k3d"
+	"github.com/kubefirst/kubefirst/internal/terraform"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Create
-	cloudRegionFlag            string
-	clusterNameFlag            string
-	clusterTypeFlag            string
-	dryRun                     bool
-	githubOwnerFlag            string
-	gitlabOwnerFlag            string
-	gitProviderFlag            string
-	gitopsTemplateURLFlag      string
-	gitopsTemplateBranchFlag   string
-	metaphorTemplateBranchFlag string
-	metaphorTemplateURLFlag    string
-	kbotPasswordFlag           string
-	useTelemetryFlag           bool
+	agentMemoryFlag             string
+	agentsFlag                  int
+	argocdPortFlag              int
+	bundleFlag                  string
+	cloudRegionFlag             string
+	clusterNameFlag             string
+	clusterTypeFlag             string
+	consolePortFlag             int
+	dryRun                      bool
+	githubOwnerFlag             string
+	gitlabOwnerFlag             string
+	gitProviderFlag             string
+	gitopsTemplateURLFlag       string
+	gitopsTemplateBranchFlag    string
+	gitopsTemplatePathFlag      string
+	metaphorTemplateBranchFlag  string
+	metaphorTemplatePathFlag    string
+	metaphorTemplateURLFlag     string
+	minioPortFlag               int
+	kbotPasswordFlag            string
+	k3sArgFlag                  []string
+	k3sImageFlag                string
+	k3sVersionFlag              string
+	portFlag                    []string
+	registryMirrorFlag          bool
+	registryPortFlag            int
+	remapPortsFlag              bool
+	serversFlag                 int
+	setFlag                     []string
+	skipTemplateCompatCheckFlag bool
+	terraformApprovalFlag       string
+	tokenValuesFlag             string
+	useSystemToolsFlag          bool
+	useTelemetryFlag            bool
+	vaultPortFlag               int
+
+	// Upgrade
+	upgradeTimeoutFlag time.Duration
 
 	// Supported git providers
 	supportedGitProviders = []string{"github", "gitlab"}
@@ -42,7 +70,7 @@ func NewCommand() *cobra.Command {
 	k3dCmd.SilenceUsage = true
 
 	// wire up new commands
-	k3dCmd.AddCommand(Create(), Destroy())
+	k3dCmd.AddCommand(Create(), Destroy(), Preload(), Start(), Stop(), Upgrade())
 
 	return k3dCmd
 }
@@ -56,22 +84,45 @@ func Create() *cobra.Command {
 	}
 
 	// todo review defaults and update descriptions
+	createCmd.Flags().StringVar(&agentMemoryFlag, "agent-memory", k3d.DefaultAgentMemory, "the memory limit of each agent node (i.e. 1024m|4g)")
+	createCmd.Flags().IntVar(&agentsFlag, "agents", k3d.DefaultAgents, "the number of k3d agent nodes, 0 runs the workloads on the servers")
+	createCmd.Flags().IntVar(&argocdPortFlag, "argocd-port", k3d.DefaultArgocdPort, "the local port of the argocd port-forward")
+	createCmd.Flags().StringVar(&bundleFlag, "bundle", "", "an offline bundle created by `kubefirst bundle create` to install the tools, templates, charts and images from instead of downloading them")
 	createCmd.Flags().StringVar(&clusterNameFlag, "cluster-name", "kubefirst", "the name of the cluster to create")
 	err := createCmd.MarkFlagRequired("cluster-name")
 	if err != nil {
 		log.Fatalf("error marking flag required: %s", err)
 	}
 	createCmd.Flags().StringVar(&clusterTypeFlag, "cluster-type", "mgmt", "the type of cluster to create (i.e. mgmt|workload)")
+	createCmd.Flags().IntVar(&consolePortFlag, "console-port", k3d.DefaultConsolePort, "the local port of the kubefirst console port-forward")
 	createCmd.Flags().BoolVar(&dryRun, "dry-run", false, "don't execute the installation")
 	createCmd.Flags().StringVar(&gitProviderFlag, "git-provider", "github", fmt.Sprintf("the git provider - one of: %s", supportedGitProviders))
 	createCmd.Flags().StringVar(&githubOwnerFlag, "github-owner", "", "the GitHub owner of the new gitops and metaphor repositories - required if using github")
 	createCmd.Flags().StringVar(&gitlabOwnerFlag, "gitlab-owner", "", "the GitLab owner (group) of the new gitops and metaphor projects - required if using gitlab")
 	createCmd.Flags().StringVar(&gitopsTemplateBranchFlag, "gitops-template-branch", "main", "the branch to clone for the gitops-template repository")
 	createCmd.Flags().StringVar(&gitopsTemplateURLFlag, "gitops-template-url", "https://github.com/kubefirst/gitops-template.git", "the fully qualified url to the gitops-template repository to clone")
+	createCmd.Flags().StringVar(&gitopsTemplatePathFlag, "gitops-template-path", "", "a local gitops-template directory or .tar.gz archive to use instead of cloning --gitops-template-url")
 	createCmd.Flags().StringVar(&kbotPasswordFlag, "kbot-password", "", "the default password to use for the kbot user")
+	createCmd.Flags().StringArrayVar(&k3sArgFlag, "k3s-arg", []string{}, "an extra k3s argument with an optional k3d node filter (i.e. --disable=metrics-server@server:*), can be repeated")
+	createCmd.Flags().StringVar(&k3sImageFlag, "k3s-image", "", "the k3s node image, defaults to the k3s version of k3d")
+	createCmd.Flags().StringVar(&k3sVersionFlag, "k3s-version", "", "the rancher/k3s version of the nodes (i.e. v1.25.7-k3s1), a shorthand for --k3s-image")
 	createCmd.Flags().StringVar(&metaphorTemplateBranchFlag, "metaphor-template-branch", "main", "the branch to clone for the metaphor-template repository")
 	createCmd.Flags().StringVar(&metaphorTemplateURLFlag, "metaphor-template-url", "https://github.com/kubefirst/metaphor-frontend-template.git", "the fully qualified url to the metaphor-template repository to clone")
+	createCmd.Flags().StringVar(&metaphorTemplatePathFlag, "metaphor-template-path", "", "a local metaphor-template directory or .tar.gz archive to use instead of cloning --metaphor-template-url")
+	createCmd.Flags().IntVar(&minioPortFlag, "minio-port", k3d.DefaultMinioPort, "the local port of the minio port-forward")
+	createCmd.Flags().StringArrayVar(&portFlag, "port", append([]string(nil), k3d.DefaultPorts...), "a host port mapped to a loadbalancer port as HOST:CONTAINER (i.e. 8443:443), 80 and 443 must be mapped, can be repeated")
+	createCmd.Flags().BoolVar(&registryMirrorFlag, "registry-mirror", false, "pull docker.io, ghcr.io and quay.io images through persistent local registry mirrors that are created or reused, `kubefirst k3d preload` seeds them")
+	createCmd.Flags().IntVar(&registryPortFlag, "registry-port", k3d.DefaultRegistryPort, "the host port of the k3d image registry")
+	createCmd.Flags().BoolVar(&remapPortsFlag, "remap-ports", false, "move the cluster ports and port-forwards that are already in use to the next free ports instead of failing")
+	createCmd.Flags().IntVar(&serversFlag, "servers", k3d.DefaultServers, "the number of k3d server nodes")
+	createCmd.Flags().BoolVar(&skipTemplateCompatCheckFlag, "skip-template-compat-check", false, "skip checking the gitops template layout and compatibility manifest against this kubefirst version")
+	createCmd.Flags().StringArrayVar(&setFlag, "set", []string{}, "an extra template token to detokenize as KEY=VALUE, replaces <KEY> in the gitops and metaphor templates (can be repeated)")
+	createCmd.Flags().StringVar(&terraformApprovalFlag, "terraform-approval", terraform.ApprovalAuto, fmt.Sprintf("how terraform changes are approved - one of: %s. plan-only saves the plan under ~/.k1/terraform-plans and stops, a later prompt run applies the saved plan and an auto run discards it", terraform.ApprovalModes))
+	createCmd.Flags().StringVar(&tokenValuesFlag, "token-values", "", "a yaml file of extra template tokens to detokenize as KEY: VALUE pairs")
+	createCmd.Flags().BoolVar(&useSystemToolsFlag, "use-system-tools", false, "use helm, kubectl, terraform and other tools found on PATH when their version is supported instead of downloading them")
 	createCmd.Flags().BoolVar(&useTelemetryFlag, "use-telemetry", true, "whether to emit telemetry")
+	createCmd.Flags().IntVar(&vaultPortFlag, "vault-port", k3d.DefaultVaultPort, "the local port of the vault port-forward")
+	createCmd.MarkFlagsMutuallyExclusive("k3s-image", "k3s-version")
 	return createCmd
 }
 
@@ -85,3 +136,53 @@ func Destroy() *cobra.Command {
 
 	return destroyCmd
 }
+
+func Preload() *cobra.Command {
+	preloadCmd := &cobra.Command{
+		Use:   "preload",
+		Short: "seed the registry mirrors with the images of the gitops registry",
+		Long:  "creates or reuses the registry mirrors of `kubefirst k3d create --registry-mirror` and pulls the images referenced in the detokenized gitops registry manifests through them, later installs find the images in the mirrors",
+		RunE:  preloadK3d,
+	}
+
+	return preloadCmd
+}
+
+func Start() *cobra.Command {
+	startCmd := &cobra.Command{
+		Use:   "start",
+		Short: "start a stopped k3d cluster",
+		Long:  "starts the k3d cluster, waits for the platform, restores the vault secrets exported by `kubefirst k3d stop`, re-opens the port-forwards and the ngrok tunnel and points the gitops repository webhook at the new tunnel",
+		RunE:  startK3d,
+	}
+
+	return startCmd
+}
+
+func Stop() *cobra.Command {
+	stopCmd := &cobra.Command{
+		Use:   "stop",
+		Short: "stop the k3d cluster to free resources, `kubefirst k3d start` resumes it",
+		Long:  "exports the vault secrets, which the dev mode vault keeps in memory, and stops the nodes of the k3d cluster, the platform keeps its state",
+		RunE:  stopK3d,
+	}
+
+	return stopCmd
+}
+
+func Upgrade() *cobra.Command {
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "upgrade the k3s version of the k3d cluster",
+		Long:  "snapshots the vault secrets, the minio state store and the argocd credentials, rolls the servers and then the agents to the new k3s version and waits for the argocd applications that were Synced and Healthy to return to it, the nodes are rolled back and the snapshot restored when they don't. The new k3s binaries are installed into the node containers, which keep their datastore and the image docker lists them with, the upgraded image is recorded in the kubefirst config",
+		RunE:  upgradeK3d,
+	}
+
+	upgradeCmd.Flags().StringVar(&k3sVersionFlag, "k3s-version", "", "the rancher/k3s version to upgrade to (i.e. v1.25.7-k3s1)")
+	err := upgradeCmd.MarkFlagRequired("k3s-version")
+	if err != nil {
+		log.Fatalf("error marking flag required: %s", err)
+	}
+	upgradeCmd.Flags().DurationVar(&upgradeTimeoutFlag, "timeout", 15*time.Minute, "how long to wait for the argocd applications after the nodes are rolled before rolling back")
+	return upgradeCmd
+}
@@ -2,10 +2,9 @@ package k3d
 
 import (
 	"context"
-	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -13,17 +12,20 @@ import (
 
 	"github.com/dustin/go-humanize"
 	"github.com/rs/zerolog/log"
-	v1 "k8s.io/api/core/v1"
 
 	"github.com/go-git/go-git/v5"
 	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/kubefirst/kubefirst/configs"
 	"github.com/kubefirst/kubefirst/internal/argocd"
+	"github.com/kubefirst/kubefirst/internal/bundle"
+	"github.com/kubefirst/kubefirst/internal/docker"
 	"github.com/kubefirst/kubefirst/internal/gitClient"
 	"github.com/kubefirst/kubefirst/internal/githubWrapper"
 	gitlab "github.com/kubefirst/kubefirst/internal/gitlabcloud"
+	"github.com/kubefirst/kubefirst/internal/gitopsTemplate"
 	"github.com/kubefirst/kubefirst/internal/handlers"
 	"github.com/kubefirst/kubefirst/internal/helm"
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/internal/k3d"
 	"github.com/kubefirst/kubefirst/internal/k8s"
 	"github.com/kubefirst/kubefirst/internal/reports"
@@ -36,7 +38,6 @@ import (
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var (
@@ -44,6 +45,21 @@ var (
 )
 
 func runK3d(cmd *cobra.Command, args []string) error {
+	agentMemoryFlag, err := cmd.Flags().GetString("agent-memory")
+	if err != nil {
+		return err
+	}
+
+	agentsFlag, err := cmd.Flags().GetInt("agents")
+	if err != nil {
+		return err
+	}
+
+	bundleFlag, err := cmd.Flags().GetString("bundle")
+	if err != nil {
+		return err
+	}
+
 	clusterNameFlag, err := cmd.Flags().GetString("cluster-name")
 	if err != nil {
 		return err
@@ -84,6 +100,11 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	gitopsTemplatePathFlag, err := cmd.Flags().GetString("gitops-template-path")
+	if err != nil {
+		return err
+	}
+
 	kbotPasswordFlag, err := cmd.Flags().GetString("kbot-password")
 	if err != nil {
 		return err
@@ -99,12 +120,154 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	metaphorTemplatePathFlag, err := cmd.Flags().GetString("metaphor-template-path")
+	if err != nil {
+		return err
+	}
+
+	k3sArgFlag, err := cmd.Flags().GetStringArray("k3s-arg")
+	if err != nil {
+		return err
+	}
+
+	k3sImageFlag, err := cmd.Flags().GetString("k3s-image")
+	if err != nil {
+		return err
+	}
+
+	k3sVersionFlag, err := cmd.Flags().GetString("k3s-version")
+	if err != nil {
+		return err
+	}
+
+	portFlag, err := cmd.Flags().GetStringArray("port")
+	if err != nil {
+		return err
+	}
+
+	registryPortFlag, err := cmd.Flags().GetInt("registry-port")
+	if err != nil {
+		return err
+	}
+
+	serversFlag, err := cmd.Flags().GetInt("servers")
+	if err != nil {
+		return err
+	}
+
+	skipTemplateCompatCheckFlag, err := cmd.Flags().GetBool("skip-template-compat-check")
+	if err != nil {
+		return err
+	}
+
+	setFlag, err := cmd.Flags().GetStringArray("set")
+	if err != nil {
+		return err
+	}
+
+	terraformApprovalFlag, err := cmd.Flags().GetString("terraform-approval")
+	if err != nil {
+		return err
+	}
+	err = terraform.ValidateApprovalMode(terraformApprovalFlag)
+	if err != nil {
+		return err
+	}
+
+	tokenValuesFlag, err := cmd.Flags().GetString("token-values")
+	if err != nil {
+		return err
+	}
+
+	useSystemToolsFlag, err := cmd.Flags().GetBool("use-system-tools")
+	if err != nil {
+		return err
+	}
+
 	useTelemetryFlag, err := cmd.Flags().GetBool("use-telemetry")
 	if err != nil {
 		return err
 	}
 
-	httpClient := http.DefaultClient
+	argocdPortFlag, err := cmd.Flags().GetInt("argocd-port")
+	if err != nil {
+		return err
+	}
+
+	consolePortFlag, err := cmd.Flags().GetInt("console-port")
+	if err != nil {
+		return err
+	}
+
+	minioPortFlag, err := cmd.Flags().GetInt("minio-port")
+	if err != nil {
+		return err
+	}
+
+	vaultPortFlag, err := cmd.Flags().GetInt("vault-port")
+	if err != nil {
+		return err
+	}
+
+	remapPortsFlag, err := cmd.Flags().GetBool("remap-ports")
+	if err != nil {
+		return err
+	}
+
+	registryMirrorFlag, err := cmd.Flags().GetBool("registry-mirror")
+	if err != nil {
+		return err
+	}
+
+	if k3sVersionFlag != "" {
+		k3sImageFlag, err = k3d.K3sImage(k3sVersionFlag)
+		if err != nil {
+			return err
+		}
+	}
+	clusterSpec := k3d.ClusterSpec{
+		Servers:        serversFlag,
+		Agents:         agentsFlag,
+		AgentMemory:    agentMemoryFlag,
+		K3sImage:       k3sImageFlag,
+		K3sArgs:        k3sArgFlag,
+		Ports:          portFlag,
+		RegistryPort:   registryPortFlag,
+		RegistryMirror: registryMirrorFlag,
+	}
+	// a cluster that is already created keeps the topology it was created with
+	if viper.GetBool("kubefirst-checks.terraform-apply-k3d") {
+		clusterSpec = k3d.ReadClusterSpec()
+	}
+	err = clusterSpec.Validate()
+	if err != nil {
+		return err
+	}
+
+	//* host port preflight, the ports of a created cluster are held by k3d
+	portForwards := k3d.PortForwards{
+		Argocd:  argocdPortFlag,
+		Vault:   vaultPortFlag,
+		Minio:   minioPortFlag,
+		Console: consolePortFlag,
+	}
+	clusterCreated := viper.GetBool("kubefirst-checks.terraform-apply-k3d")
+	hostPorts := k3d.HostPorts(&clusterSpec, &portForwards, !clusterCreated)
+	portConflicts, err := k3d.CheckHostPorts(hostPorts)
+	if err != nil {
+		return err
+	}
+	if len(portConflicts) > 0 {
+		if !remapPortsFlag {
+			return k3d.PortConflictsError(hostPorts, portConflicts)
+		}
+		err = k3d.RemapHostPorts(hostPorts, portConflicts)
+		if err != nil {
+			return err
+		}
+	}
+
+	httpClient := httpCommon.NewClient()
 
 	// Set git handlers
 	switch gitProviderFlag {
@@ -130,18 +293,33 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		isKubefirstTeam = "false"
 	}
 
+	// user defined tokens from a previous run are reused when none are provided
+	extraTokens, err := gitopsTemplate.ParseExtraTokens(setFlag, tokenValuesFlag)
+	if err != nil {
+		return err
+	}
+	if len(extraTokens) == 0 {
+		extraTokens, err = gitopsTemplate.ExtraTokensFromConfig(viper.GetStringSlice(gitopsTemplate.ExtraTokensConfigKey))
+		if err != nil {
+			return err
+		}
+	}
+
 	// required for destroy command
+	viper.Set(gitopsTemplate.ExtraTokensConfigKey, gitopsTemplate.ExtraTokensToConfig(extraTokens))
 	viper.Set("flags.cluster-name", clusterNameFlag)
 	viper.Set("flags.domain-name", k3d.DomainName)
 	viper.Set("flags.dry-run", dryRunFlag)
 	viper.Set("flags.git-provider", gitProviderFlag)
+	clusterSpec.Save()
+	portForwards.Save()
 	viper.WriteConfig()
 
 	// creates a new context, and a cancel function that allows canceling the context. The context is passed as an
 	// argument to the RunNgrok function, which is then started in a new goroutine.
 	var ctx context.Context
 	ctx, cancelContext = context.WithCancel(context.Background())
-	go pkg.RunNgrok(ctx)
+	go pkg.RunNgrok(ctx, k3d.IngressHTTPAddress())
 	if err != nil {
 		return err
 	}
@@ -172,6 +350,36 @@ func runK3d(cmd *cobra.Command, args []string) error {
 	gitopsTemplateTokens := k3d.GitopsTokenValues{}
 	var sshPrivateKey, sshPublicKey string
 
+	// an offline bundle replaces the downloads of the tools, templates, charts and images
+	var installBundle *bundle.Bundle
+	if bundleFlag != "" {
+		installBundle, err = bundle.Open(bundleFlag, fmt.Sprintf("%s/bundle", config.K1Dir), k3d.LocalhostOS, k3d.LocalhostARCH)
+		if err != nil {
+			return err
+		}
+
+		if gitopsTemplatePathFlag == "" {
+			bundledTemplate, err := installBundle.Template("gitops")
+			if err != nil {
+				return err
+			}
+			gitopsTemplatePathFlag = bundledTemplate.Path
+			gitopsTemplateURLFlag = bundledTemplate.URL
+			gitopsTemplateBranchFlag = bundledTemplate.Ref
+		}
+		if metaphorTemplatePathFlag == "" {
+			bundledTemplate, err := installBundle.Template("metaphor")
+			if err != nil {
+				return err
+			}
+			metaphorTemplatePathFlag = bundledTemplate.Path
+			metaphorTemplateURLFlag = bundledTemplate.URL
+			metaphorTemplateBranchFlag = bundledTemplate.Ref
+		}
+		viper.Set("flags.bundle", bundleFlag)
+		viper.WriteConfig()
+	}
+
 	// todo placed in configmap in kubefirst namespace, included in telemetry
 	clusterId := viper.GetString("kubefirst.cluster-id")
 	if clusterId == "" {
@@ -195,6 +403,12 @@ func runK3d(cmd *cobra.Command, args []string) error {
 	log.Info().Msgf("kubefirst version configs.K1Version: %s ", configs.K1Version)
 	log.Info().Msgf("cloning gitops-template repo url: %s ", gitopsTemplateURLFlag)
 	log.Info().Msgf("cloning gitops-template repo branch: %s ", gitopsTemplateBranchFlag)
+	if gitopsTemplatePathFlag != "" {
+		log.Info().Msgf("using local gitops-template instead of cloning: %s ", gitopsTemplatePathFlag)
+		if _, err := os.Stat(gitopsTemplatePathFlag); err != nil {
+			return fmt.Errorf("unable to read local gitops-template %s: %s", gitopsTemplatePathFlag, err)
+		}
+	}
 	// this branch flag value is overridden with a tag when running from a
 	// kubefirst binary for version compatibility
 	if metaphorTemplateBranchFlag == "main" && configs.K1Version != "development" {
@@ -203,10 +417,17 @@ func runK3d(cmd *cobra.Command, args []string) error {
 
 	log.Info().Msgf("cloning metaphor template url: %s ", metaphorTemplateURLFlag)
 	log.Info().Msgf("cloning metaphor template branch: %s ", metaphorTemplateBranchFlag)
+	if metaphorTemplatePathFlag != "" {
+		log.Info().Msgf("using local metaphor template instead of cloning: %s ", metaphorTemplatePathFlag)
+		if _, err := os.Stat(metaphorTemplatePathFlag); err != nil {
+			return fmt.Errorf("unable to read local metaphor template %s: %s", metaphorTemplatePathFlag, err)
+		}
+	}
 
 	atlantisWebhookSecret := viper.GetString("secrets.atlantis-webhook")
 	if atlantisWebhookSecret == "" {
 		atlantisWebhookSecret = pkg.Random(20)
+		pkg.RegisterSecret(atlantisWebhookSecret)
 		viper.Set("secrets.atlantis-webhook", atlantisWebhookSecret)
 		viper.WriteConfig()
 	}
@@ -228,6 +449,16 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		)
 	}
 
+	// check the container runtime before k3d fails deep in the cluster creation
+	dockerClient, err := docker.NewClient()
+	if err != nil {
+		return err
+	}
+	err = k3d.PreflightError(k3d.RuntimePreflight(dockerClient, clusterNameFlag, clusterSpec, !clusterCreated))
+	if err != nil {
+		return err
+	}
+
 	// Check git credentials
 	executionControl := viper.GetBool(fmt.Sprintf("kubefirst-checks.%s-credentials", config.GitProvider))
 	if !executionControl {
@@ -354,6 +585,7 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		}
 		log.Info().Msg("ssh key pair creation complete")
 
+		pkg.RegisterSecret(kbotPasswordFlag, sshPrivateKey)
 		viper.Set("kbot.password", kbotPasswordFlag)
 		viper.Set("kbot.private-key", sshPrivateKey)
 		viper.Set("kbot.public-key", sshPublicKey)
@@ -397,7 +629,11 @@ func runK3d(cmd *cobra.Command, args []string) error {
 	if !viper.GetBool("kubefirst-checks.tools-downloaded") {
 		log.Info().Msg("installing kubefirst dependencies")
 
-		err := k3d.DownloadTools(config.GitProvider, cGitOwner, config.ToolsDir)
+		if installBundle != nil {
+			err = installBundle.InstallTools(config.ToolsDir, k3d.Tools(config))
+		} else {
+			err = k3d.DownloadTools(config.GitProvider, cGitOwner, config.ToolsDir, useSystemToolsFlag)
+		}
 		if err != nil {
 			return err
 		}
@@ -424,18 +660,19 @@ func runK3d(cmd *cobra.Command, args []string) error {
 	gitopsTemplateTokens.ClusterType = clusterTypeFlag
 	gitopsTemplateTokens.GithubHost = k3d.GithubHost
 	gitopsTemplateTokens.GitlabHost = k3d.GitlabHost
-	gitopsTemplateTokens.ArgoWorkflowsIngressURL = fmt.Sprintf("https://argo.%s", k3d.DomainName)
-	gitopsTemplateTokens.VaultIngressURL = fmt.Sprintf("https://vault.%s", k3d.DomainName)
-	gitopsTemplateTokens.ArgocdIngressURL = fmt.Sprintf("https://argocd.%s", k3d.DomainName)
-	gitopsTemplateTokens.AtlantisIngressURL = fmt.Sprintf("https://atlantis.%s", k3d.DomainName)
-	gitopsTemplateTokens.MetaphorDevelopmentIngressURL = fmt.Sprintf("https://metaphor-development.%s", k3d.DomainName)
-	gitopsTemplateTokens.MetaphorStagingIngressURL = fmt.Sprintf("https://metaphor-staging.%s", k3d.DomainName)
-	gitopsTemplateTokens.MetaphorProductionIngressURL = fmt.Sprintf("https://metaphor-production.%s", k3d.DomainName)
+	gitopsTemplateTokens.ArgoWorkflowsIngressURL = k3d.IngressURL(fmt.Sprintf("https://argo.%s", k3d.DomainName))
+	gitopsTemplateTokens.VaultIngressURL = k3d.IngressURL(fmt.Sprintf("https://vault.%s", k3d.DomainName))
+	gitopsTemplateTokens.ArgocdIngressURL = k3d.IngressURL(fmt.Sprintf("https://argocd.%s", k3d.DomainName))
+	gitopsTemplateTokens.AtlantisIngressURL = k3d.IngressURL(fmt.Sprintf("https://atlantis.%s", k3d.DomainName))
+	gitopsTemplateTokens.MetaphorDevelopmentIngressURL = k3d.IngressURL(fmt.Sprintf("https://metaphor-development.%s", k3d.DomainName))
+	gitopsTemplateTokens.MetaphorStagingIngressURL = k3d.IngressURL(fmt.Sprintf("https://metaphor-staging.%s", k3d.DomainName))
+	gitopsTemplateTokens.MetaphorProductionIngressURL = k3d.IngressURL(fmt.Sprintf("https://metaphor-production.%s", k3d.DomainName))
 	gitopsTemplateTokens.KubefirstVersion = configs.K1Version
 	gitopsTemplateTokens.KubefirstTeam = isKubefirstTeam
 	gitopsTemplateTokens.GitProvider = config.GitProvider
 	gitopsTemplateTokens.ClusterId = clusterId
 	gitopsTemplateTokens.CloudProvider = k3d.CloudProvider
+	gitopsTemplateTokens.ExtraTokens = extraTokens
 
 	if useTelemetryFlag {
 		gitopsTemplateTokens.UseTelemetry = "true"
@@ -443,6 +680,20 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		gitopsTemplateTokens.UseTelemetry = "false"
 	}
 
+	// required for gitops upgrade and app create commands
+	gitopsTemplateTokensJSON, err := json.Marshal(gitopsTemplateTokens)
+	if err != nil {
+		return err
+	}
+	viper.Set("kubefirst.cloud-provider", k3d.CloudProvider)
+	viper.Set("kubefirst.gitops-template-tokens", string(gitopsTemplateTokensJSON))
+	viper.Set("flags.cloud-region", cloudRegionFlag)
+	viper.Set("flags.cluster-type", clusterTypeFlag)
+	viper.Set("flags.gitops-template-branch", gitopsTemplateBranchFlag)
+	viper.Set("flags.gitops-template-path", gitopsTemplatePathFlag)
+	viper.Set("flags.gitops-template-url", gitopsTemplateURLFlag)
+	viper.WriteConfig()
+
 	//* git clone and detokenize the gitops repository
 	// todo improve this logic for removing `kubefirst clean`
 	// if !viper.GetBool("template-repo.gitops.cloned") || viper.GetBool("template-repo.gitops.removed") {
@@ -457,7 +708,9 @@ func runK3d(cmd *cobra.Command, args []string) error {
 			config.GitopsDir,
 			gitopsTemplateBranchFlag,
 			gitopsTemplateURLFlag,
+			gitopsTemplatePathFlag,
 			config.K1Dir,
+			skipTemplateCompatCheckFlag,
 			&gitopsTemplateTokens,
 		)
 		if err != nil {
@@ -482,19 +735,15 @@ func runK3d(cmd *cobra.Command, args []string) error {
 
 			tfEntrypoint := config.GitopsDir + "/terraform/github"
 			tfEnvs := map[string]string{}
-			// tfEnvs = k3d.GetGithubTerraformEnvs(tfEnvs)
-			tfEnvs["GITHUB_TOKEN"] = os.Getenv("GITHUB_TOKEN")
-			tfEnvs["GITHUB_OWNER"] = githubOwnerFlag
-			tfEnvs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
-			tfEnvs["TF_VAR_atlantis_repo_webhook_url"] = atlantisWebhookURL
-			tfEnvs["TF_VAR_kubefirst_bot_ssh_public_key"] = viper.GetString("kbot.public-key")
-			tfEnvs["AWS_ACCESS_KEY_ID"] = "kray"
-			tfEnvs["AWS_SECRET_ACCESS_KEY"] = "feedkraystars"
-			tfEnvs["TF_VAR_aws_access_key_id"] = "kray"
-			tfEnvs["TF_VAR_aws_secret_access_key"] = "feedkraystars"
-			err := terraform.InitApplyAutoApprove(dryRunFlag, tfEntrypoint, tfEnvs)
+			tfEnvs = k3d.GetGithubTerraformEnvs(tfEnvs)
+			err := terraform.InitApplyWithApproval(dryRunFlag, tfEntrypoint, tfEnvs, terraformApprovalFlag)
+			if errors.Is(err, terraform.ErrPlanSaved) {
+				fmt.Println(err.Error())
+				return nil
+			}
 			if err != nil {
-				return errors.New(fmt.Sprintf("error creating github resources with terraform %s: %s", tfEntrypoint, err))
+				reports.TerraformErrorSummary(err)
+				return fmt.Errorf("error creating github resources with terraform %s: %w", tfEntrypoint, err)
 			}
 
 			log.Info().Msgf("created git repositories and teams for github.com/%s", githubOwnerFlag)
@@ -522,14 +771,15 @@ func runK3d(cmd *cobra.Command, args []string) error {
 
 			tfEntrypoint := config.GitopsDir + "/terraform/gitlab"
 			tfEnvs := map[string]string{}
-			tfEnvs["GITLAB_TOKEN"] = os.Getenv("GITLAB_TOKEN")
-			tfEnvs["GITLAB_OWNER"] = gitlabOwnerFlag
-			tfEnvs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
-			tfEnvs["TF_VAR_atlantis_repo_webhook_url"] = atlantisWebhookURL
-			tfEnvs["TF_VAR_owner_group_id"] = strconv.Itoa(gid)
-			err := terraform.InitApplyAutoApprove(dryRunFlag, tfEntrypoint, tfEnvs)
+			tfEnvs = k3d.GetGitlabTerraformEnvs(tfEnvs, gid)
+			err := terraform.InitApplyWithApproval(dryRunFlag, tfEntrypoint, tfEnvs, terraformApprovalFlag)
+			if errors.Is(err, terraform.ErrPlanSaved) {
+				fmt.Println(err.Error())
+				return nil
+			}
 			if err != nil {
-				return errors.New(fmt.Sprintf("error creating gitlab resources with terraform %s: %s", tfEntrypoint, err))
+				reports.TerraformErrorSummary(err)
+				return fmt.Errorf("error creating gitlab resources with terraform %s: %w", tfEntrypoint, err)
 			}
 
 			log.Info().Msgf("created git projects and groups for gitlab.com/%s", gitlabOwnerFlag)
@@ -609,6 +859,7 @@ func runK3d(cmd *cobra.Command, args []string) error {
 	metaphorTemplateTokens.MetaphorDevelopmentIngressURL = fmt.Sprintf("metaphor-development.%s", k3d.DomainName)
 	metaphorTemplateTokens.MetaphorStagingIngressURL = fmt.Sprintf("metaphor-staging.%s", k3d.DomainName)
 	metaphorTemplateTokens.MetaphorProductionIngressURL = fmt.Sprintf("metaphor-production.%s", k3d.DomainName)
+	metaphorTemplateTokens.ExtraTokens = extraTokens
 
 	//* git clone and detokenize the metaphor-frontend-template repository
 	if !viper.GetBool("kubefirst-checks.metaphor-repo-pushed") {
@@ -620,6 +871,8 @@ func runK3d(cmd *cobra.Command, args []string) error {
 			config.MetaphorDir,
 			metaphorTemplateBranchFlag,
 			metaphorTemplateURLFlag,
+			metaphorTemplatePathFlag,
+			skipTemplateCompatCheckFlag,
 			&metaphorTemplateTokens,
 		)
 		if err != nil {
@@ -654,11 +907,30 @@ func runK3d(cmd *cobra.Command, args []string) error {
 	if !viper.GetBool("kubefirst-checks.terraform-apply-k3d") {
 		log.Info().Msg("Creating k3d cluster")
 
-		err := k3d.ClusterCreate(clusterNameFlag, config.K1Dir, config.K3dClient, config.Kubeconfig)
+		if installBundle != nil {
+			if clusterSpec.K3sImage != "" {
+				log.Warn().Msgf("the bundle contains the default k3s image of k3d, %s is pulled from its registry", clusterSpec.K3sImage)
+			}
+			err = installBundle.LoadImages(bundle.ClusterImages)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = k3d.ClusterCreate(clusterNameFlag, config.K1Dir, config.K3dClient, config.Kubeconfig, clusterSpec)
 		if err != nil {
 			return err
 		}
 
+		if installBundle != nil {
+			if archive, ok := installBundle.ImageArchive(bundle.PlatformImages); ok {
+				err = k3d.ImportImages(config.K3dClient, clusterNameFlag, archive)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
 		log.Info().Msg("successfully created k3d cluster")
 		viper.Set("kubefirst-checks.terraform-apply-k3d", true)
 		viper.WriteConfig()
@@ -726,62 +998,35 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		}
 
 		for _, project := range createTokensForProjects {
-			var p = gitlab.DeployTokenCreateParameters{
-				Name:     fmt.Sprintf("%s-deploy", project),
-				Username: fmt.Sprintf("%s-deploy", project),
-				Scopes:   []string{"read_registry", "write_registry"},
-			}
-
-			log.Info().Msgf("creating project deploy token for project %s...", project)
-			token, err := gl.CreateProjectDeployToken(project, &p)
+			err = k3d.CreateProjectDeployTokenSecrets(&gl, config.Kubeconfig, project)
 			if err != nil {
 				log.Fatal().Msgf("error creating project deploy token for project %s: %s", project, err)
 			}
-
-			log.Info().Msgf("creating secret for project deploy token for project %s...", project)
-			usernamePasswordString := fmt.Sprintf("%s:%s", p.Username, token)
-			usernamePasswordStringB64 := base64.StdEncoding.EncodeToString([]byte(usernamePasswordString))
-			dockerConfigString := fmt.Sprintf(`{"auths": {"%s": {"username": "%s", "password": "%s", "email": "%s", "auth": "%s"}}}`, "registry.gitlab.com", p.Username, token, "k-bot@example.com", usernamePasswordStringB64)
-
-			createInNamespace := []string{"development", "staging", "production"}
-			for _, namespace := range createInNamespace {
-				deployTokenSecret := &v1.Secret{
-					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-deploy", project), Namespace: namespace},
-					Data:       map[string][]byte{".dockerconfigjson": []byte(dockerConfigString)},
-					Type:       "kubernetes.io/dockerconfigjson",
-				}
-				err = k8s.CreateSecretV2(config.Kubeconfig, deployTokenSecret)
-				if err != nil {
-					log.Error().Msgf("error while creating secret for project deploy token: %s", err)
-				}
-			}
-
-			// Create argo workflows pull secret
-			// This is formatted to work with buildkit
-			argoDeployTokenSecret := &v1.Secret{
-				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-deploy", project), Namespace: "argo"},
-				Data:       map[string][]byte{"config.json": []byte(dockerConfigString)},
-				Type:       "Opaque",
-			}
-			err = k8s.CreateSecretV2(config.Kubeconfig, argoDeployTokenSecret)
-			if err != nil {
-				log.Error().Msgf("error while creating secret for project deploy token: %s", err)
-			}
 		}
 	}
 
 	//* helm add argo repository && update
 	helmRepo := helm.HelmRepo{
 		RepoName:     "argo",
-		RepoURL:      "https://argoproj.github.io/argo-helm",
+		RepoURL:      k3d.ArgocdHelmRepoURL,
 		ChartName:    "argo-cd",
 		Namespace:    "argocd",
-		ChartVersion: "4.10.5",
+		ChartVersion: k3d.ArgocdHelmChartVersion,
+	}
+
+	if installBundle != nil {
+		chart, err := installBundle.Chart(helmRepo.ChartName, helmRepo.ChartVersion)
+		if err != nil {
+			return err
+		}
+		helmRepo.ChartArchive = chart.Path
 	}
 
 	//* helm add repo and update
 	executionControl = viper.GetBool("kubefirst-checks.argocd-helm-repo-added")
-	if !executionControl {
+	if !executionControl && installBundle != nil {
+		log.Info().Msgf("installing %s from the bundle, skipping helm repo add", helmRepo.ChartName)
+	} else if !executionControl {
 		log.Info().Msgf("helm repo add %s %s and helm repo update", helmRepo.RepoName, helmRepo.RepoURL)
 		helm.AddRepoAndUpdateRepo(dryRunFlag, config.HelmClient, helmRepo, config.Kubeconfig)
 		log.Info().Msg("helm repo added")
@@ -831,10 +1076,10 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		"argocd-server",
 		"argocd",
 		8080,
-		8080,
+		portForwards.Argocd,
 		argoCDStopChannel,
 	)
-	log.Info().Msgf("port-forward to argocd is available at %s", k3d.ArgocdPortForwardURL)
+	log.Info().Msgf("port-forward to argocd is available at %s", k3d.ArgocdPortForwardURL())
 
 	var argocdPassword string
 	//* argocd pods are ready, get and set credentials
@@ -850,6 +1095,7 @@ func runK3d(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
+		pkg.RegisterSecret(argocdPassword)
 		viper.Set("components.argocd.password", argocdPassword)
 		viper.Set("components.argocd.username", "admin")
 		viper.WriteConfig()
@@ -857,13 +1103,14 @@ func runK3d(cmd *cobra.Command, args []string) error {
 
 		log.Info().Msg("Getting an argocd auth token")
 		// todo return in here and pass argocdAuthToken as a parameter
-		token, err := argocd.GetArgoCDToken("admin", argocdPassword)
+		token, err := argocd.GetArgoCDTokenWithURL(k3d.ArgocdAPIURL(), "admin", argocdPassword)
 		if err != nil {
 			return err
 		}
 
 		log.Info().Msg("argocd admin auth token set")
 
+		pkg.RegisterSecret(token)
 		viper.Set("components.argocd.auth-token", token)
 		viper.Set("kubefirst-checks.argocd-credentials-set", true)
 		viper.WriteConfig()
@@ -915,14 +1162,15 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		"minio",
 		"minio",
 		9000,
-		9000,
+		portForwards.Minio,
 		minioStopChannel,
 	)
 
 	//copy files to Minio
-	endpoint := "localhost:9000"
+	endpoint := k3d.MinioPortForwardEndpoint()
 	accessKeyID := "k-ray"
 	secretAccessKey := "feedkraystars"
+	pkg.RegisterSecret(secretAccessKey)
 
 	// Initialize minio client object.
 	minioClient, err := minio.New(endpoint, &minio.Options{
@@ -960,7 +1208,7 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		"vault-0",
 		"vault",
 		8200,
-		8200,
+		portForwards.Vault,
 		vaultStopChannel,
 	)
 
@@ -975,19 +1223,6 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		tfEnvs := map[string]string{}
 		tfEnvs = k3d.GetVaultTerraformEnvs(config, tfEnvs)
 
-		tfEnvs["TF_VAR_email_address"] = "your@email.com"
-		tfEnvs[fmt.Sprintf("TF_VAR_%s_token", config.GitProvider)] = cGitToken
-		tfEnvs["TF_VAR_vault_addr"] = k3d.VaultPortForwardURL
-		tfEnvs["TF_VAR_vault_token"] = "k1_local_vault_token"
-		tfEnvs["VAULT_ADDR"] = k3d.VaultPortForwardURL
-		tfEnvs["VAULT_TOKEN"] = "k1_local_vault_token"
-		tfEnvs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
-		tfEnvs["TF_VAR_atlantis_repo_webhook_url"] = atlantisWebhookURL
-		tfEnvs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
-		tfEnvs["TF_VAR_kubefirst_bot_ssh_private_key"] = viper.GetString("kbot.private-key")
-		tfEnvs["TF_VAR_kubefirst_bot_ssh_public_key"] = viper.GetString("kbot.public-key")
-		tfEnvs["GITHUB_OWNER"] = viper.GetString("flags.github-owner")
-
 		if config.GitProvider == "gitlab" {
 			gl := gitlab.GitLabWrapper{
 				Client: gitlab.NewGitLabClient(cGitToken),
@@ -1004,8 +1239,13 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		}
 
 		tfEntrypoint := config.GitopsDir + "/terraform/vault"
-		err := terraform.InitApplyAutoApprove(dryRunFlag, tfEntrypoint, tfEnvs)
+		err := terraform.InitApplyWithApproval(dryRunFlag, tfEntrypoint, tfEnvs, terraformApprovalFlag)
+		if errors.Is(err, terraform.ErrPlanSaved) {
+			fmt.Println(err.Error())
+			return nil
+		}
 		if err != nil {
+			reports.TerraformErrorSummary(err)
 			return err
 		}
 
@@ -1022,20 +1262,16 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		log.Info().Msg("applying users terraform")
 
 		tfEnvs := map[string]string{}
-		tfEnvs["TF_VAR_email_address"] = "your@email.com"
-		tfEnvs[fmt.Sprintf("TF_VAR_%s_token", strings.ToUpper(config.GitProvider))] = cGitToken
-		tfEnvs["TF_VAR_vault_addr"] = k3d.VaultPortForwardURL
-		tfEnvs["TF_VAR_vault_token"] = "k1_local_vault_token"
-		tfEnvs["VAULT_ADDR"] = k3d.VaultPortForwardURL
-		tfEnvs["VAULT_TOKEN"] = "k1_local_vault_token"
-		tfEnvs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
-		tfEnvs["TF_VAR_atlantis_repo_webhook_url"] = atlantisWebhookURL
-		tfEnvs[fmt.Sprintf("%s_TOKEN", strings.ToUpper(config.GitProvider))] = cGitToken
-		tfEnvs[fmt.Sprintf("%s_OWNER", strings.ToUpper(config.GitProvider))] = cGitOwner
+		tfEnvs = k3d.GetUsersTerraformEnvs(config, tfEnvs)
 
 		tfEntrypoint := config.GitopsDir + "/terraform/users"
-		err := terraform.InitApplyAutoApprove(dryRunFlag, tfEntrypoint, tfEnvs)
+		err := terraform.InitApplyWithApproval(dryRunFlag, tfEntrypoint, tfEnvs, terraformApprovalFlag)
+		if errors.Is(err, terraform.ErrPlanSaved) {
+			fmt.Println(err.Error())
+			return nil
+		}
 		if err != nil {
+			reports.TerraformErrorSummary(err)
 			return err
 		}
 		log.Info().Msg("executed users terraform successfully")
@@ -1102,19 +1338,19 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		"kubefirst-console",
 		"kubefirst",
 		8080,
-		9094,
+		portForwards.Console,
 		consoleStopChannel,
 	)
 
 	log.Info().Msg("kubefirst installation complete")
 	log.Info().Msg("welcome to your new kubefirst platform running in K3d")
 
-	err = pkg.IsConsoleUIAvailable(pkg.KubefirstConsoleLocalURLCloud)
+	err = pkg.IsConsoleUIAvailable(k3d.ConsolePortForwardURL())
 	if err != nil {
 		log.Error().Err(err).Msg("")
 	}
 
-	err = pkg.OpenBrowser(pkg.KubefirstConsoleLocalURLCloud)
+	err = pkg.OpenBrowser(k3d.ConsolePortForwardURL())
 	if err != nil {
 		log.Error().Err(err).Msg("")
 	}
@@ -62,7 +62,7 @@ func destroyK3d(cmd *cobra.Command, args []string) error {
 		"minio",
 		"minio",
 		9000,
-		9000,
+		k3d.ReadPortForwards().Minio,
 		minioStopChannel,
 	)
 
@@ -160,11 +160,15 @@ func destroyK3d(cmd *cobra.Command, args []string) error {
 
 	if viper.GetBool("kubefirst-checks.terraform-apply-k3d") {
 		log.Info().Msg("destroying k3d resources with terraform")
+		log.Info().Msgf("k3d cluster %s topology: %s", clusterName, k3d.ReadClusterSpec())
 
 		err := k3d.DeleteK3dCluster(clusterName, config.K1Dir, config.K3dClient)
 		if err != nil {
 			return err
 		}
+		if k3d.ReadClusterSpec().RegistryMirror {
+			log.Info().Msg("the registry mirrors are kept for the next install, remove them with `k3d registry delete` and `docker volume rm`")
+		}
 
 		viper.Set("kubefirst-checks.terraform-apply-k3d", false)
 		viper.WriteConfig()
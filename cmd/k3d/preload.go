@@ -0,0 +1,55 @@
+package k3d
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kubefirst/kubefirst/internal/k3d"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func preloadK3d(cmd *cobra.Command, args []string) error {
+	clusterName := viper.GetString("flags.cluster-name")
+	gitProvider := viper.GetString("flags.git-provider")
+
+	var gitOwner string
+	switch gitProvider {
+	case "github":
+		gitOwner = viper.GetString("flags.github-owner")
+	case "gitlab":
+		gitOwner = viper.GetString("flags.gitlab-owner")
+	}
+	config := k3d.GetConfig(gitProvider, gitOwner)
+
+	// the registry is detokenized by `kubefirst k3d create`, it stays on disk when the cluster is destroyed
+	registryDir := fmt.Sprintf("%s/registry/%s", config.GitopsDir, clusterName)
+	if _, err := os.Stat(registryDir); clusterName == "" || err != nil {
+		return errors.New("no detokenized gitops registry found, run `kubefirst k3d create` first")
+	}
+	if _, err := os.Stat(config.HelmClient); err != nil {
+		return fmt.Errorf("helm was not found at %s, run `kubefirst k3d create` first", config.HelmClient)
+	}
+
+	log.Info().Msgf("collecting the images of %s", registryDir)
+	images, err := k3d.GitopsImages(registryDir, config.HelmClient)
+	if err != nil {
+		return err
+	}
+
+	err = k3d.EnsureMirrors(config.K3dClient)
+	if err != nil {
+		return err
+	}
+	err = k3d.PreloadImages(images)
+	if err != nil {
+		return err
+	}
+
+	if !k3d.ReadClusterSpec().RegistryMirror {
+		log.Info().Msg("the registry mirrors are used by clusters created with `kubefirst k3d create --registry-mirror`")
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package k3d
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kubefirst/kubefirst/internal/githubWrapper"
+	gitlab "github.com/kubefirst/kubefirst/internal/gitlabcloud"
+	"github.com/kubefirst/kubefirst/internal/k3d"
+	"github.com/kubefirst/kubefirst/internal/k8s"
+	"github.com/kubefirst/kubefirst/internal/reports"
+	"github.com/kubefirst/kubefirst/internal/vault"
+	"github.com/kubefirst/kubefirst/pkg"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func startK3d(cmd *cobra.Command, args []string) error {
+	clusterName := viper.GetString("flags.cluster-name")
+	gitProvider := viper.GetString("flags.git-provider")
+
+	if !viper.GetBool("kubefirst-checks.terraform-apply-k3d") {
+		return errors.New("no k3d cluster was created, run `kubefirst k3d create` first")
+	}
+
+	var cGitOwner, cGitToken string
+	switch gitProvider {
+	case "github":
+		cGitOwner = viper.GetString("flags.github-owner")
+		cGitToken = os.Getenv("GITHUB_TOKEN")
+	case "gitlab":
+		cGitOwner = viper.GetString("flags.gitlab-owner")
+		cGitToken = os.Getenv("GITLAB_TOKEN")
+	default:
+		return fmt.Errorf("invalid git provider option %q", gitProvider)
+	}
+
+	// the token updates the repository webhook to the new ngrok url
+	if len(cGitToken) == 0 {
+		return fmt.Errorf("please set a %s_TOKEN environment variable to continue", strings.ToUpper(gitProvider))
+	}
+
+	config := k3d.GetConfig(gitProvider, cGitOwner)
+
+	// the stopped cluster releases its ports, they are taken again when it starts
+	clusterSpec := k3d.ReadClusterSpec()
+	portForwards := k3d.ReadPortForwards()
+	hostPorts := k3d.HostPorts(&clusterSpec, &portForwards, true)
+	portConflicts, err := k3d.CheckHostPorts(hostPorts)
+	if err != nil {
+		return err
+	}
+	if len(portConflicts) > 0 {
+		return k3d.PortConflictsError(hostPorts, portConflicts)
+	}
+
+	err = k3d.ClusterStart(clusterName, config.K3dClient, config.Kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	err = k3d.WaitForCoreWorkloads(config.Kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	//* port-forwards
+	argoCDStopChannel := make(chan struct{}, 1)
+	defer func() {
+		close(argoCDStopChannel)
+	}()
+	k8s.OpenPortForwardPodWrapper(config.Kubeconfig, "argocd-server", "argocd", 8080, portForwards.Argocd, argoCDStopChannel)
+	log.Info().Msgf("port-forward to argocd is available at %s", k3d.ArgocdPortForwardURL())
+
+	minioStopChannel := make(chan struct{}, 1)
+	defer func() {
+		close(minioStopChannel)
+	}()
+	k8s.OpenPortForwardPodWrapper(config.Kubeconfig, "minio", "minio", 9000, portForwards.Minio, minioStopChannel)
+
+	vaultStopChannel := make(chan struct{}, 1)
+	defer func() {
+		close(vaultStopChannel)
+	}()
+	k8s.OpenPortForwardPodWrapper(config.Kubeconfig, "vault-0", "vault", 8200, portForwards.Vault, vaultStopChannel)
+
+	err = vault.UnsealIfSealed(config.Kubeconfig, k3d.VaultPortForwardURL())
+	if err != nil {
+		return err
+	}
+	err = k3d.WaitForVault(config.Kubeconfig, false)
+	if err != nil {
+		return err
+	}
+	err = k3d.RestoreStoppedVault(k3d.VaultPortForwardURL(), config.K1Dir)
+	if err != nil {
+		return err
+	}
+
+	consoleStopChannel := make(chan struct{}, 1)
+	defer func() {
+		close(consoleStopChannel)
+	}()
+	k8s.OpenPortForwardPodWrapper(config.Kubeconfig, "kubefirst-console", "kubefirst", 8080, portForwards.Console, consoleStopChannel)
+
+	//* ngrok tunnel and atlantis webhook
+	previousAtlantisWebhookURL, err := k3d.AtlantisWebhookURL(config.Kubeconfig)
+	if err != nil {
+		return err
+	}
+	// the tunnel of the previous run is gone, RunNgrok stores the new one
+	viper.Set("ngrok.host", "")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go pkg.RunNgrok(ctx, k3d.IngressHTTPAddress())
+
+	ngrokHost, err := waitForNgrokHost(time.Minute)
+	if err != nil {
+		return err
+	}
+	atlantisWebhookURL := fmt.Sprintf("%s/events", ngrokHost)
+
+	err = updateAtlantisWebhook(gitProvider, cGitOwner, cGitToken, previousAtlantisWebhookURL, atlantisWebhookURL)
+	if err != nil {
+		return err
+	}
+	err = k3d.UpdateAtlantisWebhookURL(config.Kubeconfig, config.KubectlClient, k3d.VaultPortForwardURL(), atlantisWebhookURL)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("k3d cluster %s started", clusterName)
+	reports.LocalHandoffScreenV2(viper.GetString("components.argocd.password"), clusterName, cGitOwner, config, false, false)
+
+	return nil
+}
+
+// waitForNgrokHost waits for pkg.RunNgrok to store the tunnel url in the kubefirst config
+func waitForNgrokHost(timeout time.Duration) (string, error) {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(time.Second) {
+		host := viper.GetString("ngrok.host")
+		if host != "" {
+			return host, nil
+		}
+	}
+	return "", errors.New("timed out waiting for the ngrok tunnel, is NGROK_AUTHTOKEN set?")
+}
+
+// updateAtlantisWebhook points the gitops repository webhook at the new ngrok tunnel
+func updateAtlantisWebhook(gitProvider, gitOwner, gitToken, previousURL, atlantisWebhookURL string) error {
+	log.Info().Msgf("updating the gitops repository webhook to %s", atlantisWebhookURL)
+	atlantisWebhookSecret := viper.GetString("secrets.atlantis-webhook")
+
+	var updated int
+	var err error
+	switch gitProvider {
+	case "github":
+		gitHubClient := githubWrapper.New()
+		updated, err = gitHubClient.UpdateWebhookURL(gitOwner, "gitops", previousURL, atlantisWebhookURL, atlantisWebhookSecret)
+	case "gitlab":
+		gl := gitlab.GitLabWrapper{
+			Client: gitlab.NewGitLabClient(gitToken),
+		}
+		updated, err = gl.UpdateProjectHookURL("gitops", previousURL, atlantisWebhookURL, atlantisWebhookSecret)
+	}
+	if err != nil {
+		return err
+	}
+
+	if updated == 0 {
+		log.Warn().Msgf("no gitops repository webhook delivered to %s was found, atlantis won't receive %s events", previousURL, gitProvider)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package k3d
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kubefirst/kubefirst/internal/k3d"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func stopK3d(cmd *cobra.Command, args []string) error {
+	clusterName := viper.GetString("flags.cluster-name")
+	gitProvider := viper.GetString("flags.git-provider")
+
+	if !viper.GetBool("kubefirst-checks.terraform-apply-k3d") {
+		return errors.New("no k3d cluster was created, run `kubefirst k3d create` first")
+	}
+
+	var gitOwner string
+	switch gitProvider {
+	case "github":
+		gitOwner = viper.GetString("flags.github-owner")
+	case "gitlab":
+		gitOwner = viper.GetString("flags.gitlab-owner")
+	}
+	config := k3d.GetConfig(gitProvider, gitOwner)
+
+	// the dev mode vault keeps its secrets in memory, `kubefirst k3d start` restores them
+	vaultAddress, closeVaultPortForward, err := openVaultPortForward(config.Kubeconfig)
+	if err != nil {
+		return err
+	}
+	err = k3d.ExportStoppedVault(vaultAddress, config.K1Dir)
+	closeVaultPortForward()
+	if err != nil {
+		return fmt.Errorf("the cluster was not stopped, exporting the vault secrets failed: %w", err)
+	}
+
+	err = k3d.ClusterStop(clusterName, config.K3dClient)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("k3d cluster %s stopped, run `kubefirst k3d start` to resume it", clusterName)
+	return nil
+}
@@ -0,0 +1,199 @@
+package k3d
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kubefirst/kubefirst/internal/docker"
+	"github.com/kubefirst/kubefirst/internal/k3d"
+	"github.com/kubefirst/kubefirst/internal/k8s"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func upgradeK3d(cmd *cobra.Command, args []string) error {
+	k3sVersionFlag, err := cmd.Flags().GetString("k3s-version")
+	if err != nil {
+		return err
+	}
+
+	upgradeTimeoutFlag, err := cmd.Flags().GetDuration("timeout")
+	if err != nil {
+		return err
+	}
+
+	targetImage, err := k3d.K3sImage(k3sVersionFlag)
+	if err != nil {
+		return err
+	}
+
+	clusterName := viper.GetString("flags.cluster-name")
+	gitProvider := viper.GetString("flags.git-provider")
+
+	if !viper.GetBool("kubefirst-checks.terraform-apply-k3d") {
+		return errors.New("no k3d cluster was created, run `kubefirst k3d create` first")
+	}
+
+	var gitOwner string
+	switch gitProvider {
+	case "github":
+		gitOwner = viper.GetString("flags.github-owner")
+	case "gitlab":
+		gitOwner = viper.GetString("flags.gitlab-owner")
+	}
+	config := k3d.GetConfig(gitProvider, gitOwner)
+
+	dockerClient, err := docker.NewClient()
+	if err != nil {
+		return err
+	}
+	clusterSpec := k3d.ReadClusterSpec()
+	err = k3d.PreflightError(k3d.RuntimePreflight(dockerClient, clusterName, clusterSpec, false))
+	if err != nil {
+		return err
+	}
+
+	nodes, err := k3d.ClusterNodes(dockerClient, clusterName)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("no nodes of k3d cluster %s were found", clusterName)
+	}
+
+	// the cluster has to be running, a stopped cluster is resumed with `kubefirst k3d start`
+	currentVersion, err := k3d.ClusterK3sVersion(config.Kubeconfig)
+	if err != nil {
+		return fmt.Errorf("could not read the k3s version of the cluster, is it started? %w", err)
+	}
+	err = k3d.CheckK3sUpgrade(currentVersion, k3sVersionFlag)
+	if err != nil {
+		return err
+	}
+	previousImage, err := k3d.K3sImage(currentVersion)
+	if err != nil {
+		return err
+	}
+
+	// the applications that are Synced and Healthy now have to be again after the upgrade
+	applications, err := k3d.ArgocdApplications(config.Kubeconfig, config.KubectlClient)
+	if err != nil {
+		return err
+	}
+	coreApplications := []string{}
+	for _, application := range applications {
+		if application.Ready() {
+			coreApplications = append(coreApplications, application.Name)
+		}
+	}
+	log.Info().Msgf("upgrading k3d cluster %s from k3s %s to %s, waiting for %d argocd applications afterwards", clusterName, currentVersion, k3sVersionFlag, len(coreApplications))
+
+	vaultAddress, closeVaultPortForward, err := openVaultPortForward(config.Kubeconfig)
+	if err != nil {
+		return err
+	}
+	snapshotDir, err := k3d.SnapshotPlatform(config.Kubeconfig, vaultAddress, config.K1Dir)
+	closeVaultPortForward()
+	if err != nil {
+		return err
+	}
+
+	upgrade := k3d.K3sUpgrade{
+		Client:        dockerClient,
+		Kubeconfig:    config.Kubeconfig,
+		KubectlClient: config.KubectlClient,
+		NodeTimeout:   5 * time.Minute,
+	}
+	rolled, err := upgrade.RollNodes(nodes, targetImage, k3sVersionFlag)
+	if err == nil {
+		err = restoreVaultSecrets(config.Kubeconfig, snapshotDir)
+	}
+	if err == nil {
+		err = k3d.WaitForArgocdApplications(config.Kubeconfig, config.KubectlClient, coreApplications, upgradeTimeoutFlag)
+	}
+	if err != nil {
+		return rollbackK3sUpgrade(config, upgrade, rolled, previousImage, currentVersion, snapshotDir, coreApplications, upgradeTimeoutFlag, err)
+	}
+
+	// the node containers keep the image they were created from, the config records the k3s image they run
+	clusterSpec.K3sImage = targetImage
+	clusterSpec.Save()
+	viper.WriteConfig()
+
+	log.Info().Msgf("k3d cluster %s upgraded to k3s %s, the pre-upgrade snapshot is kept at %s", clusterName, k3sVersionFlag, snapshotDir)
+	log.Info().Msgf("the k3s %s binaries are installed in the node containers, docker still lists them with image %s", k3sVersionFlag, previousImage)
+	return nil
+}
+
+// openVaultPortForward forwards a free local port to vault, the port-forward of a running `kubefirst k3d start`
+// keeps the vault port
+func openVaultPortForward(kubeconfig string) (string, func(), error) {
+	port := k3d.FreePort(k3d.ReadPortForwards().Vault, nil)
+	if port == 0 {
+		return "", nil, errors.New("no free port found for a vault port-forward")
+	}
+	vaultStopChannel := make(chan struct{}, 1)
+	k8s.OpenPortForwardPodWrapper(kubeconfig, "vault-0", "vault", 8200, port, vaultStopChannel)
+	return fmt.Sprintf("http://localhost:%d", port), func() { close(vaultStopChannel) }, nil
+}
+
+// restoreVaultSecrets writes the vault secrets of the snapshot to the restarted vault, the dev mode vault of k3d
+// keeps them in memory
+func restoreVaultSecrets(kubeconfig, snapshotDir string) error {
+	err := k3d.WaitForVault(kubeconfig, false)
+	if err != nil {
+		return err
+	}
+	vaultAddress, closeVaultPortForward, err := openVaultPortForward(kubeconfig)
+	if err != nil {
+		return err
+	}
+	defer closeVaultPortForward()
+	return k3d.RestoreVault(vaultAddress, snapshotDir)
+}
+
+// rollbackK3sUpgrade rolls the upgraded nodes back in reverse order, agents before servers, restores the snapshot
+// and reports the upgrade error
+func rollbackK3sUpgrade(config *k3d.K3dConfig, upgrade k3d.K3sUpgrade, rolled []k3d.Node, previousImage, previousVersion, snapshotDir string, coreApplications []string, timeout time.Duration, upgradeErr error) error {
+	log.Error().Msgf("k3s upgrade failed, rolling %d nodes back to k3s %s: %s", len(rolled), previousVersion, upgradeErr)
+
+	reversed := make([]k3d.Node, 0, len(rolled))
+	for i := len(rolled) - 1; i >= 0; i-- {
+		reversed = append(reversed, rolled[i])
+	}
+	var err error
+	if len(reversed) > 0 {
+		_, err = upgrade.RollNodes(reversed, previousImage, previousVersion)
+	}
+	if err != nil {
+		return fmt.Errorf("k3s upgrade failed: %w\nthe rollback to k3s %s failed too: %s\nthe vault secrets, minio state store and argocd credentials are snapshotted at %s",
+			upgradeErr, previousVersion, err, snapshotDir)
+	}
+
+	err = restoreSnapshot(config, snapshotDir)
+	if err != nil {
+		return fmt.Errorf("k3s upgrade failed and the nodes were rolled back to k3s %s: %w\nrestoring the snapshot at %s failed: %s",
+			previousVersion, upgradeErr, snapshotDir, err)
+	}
+	err = k3d.WaitForArgocdApplications(config.Kubeconfig, config.KubectlClient, coreApplications, timeout)
+	if err != nil {
+		log.Warn().Msgf("the platform was rolled back but %s", err)
+	}
+	return fmt.Errorf("k3s upgrade failed, the nodes were rolled back to k3s %s and the snapshot at %s was restored: %w", previousVersion, snapshotDir, upgradeErr)
+}
+
+// restoreSnapshot restores the vault secrets, the argocd credentials and the minio state store of the snapshot
+func restoreSnapshot(config *k3d.K3dConfig, snapshotDir string) error {
+	err := k3d.WaitForVault(config.Kubeconfig, false)
+	if err != nil {
+		return err
+	}
+	vaultAddress, closeVaultPortForward, err := openVaultPortForward(config.Kubeconfig)
+	if err != nil {
+		return err
+	}
+	defer closeVaultPortForward()
+	return k3d.RestorePlatform(config.Kubeconfig, vaultAddress, config.K1Dir, snapshotDir)
+}
@@ -3,9 +3,9 @@ package local
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"time"
 
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/internal/ssh"
 
 	"github.com/google/uuid"
@@ -95,7 +95,7 @@ func validateLocal(cmd *cobra.Command, args []string) error {
 		addon.AddAddon("metaphor")
 	}
 
-	httpClient := http.DefaultClient
+	httpClient := httpCommon.NewClient()
 	gitHubService := services.NewGitHubService(httpClient)
 	gitHubHandler := handlers.NewGitHubHandler(gitHubService)
 	gitHubAccessToken, err := wrappers.AuthenticateGitHubUserWrapper(config, gitHubHandler)
@@ -113,7 +113,7 @@ func validateLocal(cmd *cobra.Command, args []string) error {
 	// argument to the RunNgrok function, which is then started in a new goroutine.
 	var ctx context.Context
 	ctx, cancelContext = context.WithCancel(context.Background())
-	go pkg.RunNgrok(ctx)
+	go pkg.RunNgrok(ctx, ":80")
 
 	viper.Set("github.atlantis.webhook.secret", pkg.Random(20))
 	viper.Set("github.user", githubUser)
@@ -277,7 +277,7 @@ func validateDestroy(cmd *cobra.Command, args []string) error {
 	config := configs.ReadConfig()
 
 	log.Info().Msg("setting GitHub token...")
-	httpClient := http.DefaultClient
+	httpClient := httpCommon.NewClient()
 	gitHubService := services.NewGitHubService(httpClient)
 	gitHubHandler := handlers.NewGitHubHandler(gitHubService)
 	_, err := wrappers.AuthenticateGitHubUserWrapper(config, gitHubHandler)
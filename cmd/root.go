@@ -3,14 +3,23 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
+	"github.com/kubefirst/kubefirst/cmd/app"
+	"github.com/kubefirst/kubefirst/cmd/bundle"
 	"github.com/kubefirst/kubefirst/cmd/civo"
+	"github.com/kubefirst/kubefirst/cmd/drift"
+	"github.com/kubefirst/kubefirst/cmd/gitops"
 	"github.com/kubefirst/kubefirst/cmd/k3d"
 	"github.com/kubefirst/kubefirst/cmd/local"
+	"github.com/kubefirst/kubefirst/cmd/template"
+	"github.com/kubefirst/kubefirst/cmd/tools"
 	"github.com/kubefirst/kubefirst/configs"
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 
 	"github.com/kubefirst/kubefirst/internal/progressPrinter"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -22,7 +31,11 @@ var rootCmd = &cobra.Command{
 	checkout the docs at docs.kubefirst.io.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		// wire viper config for flags for all commands
-		return configs.InitializeViperConfig(cmd)
+		err := configs.InitializeViperConfig(cmd)
+		if err != nil {
+			return err
+		}
+		return configureHttpClient(cmd)
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("To learn more about kubefirst, run:")
@@ -43,7 +56,40 @@ func Execute() {
 	}
 }
 
+// configureHttpClient applies the proxy, certificate and timeout flags before any command reaches the network,
+// a --ca-bundle is kept in the kubefirst config for later commands
+func configureHttpClient(cmd *cobra.Command) error {
+	caBundle, err := cmd.Flags().GetString("ca-bundle")
+	if err != nil {
+		return err
+	}
+
+	httpTimeouts, err := cmd.Flags().GetStringToString("http-timeout")
+	if err != nil {
+		return err
+	}
+
+	if caBundle == "" {
+		caBundle = viper.GetString("flags.ca-bundle")
+	} else {
+		caBundle, err = filepath.Abs(caBundle)
+		if err != nil {
+			return err
+		}
+		viper.Set("flags.ca-bundle", caBundle)
+		viper.WriteConfig()
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	return httpCommon.Configure(caBundle, httpTimeouts, fmt.Sprintf("%s/.k1", homeDir))
+}
+
 func init() {
 	cobra.OnInitialize()
-	rootCmd.AddCommand(local.NewCommand(), civo.NewCommand(), k3d.NewCommand())
+	rootCmd.PersistentFlags().String("ca-bundle", "", "a PEM file of extra certificate authorities to trust, i.e. of a TLS intercepting proxy, also passed to git, terraform, helm and kubectl")
+	rootCmd.PersistentFlags().StringToString("http-timeout", map[string]string{}, "request timeouts by host, * for any other host (i.e. api.github.com=30s,*=2m), the civo api client sets its own transport and is not covered")
+	rootCmd.AddCommand(local.NewCommand(), app.NewCommand(), civo.NewCommand(), k3d.NewCommand(), drift.NewCommand(), gitops.NewCommand(), template.NewCommand(), tools.NewCommand(), bundle.NewCommand())
 }
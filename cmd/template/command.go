@@ -0,0 +1,55 @@
+package template
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Lint
+	cloudProviderFlag        string
+	clusterTypeFlag          string
+	gitProviderFlag          string
+	gitopsTemplateBranchFlag string
+	gitopsTemplatePathFlag   string
+	gitopsTemplateURLFlag    string
+
+	// Supported providers
+	supportedCloudProviders = []string{"civo", "k3d"}
+	supportedGitProviders   = []string{"github", "gitlab"}
+)
+
+func NewCommand() *cobra.Command {
+
+	templateCmd := &cobra.Command{
+		Use:   "template",
+		Short: "kubefirst gitops template utilities",
+		Long:  "kubefirst template",
+	}
+
+	// on error, doesnt show helper/usage
+	templateCmd.SilenceUsage = true
+
+	// wire up new commands
+	templateCmd.AddCommand(Lint())
+
+	return templateCmd
+}
+
+func Lint() *cobra.Command {
+	lintCmd := &cobra.Command{
+		Use:   "lint",
+		Short: "validate a gitops-template before installing",
+		Long:  "checks a gitops-template directory or git ref for the driver, cluster and terraform content and the tokens the create command expects for a cloud provider, git provider and cluster type",
+		RunE:  runLint,
+	}
+
+	lintCmd.Flags().StringVar(&cloudProviderFlag, "cloud-provider", "k3d", fmt.Sprintf("the cloud provider to lint against - one of: %s", supportedCloudProviders))
+	lintCmd.Flags().StringVar(&clusterTypeFlag, "cluster-type", "mgmt", "the type of cluster to lint against (i.e. mgmt|workload)")
+	lintCmd.Flags().StringVar(&gitProviderFlag, "git-provider", "github", fmt.Sprintf("the git provider to lint against - one of: %s", supportedGitProviders))
+	lintCmd.Flags().StringVar(&gitopsTemplateBranchFlag, "gitops-template-branch", "main", "the branch to clone for the gitops-template repository")
+	lintCmd.Flags().StringVar(&gitopsTemplatePathFlag, "gitops-template-path", "", "a local gitops-template directory or .tar.gz archive to lint instead of cloning --gitops-template-url")
+	lintCmd.Flags().StringVar(&gitopsTemplateURLFlag, "gitops-template-url", "https://github.com/kubefirst/gitops-template.git", "the fully qualified url to the gitops-template repository to clone")
+	return lintCmd
+}
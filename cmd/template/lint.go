@@ -0,0 +1,93 @@
+package template
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kubefirst/kubefirst/configs"
+	"github.com/kubefirst/kubefirst/internal/gitClient"
+	"github.com/kubefirst/kubefirst/internal/gitopsTemplate"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func runLint(cmd *cobra.Command, args []string) error {
+	cloudProvider, err := cmd.Flags().GetString("cloud-provider")
+	if err != nil {
+		return err
+	}
+
+	clusterType, err := cmd.Flags().GetString("cluster-type")
+	if err != nil {
+		return err
+	}
+
+	gitProvider, err := cmd.Flags().GetString("git-provider")
+	if err != nil {
+		return err
+	}
+
+	gitopsTemplateBranch, err := cmd.Flags().GetString("gitops-template-branch")
+	if err != nil {
+		return err
+	}
+
+	gitopsTemplatePath, err := cmd.Flags().GetString("gitops-template-path")
+	if err != nil {
+		return err
+	}
+
+	gitopsTemplateURL, err := cmd.Flags().GetString("gitops-template-url")
+	if err != nil {
+		return err
+	}
+
+	templateDir := gitopsTemplatePath
+	if gitClient.IsTarGz(gitopsTemplatePath) {
+		templateDir, err = os.MkdirTemp("", "gitops-template-lint-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(templateDir)
+
+		err = gitClient.CopyTemplateFromPath(gitopsTemplatePath, templateDir)
+		if err != nil {
+			return err
+		}
+	} else if templateDir == "" {
+		templateDir, err = os.MkdirTemp("", "gitops-template-lint-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(templateDir)
+
+		log.Info().Msgf("cloning url: %s - git ref: %s", gitopsTemplateURL, gitopsTemplateBranch)
+		_, err = gitClient.Clone(gitopsTemplateBranch, templateDir, gitopsTemplateURL)
+		if err != nil {
+			return fmt.Errorf("error cloning %s at %s: %s", gitopsTemplateURL, gitopsTemplateBranch, err)
+		}
+	}
+
+	findings, err := gitopsTemplate.Lint(templateDir, gitopsTemplate.LintOptions{
+		CloudProvider:    cloudProvider,
+		GitProvider:      gitProvider,
+		ClusterType:      clusterType,
+		KubefirstVersion: configs.K1Version,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(findings) == 0 {
+		fmt.Printf("gitops template is valid for %s-%s %s clusters\n", cloudProvider, gitProvider, clusterType)
+		return nil
+	}
+
+	fmt.Print(gitopsTemplate.FormatFindings(findings))
+
+	if gitopsTemplate.HasErrors(findings) {
+		return fmt.Errorf("gitops template is not valid for %s-%s %s clusters", cloudProvider, gitProvider, clusterType)
+	}
+
+	return nil
+}
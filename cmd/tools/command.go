@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Install
+	useSystemToolsFlag bool
+	versionsFlag       map[string]string
+
+	// Prune
+	dryRunFlag bool
+)
+
+func NewCommand() *cobra.Command {
+
+	toolsCmd := &cobra.Command{
+		Use:   "tools",
+		Short: "manage the tool versions used by the cluster",
+		Long:  "kubefirst keeps every downloaded tool version in $HOME/.k1/tools/cache and pins the versions used by the cluster in the kubefirst config, so upgrading the cli does not change the helm, kubectl or terraform an existing cluster runs with",
+	}
+
+	// on error, doesnt show helper/usage
+	toolsCmd.SilenceUsage = true
+
+	// wire up new commands
+	toolsCmd.AddCommand(List(), Install(), Prune())
+
+	return toolsCmd
+}
+
+func List() *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "list the cached tool versions and the versions selected for the cluster",
+		Long:  "lists every tool version in the tool cache with its source url and sha256, marking the version each tool of the cluster is pinned to",
+		RunE:  runList,
+	}
+
+	return listCmd
+}
+
+func Install() *cobra.Command {
+	installCmd := &cobra.Command{
+		Use:   "install [tool]...",
+		Short: "install the tool versions selected for the cluster",
+		Long:  "downloads and verifies the pinned version of each tool (every tool of the cluster when none are named) into the tool cache, points $HOME/.k1/tools at it and records the pin in the kubefirst config",
+		RunE:  runInstall,
+	}
+
+	installCmd.Flags().BoolVar(&useSystemToolsFlag, "use-system-tools", false, "use tools found on PATH when their version is supported instead of downloading them")
+	installCmd.Flags().StringToStringVar(&versionsFlag, "version", map[string]string{}, "pin tools to other versions (i.e. terraform=1.4.6,helm=v3.11.1)")
+
+	return installCmd
+}
+
+func Prune() *cobra.Command {
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "remove cached tool versions the cluster does not use",
+		Long:  "removes every version from the tool cache that is not the version a tool of the cluster is pinned to",
+		RunE:  runPrune,
+	}
+
+	pruneCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "print the versions that would be removed without removing them")
+
+	return pruneCmd
+}
@@ -0,0 +1,195 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/kubefirst/kubefirst/internal/civo"
+	"github.com/kubefirst/kubefirst/internal/downloadManager"
+	"github.com/kubefirst/kubefirst/internal/k3d"
+	"github.com/kubefirst/kubefirst/internal/reports"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// clusterTools returns the tools of the cluster in the kubefirst config, a k3d cluster when none was created yet
+func clusterTools() (string, []downloadManager.ToolSpec, error) {
+	cloudProvider := viper.GetString("kubefirst.cloud-provider")
+
+	switch cloudProvider {
+	case "civo":
+		config := civo.GetConfig(viper.GetString("flags.cluster-name"), viper.GetString("flags.domain-name"), viper.GetString("flags.github-owner"))
+		return config.ToolsDir, civo.Tools(config), nil
+	case "k3d", "":
+		gitProvider := viper.GetString("flags.git-provider")
+		config := k3d.GetConfig(gitProvider, viper.GetString(fmt.Sprintf("flags.%s-owner", gitProvider)))
+		return config.ToolsDir, k3d.Tools(config), nil
+	}
+	return "", nil, fmt.Errorf("tools are not managed for cloud provider %s", cloudProvider)
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	toolsDir, tools, err := clusterTools()
+	if err != nil {
+		return err
+	}
+
+	cache := downloadManager.NewToolCache(toolsDir)
+	records, err := cache.Records()
+	if err != nil {
+		return err
+	}
+
+	selected := map[string]bool{}
+	var summary bytes.Buffer
+	w := tabwriter.NewWriter(&summary, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TOOL\tVERSION\tSELECTED\tSHA256\tSOURCE")
+	for _, tool := range tools {
+		selected[downloadManager.ToolKey(tool.Name, tool.Version())] = true
+	}
+	for _, record := range records {
+		mark := ""
+		if selected[downloadManager.ToolKey(record.Name, record.Version)] {
+			mark = "*"
+		}
+		// hand edited or older manifests can hold a short or empty digest
+		digest := record.SHA256
+		if len(digest) > 12 {
+			digest = digest[:12]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", record.Name, record.Version, mark, digest, record.URL)
+	}
+	w.Flush()
+
+	for _, tool := range tools {
+		if systemPath := viper.GetString(downloadManager.ToolPathKey(tool.Name)); systemPath != "" {
+			fmt.Fprintf(&summary, "\n%s %s is used from the system at %s", tool.Name, tool.Version(), systemPath)
+			continue
+		}
+		if _, ok := cache.Lookup(tool.Name, tool.Version()); !ok {
+			fmt.Fprintf(&summary, "\n%s %s is selected but not installed, run `kubefirst tools install %s`", tool.Name, tool.Version(), tool.Name)
+		}
+	}
+
+	fmt.Println(reports.StyleMessageBlackAndWhite(summary.String()))
+	return nil
+}
+
+func runInstall(cmd *cobra.Command, args []string) error {
+	useSystemTools, err := cmd.Flags().GetBool("use-system-tools")
+	if err != nil {
+		return err
+	}
+
+	versions, err := cmd.Flags().GetStringToString("version")
+	if err != nil {
+		return err
+	}
+
+	toolsDir, tools, err := clusterTools()
+	if err != nil {
+		return err
+	}
+
+	tools, err = selectTools(tools, args)
+	if err != nil {
+		return err
+	}
+
+	for name, version := range versions {
+		if !hasTool(tools, name) {
+			return fmt.Errorf("--version names %s which is not being installed", name)
+		}
+		log.Info().Msgf("pinning %s to %s", name, version)
+		viper.Set(downloadManager.ToolVersionKey(name), version)
+	}
+
+	err = downloadManager.InstallTools(toolsDir, tools, k3d.LocalhostOS, k3d.LocalhostARCH, useSystemTools)
+	if err != nil {
+		return err
+	}
+	viper.WriteConfig()
+
+	for _, tool := range tools {
+		fmt.Printf("%s %s installed at %s\n", tool.Name, tool.Version(), tool.Path)
+	}
+	return nil
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+
+	toolsDir, tools, err := clusterTools()
+	if err != nil {
+		return err
+	}
+
+	pinned := map[string]bool{}
+	for _, tool := range tools {
+		pinned[downloadManager.ToolKey(tool.Name, tool.Version())] = true
+	}
+
+	pruned, err := downloadManager.NewToolCache(toolsDir).Prune(func(tool, version string) bool {
+		return pinned[downloadManager.ToolKey(tool, version)]
+	}, dryRun)
+	if err != nil {
+		return err
+	}
+
+	action := "removed"
+	if dryRun {
+		action = "would be removed"
+	}
+	for _, record := range pruned {
+		fmt.Printf("%s %s %s\n", record.Name, record.Version, action)
+	}
+	fmt.Printf("%d cached tool versions %s\n", len(pruned), action)
+	return nil
+}
+
+// selectTools narrows tools to the named ones, every tool when no names are given
+func selectTools(tools []downloadManager.ToolSpec, names []string) ([]downloadManager.ToolSpec, error) {
+	if len(names) == 0 {
+		return tools, nil
+	}
+
+	selected := []downloadManager.ToolSpec{}
+	for _, name := range names {
+		found := false
+		for _, tool := range tools {
+			if tool.Name == name {
+				selected = append(selected, tool)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown tool %s, the cluster uses %s", name, toolNames(tools))
+		}
+	}
+	return selected, nil
+}
+
+func hasTool(tools []downloadManager.ToolSpec, name string) bool {
+	for _, tool := range tools {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func toolNames(tools []downloadManager.ToolSpec) string {
+	var names bytes.Buffer
+	for i, tool := range tools {
+		if i > 0 {
+			names.WriteString(", ")
+		}
+		names.WriteString(tool.Name)
+	}
+	return names.String()
+}
@@ -2,7 +2,6 @@ package argocd
 
 import (
 	"bytes"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,6 +16,7 @@ import (
 
 	"github.com/kubefirst/kubefirst/configs"
 	"github.com/kubefirst/kubefirst/internal/argocdModel"
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/pkg"
 	"github.com/spf13/viper"
 	yaml2 "gopkg.in/yaml.v2"
@@ -279,12 +279,16 @@ func Sync(httpClient pkg.HTTPDoer, applicationName string, argoCDToken string) (
 // GetArgoCDToken expects ArgoCD username and password, and returns a ArgoCD Bearer Token. ArgoCD username and password
 // are stored in the viper file.
 func GetArgoCDToken(username string, password string) (string, error) {
+	return GetArgoCDTokenWithURL(LocalBaseURL(), username, password)
+}
+
+// GetArgoCDTokenWithURL is GetArgoCDToken for an ArgoCD api other than pkg.ArgoCDLocalBaseURL, i.e. a port-forward on
+// another local port
+func GetArgoCDTokenWithURL(argoCDBaseURL string, username string, password string) (string, error) {
 
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	customTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	httpClient := http.Client{Transport: customTransport}
+	httpClient := http.Client{Transport: httpCommon.NewTransport(true)}
 
-	url := pkg.ArgoCDLocalBaseURL + "/session"
+	url := argoCDBaseURL + "/session"
 
 	argoCDConfig := argocdModel.SessionSessionCreateRequest{
 		Username: username,
@@ -344,13 +348,13 @@ func GetArgocdAuthToken(dryRun bool) string {
 
 	payload := strings.NewReader(fmt.Sprintf("{\n\t\"username\":\"admin\",\"password\":\"%s\"\n}", viper.GetString("argocd.admin.password")))
 
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 	req, err := http.NewRequest("POST", url, payload)
 	if err != nil {
 		log.Fatal().Err(err).Msg("error getting auth token from argocd")
 	}
 
 	client := &http.Client{
+		Transport: httpCommon.NewTransport(true),
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			// N.B.: when used in production, also check for redirect loops
 			return nil
@@ -414,7 +418,7 @@ func SyncArgocdApplication(dryRun bool, applicationName, argocdAuthToken string)
 
 	// todo need to replace this with a curl wrapper and see if it WORKS
 
-	url := fmt.Sprintf("https://localhost:8080/api/v1/applications/%s/sync", applicationName)
+	url := fmt.Sprintf("%s/applications/%s/sync", LocalBaseURL(), applicationName)
 	var outb bytes.Buffer
 
 	_, _, err := pkg.ExecShellReturnStrings("curl", "-k", "-L", "-X", "POST", url, "-H", fmt.Sprintf("Authorization: Bearer %s", argocdAuthToken))
@@ -495,11 +499,9 @@ func GetArgoCDApplication(token string, applicationName string) (argocdModel.V1a
 
 	// todo: instantiate a new client on every http request isn't a good idea, we might want to work with methods and
 	//       provide resources via structs.
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	customTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	httpClient := http.Client{Transport: customTransport}
+	httpClient := http.Client{Transport: httpCommon.NewTransport(true)}
 
-	url := pkg.ArgoCDLocalBaseURL + "/applications/" + applicationName
+	url := LocalBaseURL() + "/applications/" + applicationName
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		log.Error().Err(err).Msg("")
@@ -627,7 +629,21 @@ func GetArgoEndpoint() string {
 	if viper.GetString("argocd.local.service") != "" {
 		argoCDLocalEndpoint = viper.GetString("argocd.local.service")
 	} else {
-		argoCDLocalEndpoint = pkg.ArgocdPortForwardURL
+		argoCDLocalEndpoint = fmt.Sprintf("http://localhost:%d", localPort())
 	}
 	return argoCDLocalEndpoint
 }
+
+// LocalBaseURL is pkg.ArgoCDLocalBaseURL on the host port of the ArgoCD port-forward
+func LocalBaseURL() string {
+	return fmt.Sprintf("https://localhost:%d/api/v1", localPort())
+}
+
+// localPort is the host port of the ArgoCD port-forward, k3d stores it when --argocd-port or --remap-ports moves it
+// off 8080
+func localPort() int {
+	if port := viper.GetInt("k3d.port-forwards.argocd"); port != 0 {
+		return port
+	}
+	return 8080
+}
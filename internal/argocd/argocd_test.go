@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/kubefirst/kubefirst/configs"
+	"github.com/kubefirst/kubefirst/internal/argocd"
 	"github.com/kubefirst/kubefirst/pkg"
 	"github.com/spf13/viper"
 )
@@ -79,3 +80,19 @@ func TestArgoWorkflowLivenessIntegration(t *testing.T) {
 		t.Errorf("wanted http status code 200, got %d", res.StatusCode)
 	}
 }
+
+func TestLocalBaseURL(t *testing.T) {
+	defer viper.Reset()
+
+	if url := argocd.LocalBaseURL(); url != pkg.ArgoCDLocalBaseURL {
+		t.Errorf("LocalBaseURL() = %s, want %s", url, pkg.ArgoCDLocalBaseURL)
+	}
+
+	viper.Set("k3d.port-forwards.argocd", 18080)
+	if url := argocd.LocalBaseURL(); url != "https://localhost:18080/api/v1" {
+		t.Errorf("LocalBaseURL() = %s, want the remapped port 18080", url)
+	}
+	if endpoint := argocd.GetArgoEndpoint(); endpoint != "http://localhost:18080" {
+		t.Errorf("GetArgoEndpoint() = %s, want the remapped port 18080", endpoint)
+	}
+}
@@ -0,0 +1,140 @@
+package bundle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kubefirst/kubefirst/internal/downloadManager"
+	"github.com/kubefirst/kubefirst/pkg"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// Bundle is an extracted and verified offline install bundle
+type Bundle struct {
+	Dir      string
+	Manifest *Manifest
+}
+
+// Open extracts the bundle at archivePath to dir, replacing any previous content, and verifies every file
+// against the manifest and the platform against localOs and localArchitecture
+func Open(archivePath, dir, localOs, localArchitecture string) (*Bundle, error) {
+	log.Info().Msgf("extracting bundle %s to %s", archivePath, dir)
+
+	err := os.RemoveAll(dir)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	// the manifest only covers the files it lists, the extraction rejects entries and symlinks leaving dir
+	err = pkg.ExtractTarGz(archivePath, dir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error extracting bundle %s: %w", archivePath, err)
+	}
+
+	manifest, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.OS != localOs || manifest.Arch != localArchitecture {
+		return nil, fmt.Errorf("bundle %s was created for %s/%s and cannot be used on %s/%s", archivePath, manifest.OS, manifest.Arch, localOs, localArchitecture)
+	}
+	err = manifest.verify(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Msgf("verified bundle created by kubefirst %s at %s", manifest.KubefirstVersion, manifest.CreatedAt)
+	return &Bundle{Dir: dir, Manifest: manifest}, nil
+}
+
+// Template returns a bundled template with its absolute path
+func (b *Bundle) Template(name string) (Template, error) {
+	for _, template := range b.Manifest.Templates {
+		if template.Name == name {
+			template.Path = b.path(template.Path)
+			return template, nil
+		}
+	}
+	return Template{}, fmt.Errorf("the bundle has no %s template", name)
+}
+
+// Chart returns a bundled chart with its absolute path
+func (b *Bundle) Chart(name, version string) (Chart, error) {
+	for _, chart := range b.Manifest.Charts {
+		if chart.Name == name && chart.Version == version {
+			chart.Path = b.path(chart.Path)
+			return chart, nil
+		}
+	}
+	return Chart{}, fmt.Errorf("the bundle has no %s %s chart", name, version)
+}
+
+// ImageArchive returns the absolute path of a bundled image archive, false when the bundle was created without it
+func (b *Bundle) ImageArchive(name string) (string, bool) {
+	for _, archive := range b.Manifest.Images {
+		if archive.Name == name {
+			return b.path(archive.Path), true
+		}
+	}
+	return "", false
+}
+
+// LoadImages loads a bundled image archive into the local docker daemon, bundles without it are skipped
+func (b *Bundle) LoadImages(name string) error {
+	archivePath, ok := b.ImageArchive(name)
+	if !ok {
+		log.Info().Msgf("bundle has no %s images, continuing", name)
+		return nil
+	}
+	return loadImages(archivePath)
+}
+
+// InstallTools installs the bundled version of every tool into the tool cache of toolsDir, points each tool path
+// at it and pins it. A tool the cluster already pins to a version the bundle does not hold is an error.
+func (b *Bundle) InstallTools(toolsDir string, tools []downloadManager.ToolSpec) error {
+	from := downloadManager.ToolCache{Dir: filepath.Join(b.Dir, "tools")}
+	cache := downloadManager.NewToolCache(toolsDir)
+
+	for _, tool := range tools {
+		version, err := b.toolVersion(tool)
+		if err != nil {
+			return err
+		}
+		_, err = cache.Import(from, tool.Name, version)
+		if err != nil {
+			return err
+		}
+		err = cache.Activate(tool.Name, version, tool.Path)
+		if err != nil {
+			return err
+		}
+		viper.Set(downloadManager.ToolVersionKey(tool.Name), version)
+		log.Info().Msgf("%s %s installed from the bundle at %s", tool.Name, version, tool.Path)
+	}
+	return nil
+}
+
+func (b *Bundle) toolVersion(tool downloadManager.ToolSpec) (string, error) {
+	pinned := viper.GetString(downloadManager.ToolVersionKey(tool.Name))
+	for _, record := range b.Manifest.Tools {
+		if record.Name != tool.Name {
+			continue
+		}
+		if pinned == "" || pinned == record.Version {
+			return record.Version, nil
+		}
+	}
+	if pinned != "" {
+		return "", fmt.Errorf("the cluster pins %s %s which is not in the bundle", tool.Name, pinned)
+	}
+	return "", fmt.Errorf("the bundle has no %s", tool.Name)
+}
+
+func (b *Bundle) path(rel string) string {
+	return filepath.Join(b.Dir, filepath.FromSlash(rel))
+}
@@ -0,0 +1,166 @@
+package bundle
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubefirst/kubefirst/internal/downloadManager"
+	"github.com/spf13/viper"
+)
+
+// writeTestBundle writes a bundle holding kubectl v1.0.0, a gitops template and an argo-cd chart
+func writeTestBundle(t *testing.T, tamper bool) string {
+	t.Helper()
+	staging := t.TempDir()
+
+	tools := downloadManager.ToolCache{Dir: filepath.Join(staging, "tools")}
+	binaryPath := tools.BinaryPath("kubectl", "v1.0.0")
+	os.MkdirAll(filepath.Dir(binaryPath), 0755)
+	os.WriteFile(binaryPath, []byte("kubectl"), 0755)
+	err := downloadManager.RecordTool(tools.Dir, downloadManager.ToolRecord{Name: "kubectl", Version: "v1.0.0", Path: binaryPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.MkdirAll(filepath.Join(staging, "templates", "gitops"), 0755)
+	os.WriteFile(filepath.Join(staging, "templates", "gitops", "README.md"), []byte("gitops"), 0644)
+	os.MkdirAll(filepath.Join(staging, "charts"), 0755)
+	os.WriteFile(filepath.Join(staging, "charts", "argo-cd-4.10.5.tgz"), []byte("chart"), 0644)
+
+	manifest := &Manifest{
+		OS:        "linux",
+		Arch:      "amd64",
+		Tools:     []downloadManager.ToolRecord{{Name: "kubectl", Version: "v1.0.0", Path: "tools/kubectl/v1.0.0/kubectl"}},
+		Templates: []Template{{Name: "gitops", URL: "https://example.com/gitops-template.git", Ref: "2.0.0", Path: "templates/gitops"}},
+		Charts:    []Chart{{Name: "argo-cd", Version: "4.10.5", Path: "charts/argo-cd-4.10.5.tgz"}},
+	}
+	manifest.Files, err = fileDigests(staging)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tamper {
+		os.WriteFile(filepath.Join(staging, "charts", "argo-cd-4.10.5.tgz"), []byte("tampered"), 0644)
+	}
+	err = writeManifest(staging, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "bundle.tgz")
+	err = writeTarGz(staging, archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   bool
+		localOs  string
+		wantErr  bool
+		checksum bool
+	}{
+		{name: "valid bundle", localOs: "linux"},
+		{name: "tampered file", tamper: true, localOs: "linux", wantErr: true, checksum: true},
+		{name: "other platform", localOs: "darwin", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archivePath := writeTestBundle(t, tt.tamper)
+
+			b, err := Open(archivePath, filepath.Join(t.TempDir(), "bundle"), tt.localOs, "amd64")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.checksum && !errors.Is(err, downloadManager.ErrChecksumMismatch) {
+				t.Errorf("Open() error = %v, want a checksum mismatch", err)
+			}
+			if err != nil {
+				return
+			}
+
+			template, err := b.Template("gitops")
+			if err != nil || !downloadManager.FileExists(filepath.Join(template.Path, "README.md")) || template.Ref != "2.0.0" {
+				t.Errorf("Template() = %+v, %v", template, err)
+			}
+			if _, err := b.Template("metaphor"); err == nil {
+				t.Error("Template() of a template missing from the bundle did not fail")
+			}
+			chart, err := b.Chart("argo-cd", "4.10.5")
+			if err != nil || !downloadManager.FileExists(chart.Path) {
+				t.Errorf("Chart() = %+v, %v", chart, err)
+			}
+			if _, ok := b.ImageArchive(PlatformImages); ok {
+				t.Error("ImageArchive() found images in a bundle created without them")
+			}
+		})
+	}
+}
+
+func TestOpenSymlinkEscape(t *testing.T) {
+	staging := t.TempDir()
+	os.MkdirAll(filepath.Join(staging, "templates"), 0755)
+	// the manifest does not list the symlink, extracting it has to fail before the manifest is verified
+	err := os.Symlink("../../../outside", filepath.Join(staging, "templates", "gitops"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	archivePath := filepath.Join(t.TempDir(), "bundle.tgz")
+	err = writeTarGz(staging, archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Open(archivePath, filepath.Join(t.TempDir(), "bundle"), "linux", "amd64")
+	if err == nil || !strings.Contains(err.Error(), "escapes the destination directory") {
+		t.Errorf("Open() error = %v, want the symlink rejected", err)
+	}
+}
+
+func TestInstallTools(t *testing.T) {
+	tests := []struct {
+		name    string
+		pinned  string
+		wantErr bool
+	}{
+		{name: "nothing pinned"},
+		{name: "pinned to the bundled version", pinned: "v1.0.0"},
+		{name: "pinned to another version", pinned: "v2.0.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer viper.Reset()
+			viper.Set(downloadManager.ToolVersionKey("kubectl"), tt.pinned)
+
+			b, err := Open(writeTestBundle(t, false), filepath.Join(t.TempDir(), "bundle"), "linux", "amd64")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			toolsDir := t.TempDir()
+			toolPath := filepath.Join(toolsDir, "kubectl")
+			err = b.InstallTools(toolsDir, []downloadManager.ToolSpec{{Name: "kubectl", DefaultVersion: "v3.0.0", Path: toolPath}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("InstallTools() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			content, err := os.ReadFile(toolPath)
+			if err != nil || string(content) != "kubectl" {
+				t.Errorf("InstallTools() installed %q, %v", content, err)
+			}
+			if got := viper.GetString(downloadManager.ToolVersionKey("kubectl")); got != "v1.0.0" {
+				t.Errorf("InstallTools() pinned %q, want v1.0.0", got)
+			}
+		})
+	}
+}
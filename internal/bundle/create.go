@@ -0,0 +1,175 @@
+package bundle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kubefirst/kubefirst/configs"
+	"github.com/kubefirst/kubefirst/internal/downloadManager"
+	"github.com/kubefirst/kubefirst/internal/gitClient"
+	"github.com/kubefirst/kubefirst/internal/helm"
+	"github.com/kubefirst/kubefirst/internal/k3d"
+	"github.com/rs/zerolog/log"
+)
+
+// Image archive names
+const (
+	// ClusterImages are loaded into the local docker daemon before the k3d cluster is created
+	ClusterImages = "cluster"
+	// PlatformImages are imported into the k3d cluster nodes
+	PlatformImages = "platform"
+)
+
+// Options selects the content of a new bundle
+type Options struct {
+	Output            string
+	LocalOs           string
+	LocalArchitecture string
+	Tools             []downloadManager.ToolSpec
+	Templates         []Template
+	Charts            []helm.HelmRepo
+	// IncludeImages saves the k3d cluster images and the images of Charts and Images with docker
+	IncludeImages bool
+	Images        []string
+}
+
+// Create downloads everything an offline k3d install needs and writes it with a manifest to opts.Output
+func Create(opts Options) (*Manifest, error) {
+	staging, err := os.MkdirTemp("", "kubefirst-bundle")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	manifest := &Manifest{
+		KubefirstVersion: configs.K1Version,
+		CreatedAt:        time.Now().UTC(),
+		OS:               opts.LocalOs,
+		Arch:             opts.LocalArchitecture,
+	}
+
+	//* tools, the tools directory is a tool cache
+	tools := downloadManager.ToolCache{Dir: filepath.Join(staging, "tools")}
+	for _, tool := range opts.Tools {
+		download, err := downloadManager.NewToolDownload(tool.Name, tool.Version(), opts.LocalOs, opts.LocalArchitecture)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tools.Install(download)
+		if err != nil {
+			return nil, err
+		}
+	}
+	records, err := tools.Records()
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		record.Path, _ = filepath.Rel(staging, record.Path)
+		record.Path = filepath.ToSlash(record.Path)
+		manifest.Tools = append(manifest.Tools, record)
+	}
+
+	//* templates, stored without git history
+	for _, template := range opts.Templates {
+		log.Info().Msgf("cloning %s template %s at %s", template.Name, template.URL, template.Ref)
+		cloneDir := filepath.Join(staging, "clones", template.Name)
+		_, err := gitClient.Clone(template.Ref, cloneDir, template.URL)
+		if err != nil {
+			return nil, fmt.Errorf("error cloning %s at %s: %w", template.URL, template.Ref, err)
+		}
+		template.Path = "templates/" + template.Name
+		err = gitClient.CopyTemplateFromPath(cloneDir, filepath.Join(staging, filepath.FromSlash(template.Path)))
+		if err != nil {
+			return nil, err
+		}
+		manifest.Templates = append(manifest.Templates, template)
+	}
+	err = os.RemoveAll(filepath.Join(staging, "clones"))
+	if err != nil {
+		return nil, err
+	}
+
+	//* helm charts
+	chartsDir := filepath.Join(staging, "charts")
+	err = os.MkdirAll(chartsDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	platformImages := append([]string{}, opts.Images...)
+	for _, chart := range opts.Charts {
+		chartPath, err := helm.PullChart(chart, chartsDir)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Charts = append(manifest.Charts, Chart{
+			Name:    chart.ChartName,
+			RepoURL: chart.RepoURL,
+			Version: chart.ChartVersion,
+			Path:    "charts/" + filepath.Base(chartPath),
+		})
+
+		if opts.IncludeImages {
+			chart.ChartArchive = chartPath
+			images, err := helm.ChartImages(tools.BinaryPath("helm", toolVersion(opts.Tools, "helm")), chart)
+			if err != nil {
+				return nil, err
+			}
+			platformImages = append(platformImages, images...)
+		}
+	}
+
+	//* container images
+	if opts.IncludeImages {
+		clusterImages, err := k3d.ClusterImages(tools.BinaryPath("k3d", toolVersion(opts.Tools, "k3d")))
+		if err != nil {
+			return nil, err
+		}
+
+		imagesDir := filepath.Join(staging, "images")
+		err = os.MkdirAll(imagesDir, 0755)
+		if err != nil {
+			return nil, err
+		}
+		for _, archive := range []ImageArchive{
+			{Name: ClusterImages, Path: "images/cluster.tar", Images: clusterImages},
+			{Name: PlatformImages, Path: "images/platform.tar", Images: platformImages},
+		} {
+			if len(archive.Images) == 0 {
+				continue
+			}
+			err = saveImages(archive.Images, filepath.Join(staging, filepath.FromSlash(archive.Path)))
+			if err != nil {
+				return nil, err
+			}
+			manifest.Images = append(manifest.Images, archive)
+		}
+	}
+
+	manifest.Files, err = fileDigests(staging)
+	if err != nil {
+		return nil, err
+	}
+	err = writeManifest(staging, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Msgf("writing bundle %s", opts.Output)
+	err = writeTarGz(staging, opts.Output)
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func toolVersion(tools []downloadManager.ToolSpec, name string) string {
+	for _, tool := range tools {
+		if tool.Name == name {
+			return tool.Version()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,36 @@
+package bundle
+
+import (
+	"fmt"
+
+	"github.com/kubefirst/kubefirst/pkg"
+	"github.com/rs/zerolog/log"
+)
+
+// saveImages pulls images and saves them to a single docker archive
+func saveImages(images []string, archivePath string) error {
+	for _, image := range images {
+		log.Info().Msgf("pulling image %s", image)
+		_, _, err := pkg.ExecShellReturnStrings("docker", "pull", image)
+		if err != nil {
+			return fmt.Errorf("error pulling image %s: %w", image, err)
+		}
+	}
+
+	args := append([]string{"save", "--output", archivePath}, images...)
+	_, _, err := pkg.ExecShellReturnStrings("docker", args...)
+	if err != nil {
+		return fmt.Errorf("error saving images to %s: %w", archivePath, err)
+	}
+	return nil
+}
+
+// loadImages loads a docker archive into the local docker daemon
+func loadImages(archivePath string) error {
+	log.Info().Msgf("loading images from %s", archivePath)
+	_, _, err := pkg.ExecShellReturnStrings("docker", "load", "--input", archivePath)
+	if err != nil {
+		return fmt.Errorf("error loading images from %s: %w", archivePath, err)
+	}
+	return nil
+}
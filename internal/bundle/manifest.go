@@ -0,0 +1,112 @@
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kubefirst/kubefirst/internal/downloadManager"
+)
+
+// ManifestFile describes the content of a bundle, it is stored at the root of the archive
+const ManifestFile = "bundle.json"
+
+// Manifest is the content of ManifestFile, every path is relative to the bundle root
+type Manifest struct {
+	KubefirstVersion string                       `json:"kubefirstVersion"`
+	CreatedAt        time.Time                    `json:"createdAt"`
+	OS               string                       `json:"os"`
+	Arch             string                       `json:"arch"`
+	Tools            []downloadManager.ToolRecord `json:"tools"`
+	Templates        []Template                   `json:"templates"`
+	Charts           []Chart                      `json:"charts"`
+	Images           []ImageArchive               `json:"images,omitempty"`
+	// Files is the sha256 of every other file in the bundle keyed by its slash separated path
+	Files map[string]string `json:"files"`
+}
+
+// Template is a template repository at the git ref it was cloned from
+type Template struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Ref  string `json:"ref"`
+	Path string `json:"path"`
+}
+
+// Chart is a helm chart archive
+type Chart struct {
+	Name    string `json:"name"`
+	RepoURL string `json:"repoURL"`
+	Version string `json:"version"`
+	Path    string `json:"path"`
+}
+
+// ImageArchive is a `docker save` archive of images
+type ImageArchive struct {
+	Name   string   `json:"name"`
+	Path   string   `json:"path"`
+	Images []string `json:"images"`
+}
+
+func readManifest(dir string) (*Manifest, error) {
+	content, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a kubefirst bundle: %w", dir, err)
+	}
+
+	manifest := &Manifest{}
+	err = json.Unmarshal(content, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the bundle manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func writeManifest(dir string, manifest *Manifest) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ManifestFile), content, 0644)
+}
+
+// fileDigests returns the sha256 of every file under dir keyed by its slash separated relative path
+func fileDigests(dir string) (map[string]string, error) {
+	digests := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == ManifestFile {
+			return nil
+		}
+		digest, err := downloadManager.FileSHA256(path)
+		if err != nil {
+			return err
+		}
+		digests[filepath.ToSlash(rel)] = digest
+		return nil
+	})
+	return digests, err
+}
+
+// verify checks every file listed in the manifest against its sha256
+func (m *Manifest) verify(dir string) error {
+	for rel, expected := range m.Files {
+		digest, err := downloadManager.FileSHA256(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return fmt.Errorf("bundle file %s is missing: %w", rel, err)
+		}
+		if digest != expected {
+			return fmt.Errorf("bundle file %s: %w: expected sha256 %s, got %s", rel, downloadManager.ErrChecksumMismatch, expected, digest)
+		}
+	}
+	return nil
+}
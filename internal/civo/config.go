@@ -128,6 +128,9 @@ type GitOpsDirectoryValues struct {
 
 	UseTelemetry									string
 
+	// ExtraTokens are user defined tokens keyed by `<KEY>`
+	ExtraTokens map[string]string
+
 	// MetaphorDevelopmentIngressURL                string
 	// MetaphorDevelopmentIngressNoHTTPSURL         string
 	// MetaphorProductionIngressURL                 string
@@ -158,4 +161,6 @@ type MetaphorTokenValues struct {
 	MetaphorFrontendDevelopmentIngressURL string
 	MetaphorFrontendProductionIngressURL  string
 	MetaphorFrontendStagingIngressURL     string
+	// ExtraTokens are user defined tokens keyed by `<KEY>`
+	ExtraTokens map[string]string
 }
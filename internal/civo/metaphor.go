@@ -0,0 +1,58 @@
+package civo
+
+import (
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/kubefirst/kubefirst/configs"
+	"github.com/kubefirst/kubefirst/internal/gitClient"
+	"github.com/kubefirst/kubefirst/internal/gitopsTemplate"
+)
+
+// PrepareMetaphorRepository clones and detokenizes a metaphor template into metaphorDir and commits it
+// with destinationMetaphorRepoGitURL as the github remote, pushing is left to the caller
+func PrepareMetaphorRepository(
+	destinationMetaphorRepoGitURL string,
+	k1Dir string,
+	metaphorDir string,
+	metaphorTemplateBranch string,
+	metaphorTemplateURL string,
+	metaphorTemplatePath string,
+	skipTemplateCompatCheck bool,
+	tokens *MetaphorTokenValues,
+) error {
+
+	metaphorRepo, err := gitClient.CloneOrInitRepo(metaphorTemplatePath, metaphorTemplateBranch, metaphorDir, metaphorTemplateURL)
+	if err != nil {
+		log.Info().Msgf("error opening repo at: %s", metaphorDir)
+		return err
+	}
+
+	log.Info().Msg("metaphor repository clone complete")
+
+	if !skipTemplateCompatCheck {
+		err = gitopsTemplate.CheckMetaphorTemplateCompatibility(metaphorDir, configs.K1Version, CloudProvider, tokens.ExtraTokens)
+		if err != nil {
+			os.RemoveAll(metaphorDir)
+			return err
+		}
+	}
+
+	err = CivoGithubAdjustMetaphorTemplateContent(GitProvider, k1Dir, metaphorDir)
+	if err != nil {
+		return err
+	}
+
+	err = DetokenizeCivoGithubMetaphor(metaphorDir, tokens)
+	if err != nil {
+		return err
+	}
+
+	err = gitClient.AddRemote(destinationMetaphorRepoGitURL, GitProvider, metaphorRepo)
+	if err != nil {
+		return err
+	}
+
+	return gitClient.Commit(metaphorRepo, "committing detokenized metaphor-frontend-template repo content")
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/kubefirst/kubefirst/internal/k8s"
 	"github.com/kubefirst/kubefirst/internal/vault"
+	"github.com/kubefirst/kubefirst/pkg"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
@@ -16,6 +17,7 @@ func readVaultTokenFromSecret(config *CivoConfig) string {
 		return ""
 	}
 
+	pkg.RegisterSecret(existingKubernetesSecret["root-token"])
 	return existingKubernetesSecret["root-token"]
 }
 
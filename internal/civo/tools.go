@@ -0,0 +1,14 @@
+package civo
+
+import (
+	"github.com/kubefirst/kubefirst/internal/downloadManager"
+)
+
+// Tools lists the tools of a civo cluster, the paths they are used from and the versions shipped with the cli
+func Tools(config *CivoConfig) []downloadManager.ToolSpec {
+	return []downloadManager.ToolSpec{
+		{Name: "helm", DefaultVersion: HelmClientVersion, Path: config.HelmClient},
+		{Name: "kubectl", DefaultVersion: KubectlClientVersion, Path: config.KubectlClient},
+		{Name: "terraform", DefaultVersion: TerraformClientVersion, Path: config.TerraformClient},
+	}
+}
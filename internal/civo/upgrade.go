@@ -0,0 +1,23 @@
+package civo
+
+import (
+	"os"
+)
+
+// RenderGitopsTemplate turns a cloned gitops-template into the content create pushes to the gitops
+// repository so template releases can be compared against it
+func RenderGitopsTemplate(clusterName, clusterType, templateDir string, tokens *GitOpsDirectoryValues) error {
+	// the argo workflows ci content is copied out of the template, keep it away from the live k1 directory
+	scratchDir, err := os.MkdirTemp("", "kubefirst-civo-render")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratchDir)
+
+	err = CivoGithubAdjustGitopsTemplateContent(CloudProvider, clusterName, clusterType, GitProvider, scratchDir, templateDir)
+	if err != nil {
+		return err
+	}
+
+	return DetokenizeCivoGithubGitops(templateDir, tokens)
+}
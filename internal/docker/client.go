@@ -0,0 +1,288 @@
+package docker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// requestTimeout bounds the api calls that don't stream images or archives
+const requestTimeout = 10 * time.Second
+
+// Client reads the state of the local container runtime from the docker engine api
+type Client struct {
+	// Host is the DOCKER_HOST the client connects to
+	Host       string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Info is the part of the docker system info the k3d preflight checks
+type Info struct {
+	Name            string   `json:"Name"`
+	ServerVersion   string   `json:"ServerVersion"`
+	OperatingSystem string   `json:"OperatingSystem"`
+	NCPU            int      `json:"NCPU"`
+	MemTotal        int64    `json:"MemTotal"`
+	MemoryLimit     bool     `json:"MemoryLimit"`
+	CgroupDriver    string   `json:"CgroupDriver"`
+	CgroupVersion   string   `json:"CgroupVersion"`
+	SecurityOptions []string `json:"SecurityOptions"`
+}
+
+// Rootless reports whether the daemon runs without root, rootless k3s needs cgroup v2
+func (i Info) Rootless() bool {
+	for _, option := range i.SecurityOptions {
+		if strings.Contains(option, "name=rootless") {
+			return true
+		}
+	}
+	return false
+}
+
+// Container is a docker container, Name is its first name without the leading /
+type Container struct {
+	ID     string
+	Name   string
+	State  string
+	Labels map[string]string
+}
+
+// NewClient connects to DOCKER_HOST, or to the first docker socket found on the machine
+func NewClient() (*Client, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = defaultHost()
+	}
+	return NewClientWithHost(host)
+}
+
+// NewClientWithHost connects to a unix:// or tcp:// docker host, tcp hosts use the DOCKER_TLS_VERIFY and
+// DOCKER_CERT_PATH certificates like the docker cli
+func NewClientWithHost(host string) (*Client, error) {
+	hostURL, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %s: %w", host, err)
+	}
+
+	switch hostURL.Scheme {
+	case "unix":
+		socket := hostURL.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+		return &Client{
+			Host:       host,
+			baseURL:    "http://docker",
+			httpClient: &http.Client{Transport: transport},
+		}, nil
+	case "tcp":
+		scheme := "http"
+		transport := &http.Transport{}
+		if os.Getenv("DOCKER_TLS_VERIFY") != "" {
+			tlsConfig, err := certificates(os.Getenv("DOCKER_CERT_PATH"))
+			if err != nil {
+				return nil, err
+			}
+			transport.TLSClientConfig = tlsConfig
+			scheme = "https"
+		}
+		return &Client{
+			Host:       host,
+			baseURL:    fmt.Sprintf("%s://%s", scheme, hostURL.Host),
+			httpClient: &http.Client{Transport: transport},
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported docker host %s, expected unix:// or tcp://", host)
+}
+
+// defaultHost is the socket of the docker engine, Docker Desktop and rootless docker, the engine socket when none
+// is found
+func defaultHost() string {
+	sockets := []string{"/var/run/docker.sock"}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		sockets = append(sockets,
+			filepath.Join(homeDir, ".docker", "run", "docker.sock"),
+			filepath.Join(homeDir, ".docker", "desktop", "docker.sock"),
+		)
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		sockets = append(sockets, filepath.Join(runtimeDir, "docker.sock"))
+	}
+
+	for _, socket := range sockets {
+		if _, err := os.Stat(socket); err == nil {
+			return "unix://" + socket
+		}
+	}
+	return "unix://" + sockets[0]
+}
+
+func certificates(certPath string) (*tls.Config, error) {
+	if certPath == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		certPath = filepath.Join(homeDir, ".docker")
+	}
+
+	cert, err := tls.LoadX509KeyPair(filepath.Join(certPath, "cert.pem"), filepath.Join(certPath, "key.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading the docker client certificate of %s: %w", certPath, err)
+	}
+	ca, err := os.ReadFile(filepath.Join(certPath, "ca.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading the docker ca of %s: %w", certPath, err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	return &tls.Config{Certificates: []tls.Certificate{cert}, RootCAs: pool}, nil
+}
+
+// Ping checks the daemon answers
+func (c *Client) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	response, err := c.request(ctx, http.MethodGet, "/_ping", nil, "", nil)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// Info returns the resources and the cgroup setup of the daemon
+func (c *Client) Info() (Info, error) {
+	var info Info
+	err := c.get("/info", nil, &info)
+	return info, err
+}
+
+// Containers returns the containers, stopped ones included, whose name starts with prefix
+func (c *Client) Containers(prefix string) ([]Container, error) {
+	var containers []struct {
+		ID     string            `json:"Id"`
+		Names  []string          `json:"Names"`
+		State  string            `json:"State"`
+		Labels map[string]string `json:"Labels"`
+	}
+	err := c.get("/containers/json", url.Values{"all": {"1"}, "filters": {nameFilter(prefix)}}, &containers)
+	if err != nil {
+		return nil, err
+	}
+
+	found := []Container{}
+	for _, container := range containers {
+		for _, name := range container.Names {
+			name = strings.TrimPrefix(name, "/")
+			if strings.HasPrefix(name, prefix) {
+				found = append(found, Container{ID: container.ID, Name: name, State: container.State, Labels: container.Labels})
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+// Volumes returns the names of the volumes that start with prefix
+func (c *Client) Volumes(prefix string) ([]string, error) {
+	var volumes struct {
+		Volumes []struct {
+			Name string `json:"Name"`
+		} `json:"Volumes"`
+	}
+	err := c.get("/volumes", url.Values{"filters": {nameFilter(prefix)}}, &volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, volume := range volumes.Volumes {
+		if strings.HasPrefix(volume.Name, prefix) {
+			names = append(names, volume.Name)
+		}
+	}
+	return names, nil
+}
+
+// Networks returns the names of the networks that start with prefix
+func (c *Client) Networks(prefix string) ([]string, error) {
+	var networks []struct {
+		Name string `json:"Name"`
+	}
+	err := c.get("/networks", url.Values{"filters": {nameFilter(prefix)}}, &networks)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, network := range networks {
+		if strings.HasPrefix(network.Name, prefix) {
+			names = append(names, network.Name)
+		}
+	}
+	return names, nil
+}
+
+// nameFilter is the filters query of a name, docker matches names anywhere so callers check the prefix
+func nameFilter(name string) string {
+	filter, _ := json.Marshal(map[string][]string{"name": {name}})
+	return string(filter)
+}
+
+func (c *Client) get(path string, query url.Values, v interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	response, err := c.request(ctx, http.MethodGet, path, query, "", nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// request calls the api and turns error statuses into errors, the caller closes the body of the response
+func (c *Client) request(ctx context.Context, method, path string, query url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	requestURL := c.baseURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	request, err := http.NewRequestWithContext(ctx, method, requestURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	// 304 answers a start or stop of a container that already is started or stopped
+	if response.StatusCode >= http.StatusBadRequest || (response.StatusCode >= http.StatusMultipleChoices && response.StatusCode != http.StatusNotModified) {
+		defer response.Body.Close()
+		message, _ := io.ReadAll(response.Body)
+		return nil, fmt.Errorf("docker %s %s answered %s: %s", method, path, response.Status, strings.TrimSpace(string(message)))
+	}
+	return response, nil
+}
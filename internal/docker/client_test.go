@@ -0,0 +1,124 @@
+package docker
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// serveDocker answers docker api paths with fixed json bodies on a unix socket
+func serveDocker(t *testing.T, responses map[string]string) *Client {
+	// unix socket paths are limited to about 100 characters, t.TempDir is too deep on some systems
+	dir, err := os.MkdirTemp("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	})}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	client, err := NewClientWithHost("unix://" + socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestClient(t *testing.T) {
+	client := serveDocker(t, map[string]string{
+		"/_ping":           "OK",
+		"/info":            `{"NCPU":4,"MemTotal":8589934592,"MemoryLimit":true,"CgroupVersion":"2","SecurityOptions":["name=seccomp,profile=default","name=rootless"]}`,
+		"/containers/json": `[{"Id":"a1","Names":["/k3d-kubefirst-server-0"],"State":"exited"},{"Id":"b2","Names":["/other-k3d-kubefirst-server-0"],"State":"running"}]`,
+		"/volumes":         `{"Volumes":[{"Name":"k3d-kubefirst-images"},{"Name":"backup-k3d-kubefirst-images"}]}`,
+		"/networks":        `[{"Name":"k3d-kubefirst"}]`,
+	})
+
+	err := client.Ping()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := client.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.NCPU != 4 || info.MemTotal != 8589934592 || !info.MemoryLimit || info.CgroupVersion != "2" || !info.Rootless() {
+		t.Errorf("Info() = %+v", info)
+	}
+
+	containers, err := client.Containers("k3d-kubefirst-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Container{{ID: "a1", Name: "k3d-kubefirst-server-0", State: "exited"}}
+	if !reflect.DeepEqual(containers, want) {
+		t.Errorf("Containers() = %v, want %v", containers, want)
+	}
+
+	volumes, err := client.Volumes("k3d-kubefirst-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(volumes, []string{"k3d-kubefirst-images"}) {
+		t.Errorf("Volumes() = %v, want [k3d-kubefirst-images]", volumes)
+	}
+
+	networks, err := client.Networks("k3d-kubefirst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(networks, []string{"k3d-kubefirst"}) {
+		t.Errorf("Networks() = %v, want [k3d-kubefirst]", networks)
+	}
+}
+
+func TestClientUnreachable(t *testing.T) {
+	client, err := NewClientWithHost("unix:///nonexistent/docker.sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(); err == nil {
+		t.Error("Ping() succeeded without a daemon")
+	}
+}
+
+func TestNewClientWithHost(t *testing.T) {
+	t.Setenv("DOCKER_TLS_VERIFY", "")
+	tests := []struct {
+		host    string
+		baseURL string
+		wantErr bool
+	}{
+		{host: "unix:///var/run/docker.sock", baseURL: "http://docker"},
+		{host: "tcp://10.0.0.2:2375", baseURL: "http://10.0.0.2:2375"},
+		{host: "npipe:////./pipe/docker_engine", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			client, err := NewClientWithHost(tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewClientWithHost() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && client.baseURL != tt.baseURL {
+				t.Errorf("NewClientWithHost() base url = %s, want %s", client.baseURL, tt.baseURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PullImage pulls an image, the daemon streams the progress as json messages until the pull ends
+func (c *Client) PullImage(image string) error {
+	response, err := c.request(context.Background(), http.MethodPost, "/images/create", url.Values{"fromImage": {image}}, "", nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		var message struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &message) == nil && message.Error != "" {
+			return fmt.Errorf("error pulling image %s: %s", image, message.Error)
+		}
+	}
+	return scanner.Err()
+}
+
+// CreateContainer creates a container of image without starting it and returns its id
+func (c *Client) CreateContainer(name, image string) (string, error) {
+	body, err := json.Marshal(map[string]string{"Image": image})
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	response, err := c.request(ctx, http.MethodPost, "/containers/create", url.Values{"name": {name}}, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&created)
+	if err != nil {
+		return "", err
+	}
+	if created.ID == "" {
+		return "", errors.New("docker returned no container id")
+	}
+	return created.ID, nil
+}
+
+// RemoveContainer removes a container with its anonymous volumes
+func (c *Client) RemoveContainer(container string) error {
+	return c.call(http.MethodDelete, fmt.Sprintf("/containers/%s", container), url.Values{"force": {"1"}, "v": {"1"}})
+}
+
+// StartContainer starts a container, a started container is left as is
+func (c *Client) StartContainer(container string) error {
+	return c.call(http.MethodPost, fmt.Sprintf("/containers/%s/start", container), nil)
+}
+
+// StopContainer stops a container, a stopped container is left as is
+func (c *Client) StopContainer(container string) error {
+	return c.call(http.MethodPost, fmt.Sprintf("/containers/%s/stop", container), url.Values{"t": {"30"}})
+}
+
+// CopyFromContainer returns a tar archive of a path of a container, the caller closes it
+func (c *Client) CopyFromContainer(container, path string) (io.ReadCloser, error) {
+	response, err := c.request(context.Background(), http.MethodGet, fmt.Sprintf("/containers/%s/archive", container), url.Values{"path": {path}}, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// CopyToContainer extracts a tar archive into a directory of a container, the container may be stopped
+func (c *Client) CopyToContainer(container, dir string, archive io.Reader) error {
+	response, err := c.request(context.Background(), http.MethodPut, fmt.Sprintf("/containers/%s/archive", container), url.Values{"path": {dir}}, "application/x-tar", archive)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// Exec runs a command in a running container and returns its exit code once it ends
+func (c *Client) Exec(container string, command []string) (int, error) {
+	body, err := json.Marshal(map[string]interface{}{"Cmd": command})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	response, err := c.request(ctx, http.MethodPost, fmt.Sprintf("/containers/%s/exec", container), nil, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&created)
+	response.Body.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	// a detached exec answers once it started, its state is polled until it ends
+	response, err = c.request(ctx, http.MethodPost, fmt.Sprintf("/exec/%s/start", created.ID), nil, "application/json", strings.NewReader(`{"Detach":true}`))
+	if err != nil {
+		return 0, err
+	}
+	response.Body.Close()
+
+	for deadline := time.Now().Add(time.Minute); time.Now().Before(deadline); time.Sleep(500 * time.Millisecond) {
+		var state struct {
+			Running  bool `json:"Running"`
+			ExitCode int  `json:"ExitCode"`
+		}
+		err = c.get(fmt.Sprintf("/exec/%s/json", created.ID), nil, &state)
+		if err != nil {
+			return 0, err
+		}
+		if !state.Running {
+			return state.ExitCode, nil
+		}
+	}
+	return 0, fmt.Errorf("%s in container %s did not end after a minute", strings.Join(command, " "), container)
+}
+
+// call calls an api action that answers without content, stopping a container takes up to its stop timeout
+func (c *Client) call(method, path string, query url.Values) error {
+	response, err := c.request(context.Background(), method, path, query, "", nil)
+	if err != nil {
+		return fmt.Errorf("error calling %s: %w", strings.TrimPrefix(path, "/"), err)
+	}
+	return response.Body.Close()
+}
@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPullImage(t *testing.T) {
+	client := serveDocker(t, map[string]string{
+		"/images/create": `{"status":"Pulling from rancher/k3s","id":"v1.26.3-k3s1"}
+{"status":"Downloading","progressDetail":{"current":1,"total":2}}
+{"status":"Status: Downloaded newer image for rancher/k3s:v1.26.3-k3s1"}`,
+	})
+	err := client.PullImage("rancher/k3s:v1.26.3-k3s1")
+	if err != nil {
+		t.Errorf("PullImage() = %v, want no error", err)
+	}
+
+	client = serveDocker(t, map[string]string{
+		"/images/create": `{"status":"Pulling from rancher/k3s"}
+{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}`,
+	})
+	err = client.PullImage("rancher/k3s:v0.0.0-k3s1")
+	if err == nil || !strings.Contains(err.Error(), "manifest unknown") {
+		t.Errorf("PullImage() = %v, want the error of the pull stream", err)
+	}
+}
+
+func TestCreateContainer(t *testing.T) {
+	client := serveDocker(t, map[string]string{
+		"/containers/create": `{"Id":"f00d","Warnings":[]}`,
+	})
+	id, err := client.CreateContainer("kubefirst-k3s", "rancher/k3s:v1.26.3-k3s1")
+	if err != nil || id != "f00d" {
+		t.Errorf("CreateContainer() = %q, %v, want f00d", id, err)
+	}
+
+	err = client.StartContainer("missing")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("StartContainer() = %v, want a 404 error", err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	client := serveDocker(t, map[string]string{
+		"/containers/k3d-kubefirst-agent-0/exec": `{"Id":"e1"}`,
+		"/exec/e1/start":                         ``,
+		"/exec/e1/json":                          `{"ID":"e1","Running":false,"ExitCode":1}`,
+	})
+	exitCode, err := client.Exec("k3d-kubefirst-agent-0", []string{"rm", "-rf", "/bin/aux"})
+	if err != nil || exitCode != 1 {
+		t.Errorf("Exec() = %d, %v, want exit code 1", exitCode, err)
+	}
+
+	_, err = client.Exec("missing", []string{"true"})
+	if err == nil {
+		t.Error("Exec() returned no error for a missing container")
+	}
+}
@@ -0,0 +1,367 @@
+package downloadManager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// ToolCacheDir is the directory of the tool cache inside the tools directory
+const ToolCacheDir = "cache"
+
+// ToolCache keeps every installed tool version under <tools dir>/cache/<tool>/<version>. The fixed tool paths
+// such as ~/.k1/tools/terraform point at the version selected for the cluster.
+type ToolCache struct {
+	Dir string
+}
+
+// ToolSpec is a tool a cluster uses, the path it is used from and the version shipped with the cli
+type ToolSpec struct {
+	Name           string
+	DefaultVersion string
+	Path           string
+}
+
+// NewToolCache returns the tool cache of toolsDir
+func NewToolCache(toolsDir string) ToolCache {
+	return ToolCache{Dir: filepath.Join(toolsDir, ToolCacheDir)}
+}
+
+// ToolVersionKey is the kubefirst config key pinning the version of a tool for the cluster
+func ToolVersionKey(tool string) string {
+	return fmt.Sprintf("tools.%s.version", tool)
+}
+
+// Version is the version pinned for the cluster, or the default version when nothing is pinned yet
+func (t ToolSpec) Version() string {
+	pinned := viper.GetString(ToolVersionKey(t.Name))
+	if pinned != "" {
+		return pinned
+	}
+	return t.DefaultVersion
+}
+
+// NewToolDownload returns the release artifact of a tool version
+func NewToolDownload(tool, version, localOs, localArchitecture string) (ToolDownload, error) {
+	switch tool {
+	case "helm":
+		return HelmDownload(version, localOs, localArchitecture), nil
+	case "k3d":
+		return K3dDownload(version, localOs, localArchitecture), nil
+	case "kubectl":
+		return KubectlDownload(version, localOs, localArchitecture), nil
+	case "mkcert":
+		return MkCertDownload(version, localOs, localArchitecture), nil
+	case "terraform":
+		return TerraformDownload(version, localOs, localArchitecture), nil
+	}
+	return ToolDownload{}, fmt.Errorf("unsupported tool %s", tool)
+}
+
+// BinaryPath is where a tool version is installed in the cache
+func (c ToolCache) BinaryPath(tool, version string) string {
+	return filepath.Join(c.Dir, tool, version, tool)
+}
+
+// Lookup returns the manifest record of a tool version when its binary is still installed. The record path is
+// resolved against the cache directory so a cache keeps working after it is moved, i.e. extracted from a bundle.
+func (c ToolCache) Lookup(tool, version string) (ToolRecord, bool) {
+	manifest, err := ReadToolManifest(c.Dir)
+	if err != nil {
+		return ToolRecord{}, false
+	}
+	record, ok := manifest.Tools[ToolKey(tool, version)]
+	if !ok || !FileExists(c.BinaryPath(tool, version)) {
+		return ToolRecord{}, false
+	}
+	record.Path = c.BinaryPath(tool, version)
+	return record, true
+}
+
+// Records returns the cached tool versions sorted by tool and version
+func (c ToolCache) Records() ([]ToolRecord, error) {
+	manifest, err := ReadToolManifest(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []ToolRecord{}
+	for _, record := range manifest.Tools {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+		return records[i].Version < records[j].Version
+	})
+	return records, nil
+}
+
+// Install downloads and verifies a tool version into the cache, a version that is already cached is not downloaded again
+func (c ToolCache) Install(d ToolDownload) (string, error) {
+	if record, ok := c.Lookup(d.Name, d.Version); ok {
+		log.Info().Msgf("%s %s found in the tool cache - skipping download", d.Name, d.Version)
+		return record.Path, nil
+	}
+
+	binaryPath := c.BinaryPath(d.Name, d.Version)
+	versionDir := filepath.Dir(binaryPath)
+	err := os.MkdirAll(versionDir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	artifactPath := binaryPath
+	if d.ArchiveMember != "" {
+		artifactPath = filepath.Join(versionDir, filepath.Base(d.URL))
+	}
+
+	digest, err := d.fetch(artifactPath)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case strings.HasSuffix(d.URL, ".tar.gz"):
+		err = extractTarGzMember(artifactPath, d.ArchiveMember, binaryPath)
+	case strings.HasSuffix(d.URL, ".zip"):
+		err = unzip(artifactPath, versionDir)
+	}
+	if artifactPath != binaryPath {
+		os.Remove(artifactPath)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not extract %s %s: %w", d.Name, d.Version, err)
+	}
+
+	err = os.Chmod(binaryPath, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	err = RecordTool(c.Dir, ToolRecord{
+		Name:    d.Name,
+		Version: d.Version,
+		URL:     d.URL,
+		SHA256:  digest,
+		Path:    binaryPath,
+	})
+	if err != nil {
+		return "", err
+	}
+	return binaryPath, nil
+}
+
+// Import copies a tool version from another cache, such as the tools of an offline bundle, without downloading it
+func (c ToolCache) Import(from ToolCache, tool, version string) (string, error) {
+	if record, ok := c.Lookup(tool, version); ok {
+		return record.Path, nil
+	}
+
+	record, ok := from.Lookup(tool, version)
+	if !ok {
+		return "", fmt.Errorf("%s %s is not in %s", tool, version, from.Dir)
+	}
+
+	binaryPath := c.BinaryPath(tool, version)
+	err := os.MkdirAll(filepath.Dir(binaryPath), 0755)
+	if err != nil {
+		return "", err
+	}
+	err = copyExecutable(record.Path, binaryPath)
+	if err != nil {
+		return "", err
+	}
+
+	record.Path = binaryPath
+	record.VerifiedAt = time.Time{}
+	err = RecordTool(c.Dir, record)
+	if err != nil {
+		return "", err
+	}
+	return binaryPath, nil
+}
+
+// Activate points toolPath at the cached binary of a tool version. The binary is copied where symlinks are
+// not available.
+func (c ToolCache) Activate(tool, version, toolPath string) error {
+	target := c.BinaryPath(tool, version)
+	if !FileExists(target) {
+		return fmt.Errorf("%s %s is not installed in the tool cache, run `kubefirst tools install %s`", tool, version, tool)
+	}
+
+	return linkTool(target, toolPath)
+}
+
+// linkTool replaces toolPath with a link to target
+func linkTool(target, toolPath string) error {
+	err := os.Remove(toolPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		return copyExecutable(target, toolPath)
+	}
+	return os.Symlink(target, toolPath)
+}
+
+// ActiveVersion is the cached version toolPath points at, empty when toolPath is not a link into the cache
+func (c ToolCache) ActiveVersion(tool, toolPath string) string {
+	target, err := os.Readlink(toolPath)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(filepath.Join(c.Dir, tool), target)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.Dir(rel)
+}
+
+// Prune removes every cached version for which keep returns false and returns the removed records
+func (c ToolCache) Prune(keep func(tool, version string) bool, dryRun bool) ([]ToolRecord, error) {
+	records, err := c.Records()
+	if err != nil {
+		return nil, err
+	}
+
+	pruned := []ToolRecord{}
+	keys := []string{}
+	for _, record := range records {
+		if keep(record.Name, record.Version) {
+			continue
+		}
+		pruned = append(pruned, record)
+		keys = append(keys, ToolKey(record.Name, record.Version))
+		if dryRun {
+			continue
+		}
+		err = os.RemoveAll(filepath.Join(c.Dir, record.Name, record.Version))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if dryRun || len(keys) == 0 {
+		return pruned, nil
+	}
+	return pruned, RemoveToolRecords(c.Dir, keys...)
+}
+
+// InstallTools installs the selected version of every tool into the cache of toolsDir, points each tool path at it
+// and pins the versions in the kubefirst config so later cli upgrades keep using them for this cluster. With
+// useSystemTools a supported tool found on PATH is used instead, unless the cluster pins another version.
+func InstallTools(toolsDir string, tools []ToolSpec, localOs, localArchitecture string, useSystemTools bool) error {
+	cache := NewToolCache(toolsDir)
+
+	// every step writes only its own index
+	versions := make([]string, len(tools))
+	systemPaths := make([]string, len(tools))
+
+	steps := []downloadStep{}
+	for i, tool := range tools {
+		i, tool := i, tool
+		steps = append(steps, downloadStep{tool.Name, func() error {
+			if useSystemTools {
+				systemTool, err := selectSystemTool(tool, toolsDir)
+				if err == nil {
+					err = linkTool(systemTool.Path, tool.Path)
+					if err != nil {
+						return err
+					}
+					versions[i], systemPaths[i] = systemTool.Version, systemTool.Path
+					log.Info().Msgf("using system %s %s from %s", tool.Name, systemTool.Version, systemTool.Path)
+					return nil
+				}
+				log.Info().Msgf("not using the system %s: %s - downloading %s %s", tool.Name, err, tool.Name, tool.Version())
+			}
+
+			version := tool.Version()
+			download, err := NewToolDownload(tool.Name, version, localOs, localArchitecture)
+			if err != nil {
+				return err
+			}
+			_, err = cache.Install(download)
+			if err != nil {
+				return err
+			}
+			err = cache.Activate(tool.Name, version, tool.Path)
+			if err != nil {
+				return err
+			}
+			versions[i] = version
+			log.Info().Msgf("%s %s installed at %s", tool.Name, version, tool.Path)
+			return nil
+		}})
+	}
+
+	err := downloadConcurrently(steps)
+	if err != nil {
+		return err
+	}
+
+	for i, tool := range tools {
+		viper.Set(ToolVersionKey(tool.Name), versions[i])
+		viper.Set(ToolPathKey(tool.Name), systemPaths[i])
+	}
+	return nil
+}
+
+// selectSystemTool returns the supported system binary of a tool, a cluster that pins another version keeps it
+func selectSystemTool(tool ToolSpec, toolsDir string) (SystemTool, error) {
+	systemTool, err := FindSystemTool(tool.Name, toolsDir)
+	if err != nil {
+		return SystemTool{}, err
+	}
+
+	pinned := viper.GetString(ToolVersionKey(tool.Name))
+	if pinned != "" && canonicalVersion(pinned) != canonicalVersion(systemTool.Version) {
+		return SystemTool{}, fmt.Errorf("found %s but the cluster pins %s", systemTool.Version, pinned)
+	}
+	return systemTool, nil
+}
+
+func extractTarGzMember(targzPath, member, targetFilePath string) error {
+	tarContent, err := os.Open(targzPath)
+	if err != nil {
+		return err
+	}
+	defer tarContent.Close()
+
+	extractFileFromTarGz(tarContent, member, targetFilePath)
+	if !FileExists(targetFilePath) {
+		return fmt.Errorf("%s not found in %s", member, filepath.Base(targzPath))
+	}
+	return nil
+}
+
+func copyExecutable(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
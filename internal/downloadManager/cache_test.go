@@ -0,0 +1,173 @@
+package downloadManager
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func tarGz(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(content)
+	tw.Close()
+	gz.Close()
+	return archive.Bytes()
+}
+
+func TestToolCacheInstall(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho tool\n")
+	archive := tarGz(t, "linux-amd64/tool", binary)
+	artifacts := map[string][]byte{"/tool": binary, "/tool.tar.gz": archive}
+
+	var downloads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if content, ok := artifacts[r.URL.Path]; ok {
+			atomic.AddInt32(&downloads, 1)
+			w.Write(content)
+			return
+		}
+		if content, ok := artifacts[r.URL.Path[:len(r.URL.Path)-len(".sha256")]]; ok {
+			sum := sha256.Sum256(content)
+			fmt.Fprint(w, hex.EncodeToString(sum[:]))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		path          string
+		archiveMember string
+	}{
+		{name: "binary", path: "/tool"},
+		{name: "tar.gz archive", path: "/tool.tar.gz", archiveMember: "linux-amd64/tool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&downloads, 0)
+			cache := NewToolCache(t.TempDir())
+			tool := ToolDownload{
+				Name:          "tool",
+				Version:       "v1.0.0",
+				URL:           server.URL + tt.path,
+				ChecksumURL:   server.URL + tt.path + ".sha256",
+				ArchiveMember: tt.archiveMember,
+			}
+
+			for i := 0; i < 2; i++ {
+				binaryPath, err := cache.Install(tool)
+				if err != nil {
+					t.Fatalf("Install() error = %v", err)
+				}
+				if binaryPath != cache.BinaryPath("tool", "v1.0.0") {
+					t.Errorf("Install() = %s, want %s", binaryPath, cache.BinaryPath("tool", "v1.0.0"))
+				}
+				content, err := os.ReadFile(binaryPath)
+				if err != nil || !bytes.Equal(content, binary) {
+					t.Errorf("Install() installed %q, %v", content, err)
+				}
+			}
+			if got := atomic.LoadInt32(&downloads); got != 1 {
+				t.Errorf("Install() downloaded %d times, want the second install served from the cache", got)
+			}
+
+			entries, _ := os.ReadDir(filepath.Dir(cache.BinaryPath("tool", "v1.0.0")))
+			if len(entries) != 1 {
+				t.Errorf("Install() left %d files in the version directory, want only the binary", len(entries))
+			}
+
+			record, ok := cache.Lookup("tool", "v1.0.0")
+			if !ok || record.URL != tool.URL || len(record.SHA256) != 64 {
+				t.Errorf("Lookup() = %+v, %v", record, ok)
+			}
+		})
+	}
+}
+
+func TestToolCacheActivateAndPrune(t *testing.T) {
+	toolsDir := t.TempDir()
+	cache := NewToolCache(toolsDir)
+	for _, version := range []string{"v1.0.0", "v2.0.0"} {
+		binaryPath := cache.BinaryPath("tool", version)
+		os.MkdirAll(filepath.Dir(binaryPath), 0755)
+		os.WriteFile(binaryPath, []byte(version), 0755)
+		err := RecordTool(cache.Dir, ToolRecord{Name: "tool", Version: version, Path: binaryPath})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	toolPath := filepath.Join(toolsDir, "tool")
+	os.WriteFile(toolPath, []byte("unmanaged"), 0755)
+	for _, version := range []string{"v1.0.0", "v2.0.0"} {
+		err := cache.Activate("tool", version, toolPath)
+		if err != nil {
+			t.Fatalf("Activate(%s) error = %v", version, err)
+		}
+		content, _ := os.ReadFile(toolPath)
+		if string(content) != version {
+			t.Errorf("Activate(%s) points at %q", version, content)
+		}
+		if got := cache.ActiveVersion("tool", toolPath); got != version {
+			t.Errorf("ActiveVersion() = %q, want %q", got, version)
+		}
+	}
+	if err := cache.Activate("tool", "v3.0.0", toolPath); err == nil {
+		t.Error("Activate() of a version missing from the cache did not fail")
+	}
+
+	keep := func(tool, version string) bool { return version == "v2.0.0" }
+	pruned, err := cache.Prune(keep, true)
+	if err != nil || len(pruned) != 1 || !FileExists(cache.BinaryPath("tool", "v1.0.0")) {
+		t.Fatalf("Prune(dry run) = %+v, %v", pruned, err)
+	}
+
+	pruned, err = cache.Prune(keep, false)
+	if err != nil || len(pruned) != 1 || pruned[0].Version != "v1.0.0" {
+		t.Fatalf("Prune() = %+v, %v", pruned, err)
+	}
+	if FileExists(cache.BinaryPath("tool", "v1.0.0")) {
+		t.Error("Prune() kept the unused version")
+	}
+	if _, ok := cache.Lookup("tool", "v2.0.0"); !ok {
+		t.Error("Prune() removed the selected version")
+	}
+	records, _ := cache.Records()
+	if len(records) != 1 {
+		t.Errorf("Prune() left %d manifest records, want 1", len(records))
+	}
+}
+
+func TestToolSpecVersion(t *testing.T) {
+	defer viper.Reset()
+	tool := ToolSpec{Name: "terraform", DefaultVersion: "1.3.8"}
+
+	if got := tool.Version(); got != "1.3.8" {
+		t.Errorf("Version() = %s, want the default version", got)
+	}
+
+	viper.Set(ToolVersionKey("terraform"), "1.3.7")
+	if got := tool.Version(); got != "1.3.7" {
+		t.Errorf("Version() = %s, want the pinned version", got)
+	}
+}
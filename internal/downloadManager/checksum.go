@@ -0,0 +1,257 @@
+package downloadManager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrChecksumMismatch is returned when a download does not match its upstream sha256 checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+var sha256Pattern = regexp.MustCompile(`^[a-fA-F0-9]{64}$`)
+
+// ToolDownload is a tool release artifact and where its upstream sha256 checksum is published
+type ToolDownload struct {
+	Name    string
+	Version string
+	URL     string
+	// ChecksumURL publishes the sha256 of URL, either as a bare digest or as a sha256sum style list
+	ChecksumURL string
+	// ChecksumFile is the artifact name looked up in a checksum list
+	ChecksumFile string
+	// SHA256 is a digest pinned in source, it is used instead of ChecksumURL and GithubRepo
+	SHA256 string
+	// GithubRepo reads the digest from the github release asset named ChecksumFile instead of ChecksumURL
+	GithubRepo string
+	// ArchiveMember is the path of the binary inside a .tar.gz or .zip artifact
+	ArchiveMember string
+}
+
+// HelmDownload is the helm release archive, checked against its .sha256sum
+func HelmDownload(version, localOs, localArchitecture string) ToolDownload {
+	url := fmt.Sprintf("https://get.helm.sh/helm-%s-%s-%s.tar.gz", version, localOs, localArchitecture)
+	return ToolDownload{
+		Name:          "helm",
+		Version:       version,
+		URL:           url,
+		ChecksumURL:   url + ".sha256sum",
+		ChecksumFile:  path.Base(url),
+		ArchiveMember: fmt.Sprintf("%s-%s/helm", localOs, localArchitecture),
+	}
+}
+
+// K3dDownload is the k3d release binary, checked against the release checksums.txt
+func K3dDownload(version, localOs, localArchitecture string) ToolDownload {
+	return ToolDownload{
+		Name:         "k3d",
+		Version:      version,
+		URL:          fmt.Sprintf("https://github.com/k3d-io/k3d/releases/download/%s/k3d-%s-%s", version, localOs, localArchitecture),
+		ChecksumURL:  fmt.Sprintf("https://github.com/k3d-io/k3d/releases/download/%s/checksums.txt", version),
+		ChecksumFile: fmt.Sprintf("k3d-%s-%s", localOs, localArchitecture),
+	}
+}
+
+// KubectlDownload is the kubectl release binary, checked against its .sha256
+func KubectlDownload(version, localOs, localArchitecture string) ToolDownload {
+	url := fmt.Sprintf("https://dl.k8s.io/release/%s/bin/%s/%s/kubectl", version, localOs, localArchitecture)
+	return ToolDownload{
+		Name:         "kubectl",
+		Version:      version,
+		URL:          url,
+		ChecksumURL:  url + ".sha256",
+		ChecksumFile: "kubectl",
+	}
+}
+
+// mkCertChecksums are the sha256 digests of the mkcert release binaries, mkcert publishes no checksum list
+var mkCertChecksums = map[string]string{
+	"mkcert-v1.4.4-darwin-amd64": "a32dfab51f1845d51e810db8e47dcf0e6b51ae3422426514bf5a2b8302e97d4e",
+	"mkcert-v1.4.4-darwin-arm64": "c8af0df44bce04359794dad8ea28d750437411d632748049d08644ffb66a60c6",
+	"mkcert-v1.4.4-linux-amd64":  "6d31c65b03972c6dc4a14ab429f2928300518b26503f58723e532d1b0a3bbb52",
+	"mkcert-v1.4.4-linux-arm64":  "b98f2cc69fd9147fe4d405d859c57504571adec0d3611c3eefd04107c7ac00d0",
+}
+
+// MkCertDownload is the mkcert release binary, checked against the digest pinned in mkCertChecksums. Releases
+// without a pinned digest fall back to the digest github computes for the release asset.
+func MkCertDownload(version, localOs, localArchitecture string) ToolDownload {
+	file := fmt.Sprintf("mkcert-%s-%s-%s", version, localOs, localArchitecture)
+	return ToolDownload{
+		Name:         "mkcert",
+		Version:      version,
+		URL:          fmt.Sprintf("https://github.com/FiloSottile/mkcert/releases/download/%s/%s", version, file),
+		ChecksumFile: file,
+		SHA256:       mkCertChecksums[file],
+		GithubRepo:   "FiloSottile/mkcert",
+	}
+}
+
+// TerraformDownload is the terraform release archive, checked against the release SHA256SUMS
+func TerraformDownload(version, localOs, localArchitecture string) ToolDownload {
+	file := fmt.Sprintf("terraform_%s_%s_%s.zip", version, localOs, localArchitecture)
+	return ToolDownload{
+		Name:          "terraform",
+		Version:       version,
+		URL:           fmt.Sprintf("https://releases.hashicorp.com/terraform/%s/%s", version, file),
+		ChecksumURL:   fmt.Sprintf("https://releases.hashicorp.com/terraform/%s/terraform_%s_SHA256SUMS", version, version),
+		ChecksumFile:  file,
+		ArchiveMember: "terraform",
+	}
+}
+
+// Download fetches the artifact to localFilename and verifies it against the upstream checksum. A file that
+// does not match is deleted, verified digests are recorded in the manifest of the directory holding localFilename.
+func (d ToolDownload) Download(localFilename string) error {
+	digest, err := d.fetch(localFilename)
+	if err != nil {
+		return err
+	}
+
+	return RecordTool(filepath.Dir(localFilename), ToolRecord{
+		Name:    d.Name,
+		Version: d.Version,
+		URL:     d.URL,
+		SHA256:  digest,
+	})
+}
+
+// fetch downloads the artifact to localFilename and returns its verified sha256
+func (d ToolDownload) fetch(localFilename string) (string, error) {
+	expected, err := d.ExpectedChecksum()
+	if err != nil {
+		return "", fmt.Errorf("could not read the upstream checksum for %s %s: %w", d.Name, d.Version, err)
+	}
+
+	err = DownloadFile(localFilename, d.URL)
+	if err != nil {
+		return "", err
+	}
+
+	digest, err := VerifyChecksum(localFilename, expected)
+	if err != nil {
+		return "", fmt.Errorf("%s %s downloaded from %s: %w", d.Name, d.Version, d.URL, err)
+	}
+	log.Info().Msgf("verified %s %s sha256 %s", d.Name, d.Version, digest)
+	return digest, nil
+}
+
+// ExpectedChecksum reads the upstream sha256 of the artifact
+func (d ToolDownload) ExpectedChecksum() (string, error) {
+	if d.SHA256 != "" {
+		return d.SHA256, nil
+	}
+	if d.GithubRepo != "" {
+		return githubReleaseAssetDigest(d.GithubRepo, d.Version, d.ChecksumFile)
+	}
+
+	content, err := httpGet(d.ChecksumURL)
+	if err != nil {
+		return "", err
+	}
+	return ParseChecksumList(content, d.ChecksumFile)
+}
+
+// ParseChecksumList finds the sha256 of file in sha256sum style output, a list holding a single bare digest
+// is used as is
+func ParseChecksumList(content []byte, file string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 1 && len(lines) == 1 && sha256Pattern.MatchString(fields[0]):
+			return strings.ToLower(fields[0]), nil
+		case len(fields) >= 2 && sha256Pattern.MatchString(fields[0]):
+			// sha256sum marks binary mode with a leading *, k3d lists paths under _dist/
+			name := strings.TrimPrefix(fields[1], "*")
+			if name == file || path.Base(name) == file {
+				return strings.ToLower(fields[0]), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no sha256 checksum found for %s", file)
+}
+
+// VerifyChecksum compares the sha256 of the file at filePath with expected, deleting the file on a mismatch
+func VerifyChecksum(filePath, expected string) (string, error) {
+	digest, err := FileSHA256(filePath)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(digest, expected) {
+		os.Remove(filePath)
+		return "", fmt.Errorf("%w: expected sha256 %s, got %s, the download was deleted", ErrChecksumMismatch, expected, digest)
+	}
+	return digest, nil
+}
+
+// FileSHA256 returns the hex encoded sha256 of a file
+func FileSHA256(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// githubReleaseAssetDigest reads the sha256 digest github computes for a release asset
+func githubReleaseAssetDigest(repo, tag, asset string) (string, error) {
+	content, err := httpGet(fmt.Sprintf("https://api.github.com/repos/%s/releases/tags/%s", repo, tag))
+	if err != nil {
+		return "", err
+	}
+
+	var release struct {
+		Assets []struct {
+			Name   string `json:"name"`
+			Digest string `json:"digest"`
+		} `json:"assets"`
+	}
+	err = json.Unmarshal(content, &release)
+	if err != nil {
+		return "", fmt.Errorf("could not read the %s %s release: %w", repo, tag, err)
+	}
+
+	for _, a := range release.Assets {
+		if a.Name != asset {
+			continue
+		}
+		digest := strings.TrimPrefix(a.Digest, "sha256:")
+		if !sha256Pattern.MatchString(digest) {
+			return "", fmt.Errorf("release asset %s of %s %s has no sha256 digest", asset, repo, tag)
+		}
+		return strings.ToLower(digest), nil
+	}
+	return "", fmt.Errorf("release asset %s not found in %s %s", asset, repo, tag)
+}
+
+func httpGet(url string) ([]byte, error) {
+	resp, err := httpCommon.NewClient().Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to download %s, the HTTP return status is: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
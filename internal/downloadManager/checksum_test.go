@@ -0,0 +1,170 @@
+package downloadManager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func TestParseChecksumList(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		file    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "bare digest",
+			content: helloSHA256 + "\n",
+			file:    "kubectl",
+			want:    helloSHA256,
+		},
+		{
+			name:    "sha256sum list",
+			content: "0000000000000000000000000000000000000000000000000000000000000000  terraform_1.3.8_darwin_arm64.zip\n" + helloSHA256 + "  terraform_1.3.8_linux_amd64.zip\n",
+			file:    "terraform_1.3.8_linux_amd64.zip",
+			want:    helloSHA256,
+		},
+		{
+			name:    "binary mode marker",
+			content: helloSHA256 + " *helm-v3.6.1-linux-amd64.tar.gz",
+			file:    "helm-v3.6.1-linux-amd64.tar.gz",
+			want:    helloSHA256,
+		},
+		{
+			name:    "release path prefix",
+			content: helloSHA256 + "  _dist/k3d-linux-amd64\n",
+			file:    "k3d-linux-amd64",
+			want:    helloSHA256,
+		},
+		{
+			name:    "file not listed",
+			content: helloSHA256 + "  k3d-darwin-arm64\n",
+			file:    "k3d-linux-amd64",
+			wantErr: true,
+		},
+		{
+			name:    "not a checksum",
+			content: "<html>not found</html>",
+			file:    "kubectl",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChecksumList([]byte(tt.content), tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseChecksumList() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseChecksumList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolDownloadDownload(t *testing.T) {
+	artifact := []byte("hello")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tool":
+			w.Write(artifact)
+		case "/good.sha256":
+			fmt.Fprintf(w, "%s  tool\n", helloSHA256)
+		case "/bad.sha256":
+			sum := sha256.Sum256([]byte("tampered"))
+			fmt.Fprintf(w, "%s  tool\n", hex.EncodeToString(sum[:]))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		checksumURL string
+		wantErr     error
+	}{
+		{name: "checksum matches", checksumURL: server.URL + "/good.sha256"},
+		{name: "checksum mismatch", checksumURL: server.URL + "/bad.sha256", wantErr: ErrChecksumMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toolsDir := t.TempDir()
+			localFilename := filepath.Join(toolsDir, "tool")
+			tool := ToolDownload{Name: "tool", Version: "v1.0.0", URL: server.URL + "/tool", ChecksumURL: tt.checksumURL, ChecksumFile: "tool"}
+
+			err := tool.Download(localFilename)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Download() error = %v, want %v", err, tt.wantErr)
+			}
+
+			manifest, merr := ReadToolManifest(toolsDir)
+			if merr != nil {
+				t.Fatal(merr)
+			}
+			record, recorded := manifest.Tools[ToolKey("tool", "v1.0.0")]
+
+			if tt.wantErr != nil {
+				if FileExists(localFilename) {
+					t.Error("Download() kept a file that failed verification")
+				}
+				if recorded {
+					t.Error("Download() recorded a file that failed verification")
+				}
+				return
+			}
+			if record.SHA256 != helloSHA256 || record.Version != "v1.0.0" || record.URL != tool.URL {
+				t.Errorf("Download() recorded %+v", record)
+			}
+		})
+	}
+}
+
+func TestVerifyChecksumDeletesMismatch(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "kubectl")
+	if err := os.WriteFile(filePath, []byte("hello"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	digest, err := VerifyChecksum(filePath, helloSHA256)
+	if err != nil || digest != helloSHA256 {
+		t.Fatalf("VerifyChecksum() = %q, %v", digest, err)
+	}
+
+	_, err = VerifyChecksum(filePath, "0000000000000000000000000000000000000000000000000000000000000000")
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("VerifyChecksum() error = %v, want %v", err, ErrChecksumMismatch)
+	}
+	if FileExists(filePath) {
+		t.Error("VerifyChecksum() kept a mismatched file")
+	}
+}
+
+func TestMkCertDownloadPinnedChecksum(t *testing.T) {
+	for file, digest := range mkCertChecksums {
+		if !sha256Pattern.MatchString(digest) {
+			t.Errorf("mkCertChecksums[%q] = %q, want a sha256 digest", file, digest)
+		}
+	}
+
+	// a pinned digest is read without calling the github api
+	got, err := MkCertDownload("v1.4.4", "linux", "amd64").ExpectedChecksum()
+	if err != nil {
+		t.Fatalf("ExpectedChecksum() error = %v", err)
+	}
+	if got != mkCertChecksums["mkcert-v1.4.4-linux-amd64"] {
+		t.Errorf("ExpectedChecksum() = %q, want the pinned digest", got)
+	}
+}
@@ -9,11 +9,9 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
 
 	"github.com/rs/zerolog/log"
 
@@ -24,18 +22,19 @@ import (
 type KubectlVersion struct {
 	ClientVersion struct {
 		GitVersion string `json:"gitVersion"`
-	} `json:clientVersion`
+	} `json:"clientVersion"`
 }
 
 type TerraformVersion struct {
 	TerraformVersion string `json:"terraform_version"`
 }
 
-func DownloadTarGz(binaryPath string, tarAddress string, targzPath string, URL string) error {
+// DownloadTarGz downloads and verifies a tool archive, extracting tarAddress to binaryPath
+func DownloadTarGz(binaryPath string, tarAddress string, targzPath string, tool ToolDownload) error {
 
-	log.Info().Msgf("Downloading tar.gz from %s", URL)
+	log.Info().Msgf("Downloading tar.gz from %s", tool.URL)
 
-	err := DownloadFile(targzPath, URL)
+	err := tool.Download(targzPath)
 	if err != nil {
 		return err
 	}
@@ -58,11 +57,12 @@ func DownloadTarGz(binaryPath string, tarAddress string, targzPath string, URL s
 	return nil
 }
 
-func DownloadZip(toolsDir string, URL string, zipPath string) error {
+// DownloadZip downloads and verifies a tool archive, extracting it to toolsDir
+func DownloadZip(toolsDir string, tool ToolDownload, zipPath string) error {
 
-	log.Info().Msgf("Downloading zip from %s", "URL")
+	log.Info().Msgf("Downloading zip from %s", tool.URL)
 
-	err := DownloadFile(zipPath, URL)
+	err := tool.Download(zipPath)
 	if err != nil {
 		return err
 	}
@@ -89,126 +89,60 @@ func DownloadLocalTools(config *configs.Config) error {
 		return err
 	}
 
-	var wg sync.WaitGroup
-	errorChannel := make(chan error)
-	wgDone := make(chan bool)
-	wg.Add(2)
-
-	go func() {
-		// https://github.com/k3d-io/k3d/releases/download/v5.4.6/k3d-linux-amd64
-		k3dDownloadURL := fmt.Sprintf(
-			"https://github.com/k3d-io/k3d/releases/download/%s/k3d-%s-%s",
-			config.K3dVersion,
-			config.LocalOs,
-			config.LocalArchitecture,
-		)
-		err = DownloadFile(config.K3dPath, k3dDownloadURL)
-		if err != nil {
-			errorChannel <- err
-			return
-		}
-		if FileExists(config.K3dPath) && VerifyCacheK3D(config) {
-			log.Info().Msg("K3d exists and cache validated - skipping download")
-			wg.Done()
-			return
-		}
-
-		if FileExists(config.K3dPath) && !VerifyCacheK3D(config) {
-			log.Info().Msg("K3d exists and cache version is invalid - continuing...")
-			log.Info().Msg("divergent versions may not work well, we recommend running `kubefirst clean` command and try again")
-			wg.Done()
-			return
-		}
+	err = downloadConcurrently([]downloadStep{
+		{"k3d", func() error {
+			if FileExists(config.K3dPath) {
+				if VerifyCacheK3D(config) {
+					log.Info().Msg("K3d exists and cache validated - skipping download")
+				} else {
+					log.Info().Msg("K3d exists and cache version is invalid - continuing...")
+					log.Info().Msg("divergent versions may not work well, we recommend running `kubefirst clean` command and try again")
+				}
+				return nil
+			}
 
-		if !FileExists(config.K3dPath) {
 			log.Info().Msg("k3d binnary not found - starting download")
-
-			k3dDownloadUrl := fmt.Sprintf(
-				"https://github.com/k3d-io/k3d/releases/download/%s/k3d-%s-%s",
-				config.K3dVersion,
-				config.LocalOs,
-				config.LocalArchitecture,
-			)
-			err = DownloadFile(config.K3dPath, k3dDownloadUrl)
+			err := K3dDownload(config.K3dVersion, config.LocalOs, config.LocalArchitecture).Download(config.K3dPath)
 			if err != nil {
-				errorChannel <- err
-				return
+				return err
 			}
 			err = os.Chmod(config.K3dPath, 0755)
 			if err != nil {
-				errorChannel <- err
-				return
+				return err
 			}
-			wg.Done()
 			log.Info().Msg("K3D download finished")
-
-		}
-	}()
-
-	go func() {
-		// https://github.com/FiloSottile/mkcert/releases/download/v1.4.4/mkcert-v1.4.4-darwin-amd64
-		mkCertDownloadURL := fmt.Sprintf(
-			"https://github.com/FiloSottile/mkcert/releases/download/%s/mkcert-%s-%s-%s",
-			config.MkCertVersion,
-			config.MkCertVersion,
-			config.LocalOs,
-			config.LocalArchitecture,
-		)
-		err = DownloadFile(config.MkCertPath, mkCertDownloadURL)
-		if err != nil {
-			errorChannel <- err
-			if FileExists(config.MkCertPath) && VerifyCacheMkCert(config) {
-				log.Info().Msg("MkCert exists and cache validated - skipping download")
-				wg.Done()
-				return
+			return nil
+		}},
+		{"mkcert", func() error {
+			if FileExists(config.MkCertPath) {
+				if VerifyCacheMkCert(config) {
+					log.Info().Msg("MkCert exists and cache validated - skipping download")
+				} else {
+					log.Info().Msg("MkCert exists and cache version is invalid - continuing...")
+					log.Info().Msg("divergent versions may not work well, we recommend running `kubefirst clean` command and try again")
+				}
+				return nil
 			}
 
-			if FileExists(config.MkCertPath) && !VerifyCacheMkCert(config) {
-				log.Info().Msg("MkCert exists and cache version is invalid - continuing...")
-				log.Info().Msg("divergent versions may not work well, we recommend running `kubefirst clean` command and try again")
-				wg.Done()
-				return
+			log.Info().Msg("MkCert binnary not found - starting download")
+			err := MkCertDownload(config.MkCertVersion, config.LocalOs, config.LocalArchitecture).Download(config.MkCertPath)
+			if err != nil {
+				return err
 			}
-
-			if !FileExists(config.MkCertPath) {
-				log.Info().Msg("MkCert binnary not found - starting download")
-
-				mkCertDownloadUrl := fmt.Sprintf(
-					"https://github.com/FiloSottile/mkcert/releases/download/%s/mkcert-%s-%s-%s",
-					config.MkCertVersion,
-					config.MkCertVersion,
-					config.LocalOs,
-					config.LocalArchitecture,
-				)
-				err = DownloadFile(config.MkCertPath, mkCertDownloadUrl)
-				if err != nil {
-					errorChannel <- err
-					return
-				}
-				err = os.Chmod(config.MkCertPath, 0755)
-				if err != nil {
-					errorChannel <- err
-					return
-				}
-				wg.Done()
-				log.Info().Msg("MkCert download finished")
+			err = os.Chmod(config.MkCertPath, 0755)
+			if err != nil {
+				return err
 			}
-		}
-	}()
-
-	go func() {
-		wg.Wait()
-		close(wgDone)
-	}()
-
-	select {
-	case <-wgDone:
-		log.Info().Msg("finished tools download check")
-		return nil
-	case err = <-errorChannel:
-		close(errorChannel)
+			log.Info().Msg("MkCert download finished")
+			return nil
+		}},
+	})
+	if err != nil {
 		return err
 	}
+
+	log.Info().Msg("finished tools download check")
+	return nil
 }
 
 func FileExists(path string) bool {
@@ -293,8 +227,7 @@ func VerifyCacheKubectl(config *configs.Config) bool {
 }
 
 // DownloadTools prepare download folder, and download the required installation tools for download. The downloads
-// are done via Go routines, and error are handler by the error channel. In case of some error in the download process,
-// the errorChannel will receive the error message, and process it back to the function caller.
+// run concurrently, every download is waited for and their errors are returned together to the function caller.
 func DownloadTools(config *configs.Config) error {
 
 	log.Info().Msg("starting checking tool downloads")
@@ -305,388 +238,99 @@ func DownloadTools(config *configs.Config) error {
 		return err
 	}
 
-	errorChannel := make(chan error)
-	wgDone := make(chan bool)
-	// create a waiting group (translating: create a queue of functions, and only pass the wg.Wait() function down
-	// bellow after all the wg.Add(3) functions are done (wg.Done)
-	var wg sync.WaitGroup
-	wg.Add(3)
-
-	go func() {
-		kVersion := config.KubectlVersion
-		if config.LocalOs == "darwin" && config.LocalArchitecture == "arm64" {
-			kVersion = config.KubectlVersion
-		}
-
-		kubectlDownloadURL := fmt.Sprintf(
-			"https://dl.k8s.io/release/%s/bin/%s/%s/kubectl",
-			kVersion,
-			config.LocalOs,
-			config.LocalArchitecture,
-		)
-		log.Info().Msgf("Downloading kubectl from: %s", kubectlDownloadURL)
-		err = DownloadFile(config.KubectlClientPath, kubectlDownloadURL)
-		if err != nil {
-			errorChannel <- err
-			return
-		}
-		if FileExists(config.KubectlClientPath) && VerifyCacheKubectl(config) {
-			log.Info().Msg("kubectl exists and cache validated - skipping download")
-			wg.Done()
-			return
-		}
-
-		if FileExists(config.KubectlClientPath) && !VerifyCacheKubectl(config) {
-			log.Info().Msg("kubectl exists and cache version is invalid - continuing...")
-			log.Info().Msg("divergent versions may not work well, we recommend running `kubefirst clean` command and try again")
-			wg.Done()
-			return
-		}
+	err = downloadConcurrently([]downloadStep{
+		{"kubectl", func() error {
+			if FileExists(config.KubectlClientPath) {
+				if VerifyCacheKubectl(config) {
+					log.Info().Msg("kubectl exists and cache validated - skipping download")
+				} else {
+					log.Info().Msg("kubectl exists and cache version is invalid - continuing...")
+					log.Info().Msg("divergent versions may not work well, we recommend running `kubefirst clean` command and try again")
+				}
+				return nil
+			}
 
-		if !FileExists(config.KubectlClientPath) {
 			log.Info().Msg("kubectl not found - starting download")
-
-			kubectlDownloadUrl := fmt.Sprintf(
-				"https://dl.k8s.io/release/%s/bin/%s/%s/kubectl",
-				config.KubectlVersion,
-				config.LocalOs,
-				config.LocalArchitecture,
-			)
-			log.Info().Msgf("Downloading kubectl from: %s", kubectlDownloadUrl)
-			err = DownloadFile(config.KubectlClientPath, kubectlDownloadUrl)
+			err := KubectlDownload(config.KubectlVersion, config.LocalOs, config.LocalArchitecture).Download(config.KubectlClientPath)
 			if err != nil {
-				errorChannel <- err
-				return
+				return err
 			}
 
 			err = os.Chmod(config.KubectlClientPath, 0755)
 			if err != nil {
-				errorChannel <- err
-				return
+				return err
 			}
 
-			verifyBin := VerifyCacheKubectl(config)
-			if !verifyBin {
-				errorChannel <- fmt.Errorf("failed to verify kubectl download: %v", err)
-				return
+			if !VerifyCacheKubectl(config) {
+				return fmt.Errorf("failed to verify kubectl download, expected version %s", config.KubectlVersion)
 			}
-			wg.Done()
 			log.Info().Msg("Kubectl download finished")
-		}
-	}()
-
-	go func() {
-
-		// todo: adopt latest helmVersion := "v3.9.0"
-		terraformVersion := config.TerraformVersion
-
-		terraformDownloadURL := fmt.Sprintf(
-			"https://releases.hashicorp.com/terraform/%s/terraform_%s_%s_%s.zip",
-			terraformVersion,
-			terraformVersion,
-			config.LocalOs,
-			config.LocalArchitecture,
-		)
-		log.Info().Msgf("Downloading terraform from %s", terraformDownloadURL)
-		terraformDownloadZipPath := fmt.Sprintf("%s/tools/terraform.zip", config.K1FolderPath)
-		err = DownloadFile(terraformDownloadZipPath, terraformDownloadURL)
-		if err != nil {
-			errorChannel <- fmt.Errorf("error reading terraform file, %v", err)
-			return
-		}
-		if FileExists(config.TerraformClientPath) && VerifyCacheTerraform(config) {
-			log.Info().Msg("Terraform exists and cache validated - skipping download")
-			wg.Done()
-			return
-		}
-
-		if FileExists(config.TerraformClientPath) && !VerifyCacheTerraform(config) {
-			log.Info().Msg("Terraform exists and cache version is invalid - continuing...")
-			log.Info().Msg("divergent versions may not work well, we recommend running `kubefirst clean` command and try again")
-			wg.Done()
-			return
-		}
+			return nil
+		}},
+		{"terraform", func() error {
+			if FileExists(config.TerraformClientPath) {
+				if VerifyCacheTerraform(config) {
+					log.Info().Msg("Terraform exists and cache validated - skipping download")
+				} else {
+					log.Info().Msg("Terraform exists and cache version is invalid - continuing...")
+					log.Info().Msg("divergent versions may not work well, we recommend running `kubefirst clean` command and try again")
+				}
+				return nil
+			}
 
-		if !FileExists(config.TerraformClientPath) {
 			log.Info().Msg("Terraform not found - starting download")
-
-			terraformVersion := config.TerraformVersion
-
-			terraformDownloadUrl := fmt.Sprintf(
-				"https://releases.hashicorp.com/terraform/%s/terraform_%s_%s_%s.zip",
-				terraformVersion,
-				terraformVersion,
-				config.LocalOs,
-				config.LocalArchitecture,
-			)
-			log.Info().Msgf("Downloading terraform from %s", terraformDownloadUrl)
 			terraformDownloadZipPath := fmt.Sprintf("%s/terraform.zip", config.K1ToolsPath)
-			err = DownloadFile(terraformDownloadZipPath, terraformDownloadUrl)
+			err := TerraformDownload(config.TerraformVersion, config.LocalOs, config.LocalArchitecture).Download(terraformDownloadZipPath)
 			if err != nil {
-				errorChannel <- fmt.Errorf("error reading terraform file, %v", err)
-				return
+				return fmt.Errorf("error reading terraform file, %v", err)
 			}
 
-			unzip(terraformDownloadZipPath, config.K1ToolsPath)
+			err = unzip(terraformDownloadZipPath, config.K1ToolsPath)
+			if err != nil {
+				return err
+			}
 
 			err = os.Chmod(config.K1ToolsPath, 0777)
 			if err != nil {
-				errorChannel <- err
-				return
+				return err
 			}
 
 			err = os.Chmod(fmt.Sprintf("%s/terraform", config.K1ToolsPath), 0755)
 			if err != nil {
-				errorChannel <- err
-				return
+				return err
 			}
-			err = os.RemoveAll(fmt.Sprintf("%s/terraform.zip", config.K1ToolsPath))
+			err = os.RemoveAll(terraformDownloadZipPath)
 			if err != nil {
-				errorChannel <- err
-				return
+				return err
 			}
-			wg.Done()
 			log.Info().Msg("Terraform download finished")
-		}
-	}()
-
-	go func() {
-		helmVersion := config.HelmVersion
-		helmDownloadURL := fmt.Sprintf(
-			"https://get.helm.sh/helm-%s-%s-%s.tar.gz",
-			helmVersion,
-			config.LocalOs,
-			config.LocalArchitecture,
-		)
-		log.Info().Msgf("Downloading terraform from %s", helmDownloadURL)
-		helmDownloadTarGzPath := fmt.Sprintf("%s/tools/helm.tar.gz", config.K1FolderPath)
-
-		err = DownloadFile(helmDownloadTarGzPath, helmDownloadURL)
-		if err != nil {
-			return
-		}
-
-		os.Remove(helmDownloadTarGzPath)
-		helmTarDownload, err := os.Open(helmDownloadTarGzPath)
-		if err != nil {
-			return
-		}
-
-		extractFileFromTarGz(
-			helmTarDownload,
-			fmt.Sprintf("%s-%s/helm", config.LocalOs, config.LocalArchitecture),
-			config.HelmClientPath,
-		)
-		err = os.Chmod(config.HelmClientPath, 0755)
-		if err != nil {
-			return
-		}
-	}()
-
-	go func() {
-		wg.Wait()
-		close(wgDone)
-	}()
-
-	select {
-	case <-wgDone:
-		log.Info().Msg("finished tools download check")
-		return nil
-	case err = <-errorChannel:
-		close(errorChannel)
-		return err
-	}
-}
-
-// todo create a download function for binaries and a download zip function, call these individually to increase re-usability
-func CivoDownloadTools(helmClientPath, helmClientVersion, kubectlClientPath, kubectlClientVersion, localOs, localArchitecture, terraformClientVersion, toolsDirPath string) error {
-
-	log.Info().Msg("starting downloads...")
-
-	// create folder if it doesn't exist
-	err := createDirIfDontExist(toolsDirPath)
-	if err != nil {
-		return err
-	}
-
-	errorChannel := make(chan error)
-	wgDone := make(chan bool)
-	// create a waiting group (translating: create a queue of functions, and only pass the wg.Wait() function down
-	// bellow after all the wg.Add(3) functions are done (wg.Done)
-	var wg sync.WaitGroup
-	wg.Add(3)
-
-	go func() {
-
-		kubectlDownloadURL := fmt.Sprintf(
-			"https://dl.k8s.io/release/%s/bin/%s/%s/kubectl",
-			kubectlClientVersion,
-			localOs,
-			localArchitecture,
-		)
-		log.Info().Msgf("Downloading kubectl from: %s", kubectlDownloadURL)
-		err = DownloadFile(kubectlClientPath, kubectlDownloadURL)
-		if err != nil {
-			errorChannel <- err
-			return
-		}
-
-		err = os.Chmod(kubectlClientPath, 0755)
-		if err != nil {
-			errorChannel <- err
-			return
-		}
-
-		log.Info().Msgf("going to print the kubeconfig env in runtime: %s", os.Getenv("KUBECONFIG"))
-
-		kubectlStdOut, kubectlStdErr, err := pkg.ExecShellReturnStrings(kubectlClientPath, "version", "--client", "--short")
-		log.Info().Msgf("-> kubectl version:\n\t%s\n\t%s\n", kubectlStdOut, kubectlStdErr)
-		if err != nil {
-			errorChannel <- fmt.Errorf("failed to call kubectlVersionCmd.Run(): %v", err)
-			return
-		}
-		wg.Done()
-		log.Info().Msg("Kubectl download finished")
-	}()
-
-	go func() {
-
-		terraformDownloadURL := fmt.Sprintf(
-			"https://releases.hashicorp.com/terraform/%s/terraform_%s_%s_%s.zip",
-			terraformClientVersion,
-			terraformClientVersion,
-			localOs,
-			localArchitecture,
-		)
-		log.Info().Msgf("Downloading terraform from %s", terraformDownloadURL)
-		terraformDownloadZipPath := fmt.Sprintf("%s/terraform.zip", toolsDirPath)
-		err = DownloadFile(terraformDownloadZipPath, terraformDownloadURL)
-		if err != nil {
-			errorChannel <- fmt.Errorf("error downloading terraform file, %v", err)
-			return
-		}
-
-		unzip(terraformDownloadZipPath, toolsDirPath)
-
-		err = os.Chmod(toolsDirPath, 0777)
-		if err != nil {
-			errorChannel <- err
-			return
-		}
-
-		err = os.Chmod(fmt.Sprintf("%s/terraform", toolsDirPath), 0755)
-		if err != nil {
-			errorChannel <- err
-			return
-		}
-		err = os.RemoveAll(fmt.Sprintf("%s/terraform.zip", toolsDirPath))
-		if err != nil {
-			errorChannel <- err
-			return
-		}
-		// todo output terraform client version to be consistent with others
-		wg.Done()
-		log.Info().Msg("Terraform download finished")
-	}()
-
-	go func() {
-		helmDownloadURL := fmt.Sprintf(
-			"https://get.helm.sh/helm-%s-%s-%s.tar.gz",
-			helmClientVersion,
-			localOs,
-			localArchitecture,
-		)
-		log.Info().Msgf("Downloading helm from %s", helmDownloadURL)
-		helmDownloadTarGzPath := fmt.Sprintf("%s/helm.tar.gz", toolsDirPath)
-
-		err = DownloadFile(helmDownloadTarGzPath, helmDownloadURL)
-		if err != nil {
-			errorChannel <- err
-			return
-		}
-
-		helmTarDownload, err := os.Open(helmDownloadTarGzPath)
-		if err != nil {
-			errorChannel <- fmt.Errorf("could not read helm download content")
-			return
-		}
-
-		extractFileFromTarGz(
-			helmTarDownload,
-			fmt.Sprintf("%s-%s/helm", localOs, localArchitecture),
-			helmClientPath,
-		)
-		err = os.Chmod(helmClientPath, 0755)
-		if err != nil {
-			errorChannel <- err
-			return
-		}
-
-		// currently argocd init values is generated by kubefirst ssh
-		// todo helm install argocd --create-namespace --wait --values ~/.kubefirst/argocd-init-values.yaml argo/argo-cd
-		helmStdOut, helmStdErr, err := pkg.ExecShellReturnStrings(
-			helmClientPath,
-			"version",
-			"--client",
-			"--short",
-		)
-		if err != nil {
-			log.Info().Msg(helmStdErr)
-			errorChannel <- fmt.Errorf("error executing helm version command: %v", err)
-			return
-		}
-		os.Remove(helmDownloadTarGzPath)
-
-		log.Info().Msgf("Helm version: %s", helmStdOut)
-
-		wg.Done()
-
-		log.Info().Msg("Helm download finished")
-
-	}()
-
-	go func() {
-		wg.Wait()
-		close(wgDone)
-	}()
-
-	select {
-	case <-wgDone:
-		log.Info().Msg("downloads finished")
-		return nil
-	case err = <-errorChannel:
-		close(errorChannel)
-		return err
-	}
-}
-
-// DownloadFile Downloads a file from the "url" parameter, localFilename is the file destination in the local machine.
-func DownloadFile(localFilename string, url string) error {
-	// create local file
-	out, err := os.Create(localFilename)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	// get data
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	// check server response
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unable to download the required file, the HTTP return status is: %s", resp.Status)
-	}
+			return nil
+		}},
+		{"helm", func() error {
+			if FileExists(config.HelmClientPath) && VerifyCacheHelm(config) {
+				log.Info().Msg("Helm exists and cache validated - skipping download")
+				return nil
+			}
 
-	// writer the body to the file
-	_, err = io.Copy(out, resp.Body)
+			helmDownloadTarGzPath := fmt.Sprintf("%s/helm.tar.gz", config.K1ToolsPath)
+			err := DownloadTarGz(
+				config.HelmClientPath,
+				fmt.Sprintf("%s-%s/helm", config.LocalOs, config.LocalArchitecture),
+				helmDownloadTarGzPath,
+				HelmDownload(config.HelmVersion, config.LocalOs, config.LocalArchitecture),
+			)
+			if err != nil {
+				return err
+			}
+			log.Info().Msg("Helm download finished")
+			return nil
+		}},
+	})
 	if err != nil {
 		return err
 	}
 
+	log.Info().Msg("finished tools download check")
 	return nil
 }
 
@@ -0,0 +1,180 @@
+package downloadManager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
+	"github.com/kubefirst/kubefirst/internal/progressPrinter"
+	"github.com/kubefirst/kubefirst/pkg"
+)
+
+var (
+	// downloadAttempts is the number of times a download is started or resumed before it fails
+	downloadAttempts = 5
+	// downloadRetrySleep is the wait before the first retry, it doubles with jitter on every attempt
+	downloadRetrySleep = 2 * time.Second
+)
+
+// httpStatusError is an unexpected response to a download request
+type httpStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unable to download %s, the HTTP return status is: %s", e.URL, e.Status)
+}
+
+// retryable reports whether the server may answer differently to the same request later
+func (e *httpStatusError) retryable() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests
+}
+
+// DownloadFile Downloads a file from the "url" parameter, localFilename is the file destination in the local machine.
+// The content is written to a partial file next to localFilename that later attempts resume with an HTTP Range request,
+// failed attempts are retried with backoff and the bytes received are reported to the progress printer.
+func DownloadFile(localFilename string, url string) error {
+	// the partial file is named after the url so a resumed download never mixes content of different releases
+	urlHash := sha256.Sum256([]byte(url))
+	partFilename := fmt.Sprintf("%s.%s.part", localFilename, hex.EncodeToString(urlHash[:])[:12])
+	trackerKey := fmt.Sprintf("download-%s", localFilename)
+
+	attempt := 0
+	err := pkg.Retry(downloadAttempts, downloadRetrySleep, fmt.Sprintf("download %s", url), func() error {
+		attempt++
+		err := downloadPart(partFilename, url, trackerKey, attempt == 1)
+		if err == nil {
+			return nil
+		}
+
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) && !statusErr.retryable() {
+			return pkg.StopRetry(err)
+		}
+		if attempt < downloadAttempts {
+			log.Warn().Msgf("download of %s failed, resuming (attempt %d of %d): %s", url, attempt, downloadAttempts, err)
+		}
+		return err
+	})
+	progressPrinter.MarkTrackerDone(trackerKey, err)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(partFilename, localFilename)
+}
+
+// downloadPart downloads url to partFilename, resuming from the bytes already in partFilename when the server supports ranges
+func downloadPart(partFilename, url, trackerKey string, addTracker bool) error {
+	var offset int64
+	if fi, err := os.Stat(partFilename); err == nil {
+		offset = fi.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := httpCommon.NewClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+		log.Info().Msgf("resuming download of %s at byte %d", url, offset)
+	case http.StatusOK:
+		// the server ignored the range, start over
+		flags |= os.O_TRUNC
+		offset = 0
+	case http.StatusRequestedRangeNotSatisfiable:
+		os.Remove(partFilename)
+		return fmt.Errorf("could not resume the download of %s at byte %d, restarting", url, offset)
+	default:
+		return &httpStatusError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	total := int64(0)
+	if resp.ContentLength > 0 {
+		total = offset + resp.ContentLength
+	}
+	if addTracker {
+		progressPrinter.AddBytesTracker(trackerKey, fmt.Sprintf("Downloading %s", filepath.Base(url)), total)
+	}
+	progressPrinter.SetTrackerValue(trackerKey, offset)
+
+	out, err := os.OpenFile(partFilename, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, io.TeeReader(resp.Body, &progressWriter{key: trackerKey, value: offset}))
+	return err
+}
+
+// progressWriter reports the bytes written through it to a progress printer tracker
+type progressWriter struct {
+	key   string
+	value int64
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.value += int64(len(p))
+	progressPrinter.SetTrackerValue(w.key, w.value)
+	return len(p), nil
+}
+
+// downloadStep is an independent download run by downloadConcurrently
+type downloadStep struct {
+	name string
+	run  func() error
+}
+
+// downloadConcurrently runs every step at the same time and waits for all of them, the errors of failed steps
+// are returned together
+func downloadConcurrently(steps []downloadStep) error {
+	errs := make([]error, len(steps))
+
+	var wg sync.WaitGroup
+	for i, step := range steps {
+		wg.Add(1)
+		go func(i int, step downloadStep) {
+			defer wg.Done()
+			errs[i] = step.run()
+		}(i, step)
+	}
+	wg.Wait()
+
+	failed := []string{}
+	for i, err := range errs {
+		if err != nil {
+			log.Error().Msgf("%s download failed: %s", steps[i].name, err)
+			failed = append(failed, fmt.Sprintf("%s: %s", steps[i].name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d tool downloads failed: %s", len(failed), len(steps), strings.Join(failed, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package downloadManager
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownloadFileResumes(t *testing.T) {
+	downloadRetrySleep = time.Millisecond
+	defer func() { downloadRetrySleep = 2 * time.Second }()
+
+	content := bytes.Repeat([]byte("kubefirst"), 1000)
+
+	var mu sync.Mutex
+	ranges := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		first := len(ranges) == 1
+		mu.Unlock()
+
+		if first {
+			// drop the connection half way through the body
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			w.Write(content[:len(content)/2])
+			return
+		}
+		http.ServeContent(w, r, "tool", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	localFilename := filepath.Join(t.TempDir(), "tool")
+	err := DownloadFile(localFilename, server.URL+"/tool")
+	if err != nil {
+		t.Fatalf("DownloadFile() error = %v", err)
+	}
+
+	got, err := os.ReadFile(localFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("DownloadFile() wrote %d bytes, want %d", len(got), len(content))
+	}
+	if len(ranges) != 2 || ranges[1] != fmt.Sprintf("bytes=%d-", len(content)/2) {
+		t.Errorf("DownloadFile() requested ranges %q, want a resume at byte %d", ranges, len(content)/2)
+	}
+
+	parts, _ := filepath.Glob(localFilename + ".*.part")
+	if len(parts) != 0 {
+		t.Errorf("DownloadFile() left partial files %v", parts)
+	}
+}
+
+func TestDownloadFileDoesNotRetryClientErrors(t *testing.T) {
+	downloadRetrySleep = time.Millisecond
+	defer func() { downloadRetrySleep = 2 * time.Second }()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	err := DownloadFile(filepath.Join(t.TempDir(), "tool"), server.URL+"/missing")
+	var statusErr *httpStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("DownloadFile() error = %v, want a 404 status error", err)
+	}
+	if requests != 1 {
+		t.Errorf("DownloadFile() sent %d requests for a 404, want 1", requests)
+	}
+}
+
+func TestDownloadConcurrently(t *testing.T) {
+	var mu sync.Mutex
+	ran := map[string]bool{}
+	step := func(name string, err error) downloadStep {
+		return downloadStep{name, func() error {
+			mu.Lock()
+			ran[name] = true
+			mu.Unlock()
+			return err
+		}}
+	}
+
+	err := downloadConcurrently([]downloadStep{
+		step("helm", errors.New("connection reset")),
+		step("kubectl", nil),
+		step("terraform", errors.New("checksum mismatch")),
+	})
+
+	if len(ran) != 3 {
+		t.Errorf("downloadConcurrently() ran %v, want every step", ran)
+	}
+	if err == nil {
+		t.Fatal("downloadConcurrently() error = nil, want the failed steps")
+	}
+	for _, want := range []string{"2 of 3", "helm: connection reset", "terraform: checksum mismatch"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("downloadConcurrently() error = %q, want it to contain %q", err, want)
+		}
+	}
+}
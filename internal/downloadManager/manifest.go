@@ -0,0 +1,101 @@
+package downloadManager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ToolManifestFile records the verified tool downloads of a tools directory
+const ToolManifestFile = "tools-manifest.json"
+
+// manifestMu serializes manifest updates from concurrent downloads
+var manifestMu sync.Mutex
+
+// ToolRecord is a verified tool download
+type ToolRecord struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	SHA256  string `json:"sha256"`
+	// Path is the installed binary of a tool cache entry
+	Path       string    `json:"path,omitempty"`
+	VerifiedAt time.Time `json:"verifiedAt"`
+}
+
+// ToolManifest is the content of ToolManifestFile keyed by ToolKey
+type ToolManifest struct {
+	Tools map[string]ToolRecord `json:"tools"`
+}
+
+// ToolKey is the manifest key of a tool version
+func ToolKey(name, version string) string {
+	return fmt.Sprintf("%s@%s", name, version)
+}
+
+// ReadToolManifest reads the manifest of toolsDir, a missing manifest is empty
+func ReadToolManifest(toolsDir string) (*ToolManifest, error) {
+	manifest := &ToolManifest{Tools: map[string]ToolRecord{}}
+
+	content, err := os.ReadFile(filepath.Join(toolsDir, ToolManifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(content, manifest)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Tools == nil {
+		manifest.Tools = map[string]ToolRecord{}
+	}
+	return manifest, nil
+}
+
+// RecordTool adds or replaces the record of a tool in the manifest of toolsDir
+func RecordTool(toolsDir string, record ToolRecord) error {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+
+	manifest, err := ReadToolManifest(toolsDir)
+	if err != nil {
+		return err
+	}
+
+	if record.VerifiedAt.IsZero() {
+		record.VerifiedAt = time.Now().UTC()
+	}
+	manifest.Tools[ToolKey(record.Name, record.Version)] = record
+	return writeToolManifest(toolsDir, manifest)
+}
+
+// RemoveToolRecords deletes the records of the given ToolKey values from the manifest of toolsDir
+func RemoveToolRecords(toolsDir string, keys ...string) error {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+
+	manifest, err := ReadToolManifest(toolsDir)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		delete(manifest.Tools, key)
+	}
+	return writeToolManifest(toolsDir, manifest)
+}
+
+func writeToolManifest(toolsDir string, manifest *ToolManifest) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(toolsDir, ToolManifestFile), content, 0644)
+}
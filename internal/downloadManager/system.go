@@ -0,0 +1,156 @@
+package downloadManager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubefirst/kubefirst/pkg"
+	"golang.org/x/mod/semver"
+)
+
+// ToolVersionRange is the range of versions kubefirst supports for a tool found on PATH, Min is inclusive and
+// Max is exclusive
+type ToolVersionRange struct {
+	Min string
+	Max string
+}
+
+// SupportedToolVersions are the system tool versions accepted by --use-system-tools
+var SupportedToolVersions = map[string]ToolVersionRange{
+	"helm":      {Min: "v3.6.0", Max: "v4.0.0"},
+	"k3d":       {Min: "v5.4.0", Max: "v6.0.0"},
+	"kubectl":   {Min: "v1.22.0", Max: "v1.27.0"},
+	"mkcert":    {Min: "v1.4.0", Max: "v2.0.0"},
+	"terraform": {Min: "v1.3.0", Max: "v2.0.0"},
+}
+
+// versionArgs are the arguments printing the version of a tool in the format ParseToolVersion reads
+var versionArgs = map[string][]string{
+	"helm":      {"version", "--template={{.Version}}"},
+	"k3d":       {"version"},
+	"kubectl":   {"version", "--client", "-o", "json"},
+	"mkcert":    {"--version"},
+	"terraform": {"version", "-json"},
+}
+
+// SystemTool is a tool found on PATH
+type SystemTool struct {
+	Name    string
+	Path    string
+	Version string
+}
+
+// ToolPathKey is the kubefirst config key recording the system binary a tool of the cluster uses, empty when
+// the tool comes from the tool cache
+func ToolPathKey(tool string) string {
+	return fmt.Sprintf("tools.%s.path", tool)
+}
+
+// FindSystemTool looks up a tool on PATH and returns it when its version is supported. Binaries inside
+// toolsDir, such as a previous kubefirst install on PATH, are not system tools.
+func FindSystemTool(name, toolsDir string) (SystemTool, error) {
+	args, ok := versionArgs[name]
+	if !ok {
+		return SystemTool{}, fmt.Errorf("unsupported tool %s", name)
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return SystemTool{}, fmt.Errorf("%s not found on PATH", name)
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return SystemTool{}, err
+	}
+	if isWithin(path, toolsDir) {
+		return SystemTool{}, fmt.Errorf("%s on PATH is installed by kubefirst at %s", name, path)
+	}
+
+	out, _, err := pkg.ExecShellReturnStrings(path, args...)
+	if err != nil {
+		return SystemTool{}, fmt.Errorf("could not read the version of %s: %w", path, err)
+	}
+	version, err := ParseToolVersion(name, out)
+	if err != nil {
+		return SystemTool{}, err
+	}
+
+	err = CheckToolVersion(name, version)
+	if err != nil {
+		return SystemTool{}, fmt.Errorf("%s at %s: %w", name, path, err)
+	}
+	return SystemTool{Name: name, Path: path, Version: version}, nil
+}
+
+// ParseToolVersion reads the version of a tool from the output of its versionArgs
+func ParseToolVersion(name, output string) (string, error) {
+	output = strings.TrimSpace(output)
+
+	var version string
+	switch name {
+	case "kubectl":
+		var k KubectlVersion
+		err := json.Unmarshal([]byte(output), &k)
+		if err != nil {
+			return "", fmt.Errorf("could not parse the kubectl version: %w", err)
+		}
+		version = k.ClientVersion.GitVersion
+	case "terraform":
+		var tfVersion TerraformVersion
+		err := json.Unmarshal([]byte(output), &tfVersion)
+		if err != nil {
+			return "", fmt.Errorf("could not parse the terraform version: %w", err)
+		}
+		version = tfVersion.TerraformVersion
+	case "k3d":
+		// k3d version v5.4.6
+		for _, line := range strings.Split(output, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) >= 3 && fields[0] == "k3d" && fields[1] == "version" {
+				version = fields[2]
+			}
+		}
+	case "helm", "mkcert":
+		version = output
+	default:
+		return "", fmt.Errorf("unsupported tool %s", name)
+	}
+
+	if !semver.IsValid(canonicalVersion(version)) {
+		return "", fmt.Errorf("could not read a %s version from: %s", name, output)
+	}
+	return version, nil
+}
+
+// CheckToolVersion returns an error when version is outside the supported range of the tool
+func CheckToolVersion(name, version string) error {
+	supported, ok := SupportedToolVersions[name]
+	if !ok {
+		return fmt.Errorf("unsupported tool %s", name)
+	}
+
+	v := canonicalVersion(version)
+	if semver.Compare(v, supported.Min) < 0 || semver.Compare(v, supported.Max) >= 0 {
+		return fmt.Errorf("version %s is outside the supported range >= %s, < %s", version, supported.Min, supported.Max)
+	}
+	return nil
+}
+
+// canonicalVersion prefixes the v terraform versions are printed without
+func canonicalVersion(version string) string {
+	if strings.HasPrefix(version, "v") {
+		return version
+	}
+	return "v" + version
+}
+
+func isWithin(path, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
@@ -0,0 +1,136 @@
+package downloadManager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestParseToolVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		tool    string
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{name: "kubectl json", tool: "kubectl", output: `{"clientVersion": {"gitVersion": "v1.25.4"}, "kustomizeVersion": "v4.5.7"}`, want: "v1.25.4"},
+		{name: "terraform json", tool: "terraform", output: `{"terraform_version": "1.3.8", "platform": "linux_amd64"}`, want: "1.3.8"},
+		{name: "k3d", tool: "k3d", output: "k3d version v5.4.6\nk3s version v1.24.4-k3s1 (default)\n", want: "v5.4.6"},
+		{name: "helm template", tool: "helm", output: "v3.11.1", want: "v3.11.1"},
+		{name: "mkcert", tool: "mkcert", output: "v1.4.4\n", want: "v1.4.4"},
+		{name: "garbage", tool: "helm", output: "command not found", wantErr: true},
+		{name: "kubectl without json", tool: "kubectl", output: "Client Version: v1.25.4", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseToolVersion(tt.tool, tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseToolVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseToolVersion() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckToolVersion(t *testing.T) {
+	tests := []struct {
+		tool    string
+		version string
+		wantErr bool
+	}{
+		{tool: "terraform", version: "1.3.0"},
+		{tool: "terraform", version: "1.5.7"},
+		{tool: "terraform", version: "1.2.9", wantErr: true},
+		{tool: "helm", version: "v2.17.0", wantErr: true},
+		{tool: "kubectl", version: "v1.27.0", wantErr: true},
+		{tool: "kubectl", version: "v1.26.3"},
+		{tool: "argocd", version: "v2.6.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %s", tt.tool, tt.version), func(t *testing.T) {
+			err := CheckToolVersion(tt.tool, tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckToolVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// fakeSystemTool puts a helm printing version on PATH
+func fakeSystemTool(t *testing.T, version string) string {
+	t.Helper()
+	binDir := t.TempDir()
+	helmPath := filepath.Join(binDir, "helm")
+	err := os.WriteFile(helmPath, []byte(fmt.Sprintf("#!/bin/sh\necho %s\n", version)), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+	return helmPath
+}
+
+func TestInstallToolsUsesSystemTools(t *testing.T) {
+	defer viper.Reset()
+	helmPath := fakeSystemTool(t, "v3.9.0")
+
+	toolsDir := t.TempDir()
+	toolPath := filepath.Join(toolsDir, "helm")
+	err := InstallTools(toolsDir, []ToolSpec{{Name: "helm", DefaultVersion: "v3.6.1", Path: toolPath}}, "linux", "amd64", true)
+	if err != nil {
+		t.Fatalf("InstallTools() error = %v", err)
+	}
+
+	target, err := os.Readlink(toolPath)
+	if err != nil || target != helmPath {
+		t.Errorf("InstallTools() linked %s to %s, %v, want %s", toolPath, target, err, helmPath)
+	}
+	if got := viper.GetString(ToolVersionKey("helm")); got != "v3.9.0" {
+		t.Errorf("InstallTools() pinned %s, want v3.9.0", got)
+	}
+	if got := viper.GetString(ToolPathKey("helm")); got != helmPath {
+		t.Errorf("InstallTools() recorded path %s, want %s", got, helmPath)
+	}
+}
+
+func TestSelectSystemTool(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		pinned  string
+		wantErr bool
+	}{
+		{name: "supported", version: "v3.9.0"},
+		{name: "matches the pin", version: "v3.9.0", pinned: "v3.9.0"},
+		{name: "pinned to another version", version: "v3.9.0", pinned: "v3.6.1", wantErr: true},
+		{name: "unsupported", version: "v2.17.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer viper.Reset()
+			fakeSystemTool(t, tt.version)
+			viper.Set(ToolVersionKey("helm"), tt.pinned)
+
+			_, err := selectSystemTool(ToolSpec{Name: "helm"}, t.TempDir())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("selectSystemTool() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("installed by kubefirst", func(t *testing.T) {
+		helmPath := fakeSystemTool(t, "v3.9.0")
+		_, err := selectSystemTool(ToolSpec{Name: "helm"}, filepath.Dir(helmPath))
+		if err == nil {
+			t.Error("selectSystemTool() accepted a binary from the kubefirst tools directory")
+		}
+	})
+}
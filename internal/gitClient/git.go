@@ -834,3 +834,37 @@ func CreateGitHubRemote(gitOpsLocalRepoPath string, gitHubUser string, repoName
 
 	return nil
 }
+
+// CloneWithToken clones the default branch of a private repository over https
+func CloneWithToken(repoURL, repoLocalPath, token string) (*git.Repository, error) {
+	repo, err := git.PlainClone(repoLocalPath, false, &git.CloneOptions{
+		URL: repoURL,
+		Auth: &http.BasicAuth{
+			Username: "kubefirst-bot",
+			Password: token,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// PushBranch pushes a single local branch to the remote
+func PushBranch(repo *git.Repository, remoteName, branch, token string) error {
+	refSpec := gitConfig.RefSpec(fmt.Sprintf("refs/heads/%s:refs/heads/%s", branch, branch))
+	err := repo.Push(&git.PushOptions{
+		RemoteName: remoteName,
+		RefSpecs:   []gitConfig.RefSpec{refSpec},
+		Auth: &http.BasicAuth{
+			Username: "kubefirst-bot",
+			Password: token,
+		},
+	})
+	if err != nil {
+		log.Info().Msgf("error pushing branch %s to remote %s: %s", branch, remoteName, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package gitClient
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kubefirst/kubefirst/pkg"
+	cp "github.com/otiai10/copy"
+	"github.com/rs/zerolog/log"
+)
+
+// CloneOrInitRepo uses the local template at templatePath when provided, otherwise it clones gitRef from repoURL.
+// Either way the returned repository has `main` checked out at repoLocalPath.
+func CloneOrInitRepo(templatePath, gitRef, repoLocalPath, repoURL string) (*git.Repository, error) {
+	if templatePath == "" {
+		return CloneRefSetMain(gitRef, repoLocalPath, repoURL)
+	}
+	return InitRepoFromPath(templatePath, repoLocalPath)
+}
+
+// InitRepoFromPath copies a local template directory or .tar.gz archive to repoLocalPath and initializes
+// a new git repository on the `main` branch, any git history in the template is discarded
+func InitRepoFromPath(templatePath, repoLocalPath string) (*git.Repository, error) {
+
+	log.Info().Msgf("initializing repository at %s from local template: %s", repoLocalPath, templatePath)
+
+	err := CopyTemplateFromPath(templatePath, repoLocalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := git.PlainInit(repoLocalPath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main")))
+	if err != nil {
+		return nil, fmt.Errorf("error setting main branch for %s: %s", repoLocalPath, err)
+	}
+
+	return repo, nil
+}
+
+// CopyTemplateFromPath copies the content of a local template directory or .tar.gz archive to destination
+func CopyTemplateFromPath(templatePath, destination string) error {
+	info, err := os.Stat(templatePath)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return cp.Copy(templatePath, destination, cp.Options{
+			Skip: func(src string) (bool, error) {
+				return filepath.Base(src) == ".git", nil
+			},
+		})
+	}
+
+	if IsTarGz(templatePath) {
+		return extractTemplateTarGz(templatePath, destination)
+	}
+
+	return fmt.Errorf("template path %s must be a directory or a .tar.gz archive", templatePath)
+}
+
+// IsTarGz reports whether path names a gzipped tarball
+func IsTarGz(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// extractTemplateTarGz extracts a template archive to destination. Archives with a single top level
+// directory, such as the ones produced by `git archive --prefix` or GitHub, have that directory stripped.
+func extractTemplateTarGz(archivePath, destination string) error {
+	prefix, err := tarGzCommonPrefix(archivePath)
+	if err != nil {
+		return err
+	}
+
+	return pkg.ExtractTarGz(archivePath, destination, func(name string) string {
+		if prefix != "" && name+"/" == prefix {
+			return ""
+		}
+		name = strings.TrimPrefix(name, prefix)
+		if name == ".git" || strings.HasPrefix(name, ".git/") {
+			return ""
+		}
+		return name
+	})
+}
+
+// tarGzCommonPrefix returns the single top level directory of an archive, including the trailing
+// slash, or an empty string when the archive has content at its root
+func tarGzCommonPrefix(archivePath string) (string, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return "", err
+	}
+	defer gzipReader.Close()
+
+	prefix := ""
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+
+		name := filepath.ToSlash(filepath.Clean(header.Name))
+		parts := strings.SplitN(name, "/", 2)
+		if len(parts) == 1 && header.Typeflag != tar.TypeDir {
+			return "", nil
+		}
+		if prefix == "" {
+			prefix = parts[0]
+		} else if prefix != parts[0] {
+			return "", nil
+		}
+	}
+
+	if prefix == "" {
+		return "", nil
+	}
+	return prefix + "/", nil
+}
@@ -0,0 +1,104 @@
+package gitClient
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestTarGz(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	gzipWriter := gzip.NewWriter(out)
+	defer gzipWriter.Close()
+	tarWriter := tar.NewWriter(gzipWriter)
+	defer tarWriter.Close()
+
+	for name, content := range files {
+		err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCopyTemplateFromPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name:  "archive with content at the root",
+			files: map[string]string{"k3d-github/main.tf": "a", "README.md": "b"},
+			want:  []string{"k3d-github/main.tf", "README.md"},
+		},
+		{
+			name:  "archive with a single top level directory",
+			files: map[string]string{"gitops-template-1.0.0/k3d-github/main.tf": "a", "gitops-template-1.0.0/README.md": "b"},
+			want:  []string{"k3d-github/main.tf", "README.md"},
+		},
+		{
+			name:  "git metadata is skipped",
+			files: map[string]string{"template/.git/HEAD": "ref", "template/README.md": "b"},
+			want:  []string{"README.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := filepath.Join(t.TempDir(), "template.tar.gz")
+			writeTestTarGz(t, archive, tt.files)
+
+			destination := t.TempDir()
+			if err := CopyTemplateFromPath(archive, destination); err != nil {
+				t.Fatalf("CopyTemplateFromPath() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if _, err := os.Stat(filepath.Join(destination, want)); err != nil {
+					t.Errorf("CopyTemplateFromPath() missing %s: %v", want, err)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(destination, ".git")); err == nil {
+				t.Error("CopyTemplateFromPath() copied .git")
+			}
+		})
+	}
+}
+
+func TestInitRepoFromPath(t *testing.T) {
+	templateDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(templateDir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, "README.md"), []byte("gitops"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repoDir := filepath.Join(t.TempDir(), "gitops")
+	repo, err := InitRepoFromPath(templateDir, repoDir)
+	if err != nil {
+		t.Fatalf("InitRepoFromPath() error = %v", err)
+	}
+
+	head, err := repo.Storer.Reference("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Target().String() != "refs/heads/main" {
+		t.Errorf("InitRepoFromPath() HEAD = %s, want refs/heads/main", head.Target())
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, "README.md")); err != nil {
+		t.Errorf("InitRepoFromPath() did not copy template content: %v", err)
+	}
+}
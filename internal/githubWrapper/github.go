@@ -12,6 +12,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/google/go-github/v45/github"
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"golang.org/x/oauth2"
 )
 
@@ -29,7 +30,7 @@ func New() GithubSession {
 		log.Fatal().Msg("Unauthorized: No token present")
 	}
 	var gSession GithubSession
-	gSession.context = context.Background()
+	gSession.context = context.WithValue(context.Background(), oauth2.HTTPClient, httpCommon.NewClient())
 	gSession.staticToken = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 	gSession.oauthClient = oauth2.NewClient(gSession.context, gSession.staticToken)
 	gSession.gitClient = github.NewClient(gSession.oauthClient)
@@ -60,13 +61,39 @@ func (g GithubSession) CreateWebhookRepo(org, repo, hookName, hookURL, hookSecre
 	return nil
 }
 
-// CreatePrivateRepo - Use github API to create a private repo
-func (g GithubSession) CreatePrivateRepo(org string, name string, description string) error {
+// UpdateWebhookURL - Use github API to point the webhooks of a repo delivered to oldURL at newURL, returns the
+// number of updated webhooks
+func (g GithubSession) UpdateWebhookURL(owner, repo, oldURL, newURL, hookSecret string) (int, error) {
+	hooks, _, err := g.gitClient.Repositories.ListHooks(g.context, owner, repo, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return 0, fmt.Errorf("error listing the webhooks of %s/%s: %v", owner, repo, err)
+	}
+
+	updated := 0
+	for _, hook := range hooks {
+		if hook.Config["url"] != oldURL {
+			continue
+		}
+		// the config is replaced as a whole and the secret is never returned
+		hook.Config["url"] = newURL
+		hook.Config["secret"] = hookSecret
+		_, _, err = g.gitClient.Repositories.EditHook(g.context, owner, repo, hook.GetID(), &github.Hook{Config: hook.Config})
+		if err != nil {
+			return updated, fmt.Errorf("error updating webhook %d of %s/%s: %v", hook.GetID(), owner, repo, err)
+		}
+		log.Printf("Successfully updated hook (id): %v", hook.GetID())
+		updated++
+	}
+
+	return updated, nil
+}
+
+// CreatePrivateRepo - Use github API to create a private repo, autoInit adds an initial commit
+func (g GithubSession) CreatePrivateRepo(org string, name string, description string, autoInit bool) error {
 	if name == "" {
 		log.Fatal().Msg("No name: New repos must be given a name")
 	}
 	isPrivate := true
-	autoInit := true
 	r := &github.Repository{Name: &name,
 		Private:     &isPrivate,
 		Description: &description,
@@ -8,7 +8,6 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
-	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
@@ -16,6 +15,7 @@ import (
 	"time"
 
 	"github.com/kubefirst/kubefirst/internal/gitClient"
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	internalSSH "github.com/kubefirst/kubefirst/internal/ssh"
 
 	"github.com/kubefirst/kubefirst/internal/argocd"
@@ -172,7 +172,7 @@ func AwaitHostNTimes(appName string, dryRun bool, times int) bool {
 	hostReady := false
 	for i := 0; i < max; i++ {
 		hostedZoneName := viper.GetString("aws.hostedzonename")
-		resp, _ := http.Get(fmt.Sprintf("https://%s.%s", appName, hostedZoneName))
+		resp, _ := httpCommon.NewClient().Get(fmt.Sprintf("https://%s.%s", appName, hostedZoneName))
 		if resp != nil && resp.StatusCode == 200 {
 			log.Info().Msgf("%s host resolved, 30 second grace period required...", appName)
 			time.Sleep(time.Second * 30)
@@ -326,7 +326,7 @@ func GitlabKeyUpload(dryRun bool) {
 
 		gitlabURLBase := viper.GetString("gitlab.local.service")
 
-		resp, err := http.PostForm(gitlabURLBase+"/api/v4/user/keys?private_token="+gitlabToken, data)
+		resp, err := httpCommon.NewClient().PostForm(gitlabURLBase+"/api/v4/user/keys?private_token="+gitlabToken, data)
 		if err != nil {
 			log.Panic().Msgf("%s", err)
 		}
@@ -4,12 +4,13 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/rs/zerolog/log"
 	"github.com/xanzy/go-gitlab"
 )
 
 func NewGitLabClient(token string) *gitlab.Client {
-	git, err := gitlab.NewClient(token)
+	git, err := gitlab.NewClient(token, gitlab.WithHTTPClient(httpCommon.NewClient()))
 	if err != nil {
 		fmt.Println(err)
 	}
@@ -101,6 +102,23 @@ func (gl *GitLabWrapper) GetGroups() ([]gitlab.Group, error) {
 	return container, nil
 }
 
+// CreateProject creates a private project in a group
+func (gl *GitLabWrapper) CreateProject(projectName string, description string, groupID int) error {
+	visibility := gitlab.PrivateVisibility
+	project, _, err := gl.Client.Projects.CreateProject(&gitlab.CreateProjectOptions{
+		Name:        &projectName,
+		Description: &description,
+		NamespaceID: &groupID,
+		Visibility:  &visibility,
+	})
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("created project %s", project.PathWithNamespace)
+
+	return nil
+}
+
 // GetProjectID
 func (gl *GitLabWrapper) GetProjectID(projectName string) (int, error) {
 	owned := true
@@ -132,6 +150,38 @@ func (gl *GitLabWrapper) GetProjectID(projectName string) (int, error) {
 	return 0, errors.New(fmt.Sprintf("could not get project ID for project %s", projectName))
 }
 
+// UpdateProjectHookURL points the webhooks of a project delivered to oldURL at newURL, returns the number of
+// updated webhooks
+func (gl *GitLabWrapper) UpdateProjectHookURL(projectName, oldURL, newURL, hookToken string) (int, error) {
+	projectID, err := gl.GetProjectID(projectName)
+	if err != nil {
+		return 0, err
+	}
+
+	hooks, _, err := gl.Client.Projects.ListProjectHooks(projectID, &gitlab.ListProjectHooksOptions{PerPage: 100})
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, hook := range hooks {
+		if hook.URL != oldURL {
+			continue
+		}
+		_, _, err = gl.Client.Projects.EditProjectHook(projectID, hook.ID, &gitlab.EditProjectHookOptions{
+			URL:   &newURL,
+			Token: &hookToken,
+		})
+		if err != nil {
+			return updated, err
+		}
+		log.Info().Msgf("updated webhook %d of project %s", hook.ID, projectName)
+		updated++
+	}
+
+	return updated, nil
+}
+
 // GetProjects
 func (gl *GitLabWrapper) GetProjects() ([]gitlab.Project, error) {
 	owned := true
@@ -411,3 +461,26 @@ func (gl *GitLabWrapper) ListProjectDeployTokens(projectName string) ([]gitlab.D
 
 	return container, nil
 }
+
+// Merge Requests
+
+// CreateMergeRequest opens a merge request in a project and returns its web url
+func (gl *GitLabWrapper) CreateMergeRequest(projectName, sourceBranch, targetBranch, title, description string) (string, error) {
+	projectID, err := gl.GetProjectID(projectName)
+	if err != nil {
+		return "", err
+	}
+
+	mergeRequest, _, err := gl.Client.MergeRequests.CreateMergeRequest(projectID, &gitlab.CreateMergeRequestOptions{
+		Title:        &title,
+		Description:  &description,
+		SourceBranch: &sourceBranch,
+		TargetBranch: &targetBranch,
+	})
+	if err != nil {
+		return "", err
+	}
+	log.Info().Msgf("created merge request %d for project %s", mergeRequest.IID, projectName)
+
+	return mergeRequest.WebURL, nil
+}
@@ -0,0 +1,81 @@
+package gitopsTemplate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ignoredExtensions are never detokenized, regardless of their content
+var ignoredExtensions = map[string]bool{
+	".eot":   true,
+	".gif":   true,
+	".gz":    true,
+	".ico":   true,
+	".jar":   true,
+	".jpeg":  true,
+	".jpg":   true,
+	".pdf":   true,
+	".png":   true,
+	".tgz":   true,
+	".ttf":   true,
+	".woff":  true,
+	".woff2": true,
+	".zip":   true,
+}
+
+// ignoredDirectories are never walked during detokenization
+var ignoredDirectories = map[string]bool{
+	".git":       true,
+	".terraform": true,
+}
+
+// DetokenizeDirectory walks root and rewrites every text file with the content returned by replace.
+// Binary files, symlinks, .git and .terraform trees are left untouched and file modes are preserved.
+func DetokenizeDirectory(root string, replace func(content string) string) error {
+	return filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			if path != root && ignoredDirectories[fi.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// filepath.Walk does not follow symlinks, their targets inside root are visited on their own
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		if ignoredExtensions[strings.ToLower(filepath.Ext(fi.Name()))] {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !IsTextFile(fi.Name(), content) {
+			return nil
+		}
+
+		newContent := replace(string(content))
+		if newContent == string(content) {
+			return nil
+		}
+
+		err = os.WriteFile(path, []byte(newContent), fi.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		return os.Chmod(path, fi.Mode().Perm())
+	})
+}
+
+// IsTextFile reports whether a file can be detokenized, files with an ignored extension or binary content can't
+func IsTextFile(name string, content []byte) bool {
+	return !ignoredExtensions[strings.ToLower(filepath.Ext(name))] && !isBinary(content)
+}
@@ -0,0 +1,77 @@
+package gitopsTemplate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetokenizeDirectory(t *testing.T) {
+	root := t.TempDir()
+	binaryContent := "\x89PNG\x00<CLUSTER_NAME>"
+
+	writeTemplateFile(t, root, "registry/app.yaml", "name: <CLUSTER_NAME>")
+	writeTemplateFile(t, root, "logo.png", "<CLUSTER_NAME>")
+	writeTemplateFile(t, root, "assets/blob", binaryContent)
+	writeTemplateFile(t, root, ".git/config", "<CLUSTER_NAME>")
+	writeTemplateFile(t, root, "terraform/github/.terraform/state", "<CLUSTER_NAME>")
+	writeTemplateFile(t, root, "scripts/run.sh", "echo <CLUSTER_NAME>")
+	if err := os.Chmod(filepath.Join(root, "scripts/run.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("app.yaml", filepath.Join(root, "registry/link.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := DetokenizeDirectory(root, func(content string) string {
+		return strings.Replace(content, "<CLUSTER_NAME>", "kubefirst", -1)
+	})
+	if err != nil {
+		t.Fatalf("DetokenizeDirectory() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"text files are detokenized", "registry/app.yaml", "name: kubefirst"},
+		{"ignored extensions are untouched", "logo.png", "<CLUSTER_NAME>"},
+		{"binary content is untouched", "assets/blob", binaryContent},
+		{".git is skipped", ".git/config", "<CLUSTER_NAME>"},
+		{".terraform is skipped", "terraform/github/.terraform/state", "<CLUSTER_NAME>"},
+		{"scripts are detokenized", "scripts/run.sh", "echo kubefirst"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := os.ReadFile(filepath.Join(root, tt.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("%s content = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("file modes are preserved", func(t *testing.T) {
+		fi, err := os.Stat(filepath.Join(root, "scripts/run.sh"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != 0755 {
+			t.Errorf("scripts/run.sh mode = %v, want %v", fi.Mode().Perm(), os.FileMode(0755))
+		}
+	})
+
+	t.Run("symlinks are preserved", func(t *testing.T) {
+		target, err := os.Readlink(filepath.Join(root, "registry/link.yaml"))
+		if err != nil {
+			t.Fatalf("registry/link.yaml is no longer a symlink: %v", err)
+		}
+		if target != "app.yaml" {
+			t.Errorf("registry/link.yaml target = %q, want %q", target, "app.yaml")
+		}
+	})
+}
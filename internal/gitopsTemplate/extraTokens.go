@@ -0,0 +1,103 @@
+package gitopsTemplate
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ExtraTokensConfigKey is the viper key user defined tokens are persisted under as KEY=VALUE entries
+const ExtraTokensConfigKey = "flags.extra-tokens"
+
+var extraTokenNamePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// ParseExtraTokens builds the user defined tokens from a yaml values file and --set KEY=VALUE entries,
+// --set entries take precedence over the values file. The returned map is keyed by `<KEY>`.
+func ParseExtraTokens(setValues []string, valuesFile string) (map[string]string, error) {
+	extraTokens := map[string]string{}
+
+	if valuesFile != "" {
+		content, err := os.ReadFile(valuesFile)
+		if err != nil {
+			return nil, err
+		}
+		values := map[string]string{}
+		err = yaml.Unmarshal(content, &values)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing token values file %s: %s", valuesFile, err)
+		}
+		for key, value := range values {
+			err = addExtraToken(extraTokens, key, value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, setValue := range setValues {
+		key, value, found := strings.Cut(setValue, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid --set value %q, expected KEY=VALUE", setValue)
+		}
+		err := addExtraToken(extraTokens, key, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return extraTokens, nil
+}
+
+// addExtraToken validates a user defined token name against the reserved built-in tokens
+func addExtraToken(extraTokens map[string]string, key, value string) error {
+	name := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(key), "<"), ">")
+	if !extraTokenNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid token name %q, token names must be upper case letters, digits and underscores", key)
+	}
+
+	token := fmt.Sprintf("<%s>", name)
+	if IsReservedToken(token) {
+		return fmt.Errorf("token %s is reserved by kubefirst and cannot be overridden", token)
+	}
+
+	extraTokens[token] = value
+	return nil
+}
+
+// IsReservedToken reports whether a token is detokenized by kubefirst for any cloud provider
+func IsReservedToken(token string) bool {
+	for cloudProvider := range supportedTokens {
+		if IsSupportedToken(cloudProvider, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtraTokensToConfig converts user defined tokens to sorted KEY=VALUE entries, viper lower cases map keys
+// so the tokens are persisted as a list
+func ExtraTokensToConfig(extraTokens map[string]string) []string {
+	entries := []string{}
+	for token, value := range extraTokens {
+		entries = append(entries, fmt.Sprintf("%s=%s", strings.Trim(token, "<>"), value))
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// ExtraTokensFromConfig converts persisted KEY=VALUE entries back to user defined tokens
+func ExtraTokensFromConfig(entries []string) (map[string]string, error) {
+	return ParseExtraTokens(entries, "")
+}
+
+// ReplaceExtraTokens substitutes user defined tokens in content
+func ReplaceExtraTokens(content string, extraTokens map[string]string) string {
+	for token, value := range extraTokens {
+		content = strings.Replace(content, token, value, -1)
+	}
+	return content
+}
@@ -0,0 +1,85 @@
+package gitopsTemplate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseExtraTokens(t *testing.T) {
+	valuesFile := filepath.Join(t.TempDir(), "values.yaml")
+	err := os.WriteFile(valuesFile, []byte("COST_CENTER: platform\nSLACK_CHANNEL: \"#alerts\"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		setValues  []string
+		valuesFile string
+		want       map[string]string
+		wantErr    bool
+	}{
+		{
+			name:      "set values",
+			setValues: []string{"COST_CENTER=platform", "<TEAM>=a=b"},
+			want:      map[string]string{"<COST_CENTER>": "platform", "<TEAM>": "a=b"},
+		},
+		{
+			name:       "set values override the values file",
+			setValues:  []string{"COST_CENTER=finance"},
+			valuesFile: valuesFile,
+			want:       map[string]string{"<COST_CENTER>": "finance", "<SLACK_CHANNEL>": "#alerts"},
+		},
+		{
+			name:      "missing value separator",
+			setValues: []string{"COST_CENTER"},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid token name",
+			setValues: []string{"cost-center=platform"},
+			wantErr:   true,
+		},
+		{
+			name:      "reserved token name",
+			setValues: []string{"CLUSTER_NAME=other"},
+			wantErr:   true,
+		},
+		{
+			name:      "token reserved by another cloud provider",
+			setValues: []string{"KUBE_CONFIG_PATH=/tmp/kubeconfig"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExtraTokens(tt.setValues, tt.valuesFile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseExtraTokens() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseExtraTokens() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtraTokensConfigRoundTrip(t *testing.T) {
+	extraTokens := map[string]string{"<COST_CENTER>": "platform", "<SLACK_CHANNEL>": "#alerts"}
+
+	entries := ExtraTokensToConfig(extraTokens)
+	want := []string{"COST_CENTER=platform", "SLACK_CHANNEL=#alerts"}
+	if !reflect.DeepEqual(entries, want) {
+		t.Fatalf("ExtraTokensToConfig() = %v, want %v", entries, want)
+	}
+
+	got, err := ExtraTokensFromConfig(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, extraTokens) {
+		t.Errorf("ExtraTokensFromConfig() = %v, want %v", got, extraTokens)
+	}
+}
@@ -0,0 +1,226 @@
+package gitopsTemplate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// SeverityError marks a finding that will break an installation
+	SeverityError = "error"
+	// SeverityWarning marks a finding that will leave content un-detokenized
+	SeverityWarning = "warning"
+)
+
+// Finding is a single problem reported by Lint
+type Finding struct {
+	Severity string
+	Path     string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s: %s", f.Severity, f.Path, f.Message)
+}
+
+// LintOptions describes the installation a gitops template is linted against
+type LintOptions struct {
+	CloudProvider    string
+	GitProvider      string
+	ClusterType      string
+	KubefirstVersion string
+}
+
+// expectedPath is a path that the create commands copy or rename from the template
+type expectedPath struct {
+	path        string
+	dir         bool
+	scanTokens  bool
+	description string
+}
+
+// expectedLayout returns the paths the create command for a cloud provider relies on, relative to the template root
+func expectedLayout(opts LintOptions) ([]expectedPath, error) {
+	driverDir := fmt.Sprintf("%s-%s", opts.CloudProvider, opts.GitProvider)
+
+	switch opts.CloudProvider {
+	case "k3d":
+		layout := []expectedPath{
+			{driverDir, true, true, "driver content copied to the gitops repository root"},
+			{fmt.Sprintf(".kubefirst/clusters/%s-template", opts.ClusterType), true, true, "cluster content copied to registry/<cluster-name>"},
+			{fmt.Sprintf("%s/terraform/%s", driverDir, opts.GitProvider), true, false, "terraform entrypoint for the git provider"},
+			{fmt.Sprintf("%s/terraform/%s/remote-backend.md", driverDir, opts.GitProvider), false, false, "remote backend renamed to remote-backend.tf after the state is migrated"},
+			{fmt.Sprintf("%s/terraform/vault", driverDir), true, false, "terraform entrypoint for vault"},
+			{fmt.Sprintf("%s/terraform/users", driverDir), true, false, "terraform entrypoint for users"},
+			{".kubefirst/ci/.argo", true, true, "argo workflows ci content copied to the metaphor repository"},
+		}
+		switch opts.GitProvider {
+		case "github":
+			layout = append(layout, expectedPath{".kubefirst/ci/.github", true, true, "github actions ci content copied to the metaphor repository"})
+		case "gitlab":
+			layout = append(layout, expectedPath{".kubefirst/ci/.gitlab-ci.yml", false, true, "gitlab ci content copied to the metaphor repository"})
+		}
+		return layout, nil
+	case "civo":
+		if opts.GitProvider != "github" {
+			return nil, fmt.Errorf("git provider %q is not supported for cloud provider civo", opts.GitProvider)
+		}
+		return []expectedPath{
+			{driverDir, true, true, "driver content copied to the gitops repository root"},
+			{fmt.Sprintf("%s-cluster-template", opts.ClusterType), true, true, "cluster content copied to registry/<cluster-name>"},
+			{fmt.Sprintf("%s/terraform/%s", driverDir, opts.GitProvider), true, false, "terraform entrypoint for the git provider"},
+			{fmt.Sprintf("%s/terraform/civo", driverDir), true, false, "terraform entrypoint for civo"},
+			{fmt.Sprintf("%s/terraform/vault", driverDir), true, false, "terraform entrypoint for vault"},
+			{fmt.Sprintf("%s/terraform/users", driverDir), true, false, "terraform entrypoint for users"},
+			{"argo-workflows/.argo", true, true, "argo workflows ci content copied to the metaphor repository"},
+			{"argo-workflows/.github", true, true, "github actions ci content copied to the metaphor repository"},
+		}, nil
+	default:
+		return nil, fmt.Errorf("cloud provider %q is not supported", opts.CloudProvider)
+	}
+}
+
+// Lint checks a gitops template directory against the structure and tokens the create command for
+// the provided cloud provider, git provider and cluster type expects. Findings are sorted by path.
+func Lint(templateDir string, opts LintOptions) ([]Finding, error) {
+	findings, err := lintLayout(templateDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := ReadManifest(templateDir)
+	if err != nil {
+		findings = append(findings, Finding{SeverityError, ManifestFile, err.Error()})
+	} else if manifest == nil {
+		findings = append(findings, Finding{SeverityWarning, ManifestFile, "missing, the template compatibility check will be skipped"})
+	} else {
+		err = manifest.Check(opts.KubefirstVersion, opts.CloudProvider, nil)
+		if err != nil {
+			findings = append(findings, Finding{SeverityError, ManifestFile, err.Error()})
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Path < findings[j].Path
+	})
+
+	return findings, nil
+}
+
+// lintLayout checks the paths the create command relies on and the tokens in them
+func lintLayout(templateDir string, opts LintOptions) ([]Finding, error) {
+	layout, err := expectedLayout(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(templateDir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", templateDir)
+	}
+
+	findings := []Finding{}
+	for _, expected := range layout {
+		fullPath := filepath.Join(templateDir, expected.path)
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			findings = append(findings, Finding{SeverityError, expected.path, fmt.Sprintf("missing (%s)", expected.description)})
+			continue
+		}
+		if expected.dir != info.IsDir() {
+			kind := "a file"
+			if expected.dir {
+				kind = "a directory"
+			}
+			findings = append(findings, Finding{SeverityError, expected.path, fmt.Sprintf("expected %s (%s)", kind, expected.description)})
+			continue
+		}
+		if expected.scanTokens {
+			tokenFindings, err := lintTokens(templateDir, expected.path, opts.CloudProvider)
+			if err != nil {
+				return nil, err
+			}
+			findings = append(findings, tokenFindings...)
+		}
+	}
+	return findings, nil
+}
+
+// HasErrors reports whether any finding has error severity
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// lintTokens reports tokens under relPath that are not detokenized for the cloud provider
+func lintTokens(templateDir, relPath, cloudProvider string) ([]Finding, error) {
+	findings := []Finding{}
+
+	err := filepath.Walk(filepath.Join(templateDir, relPath), func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if fi.Name() == ".git" || fi.Name() == ".terraform" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !fi.Mode().IsRegular() || ignoredExtensions[strings.ToLower(filepath.Ext(fi.Name()))] {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if isBinary(content) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(templateDir, path)
+		if err != nil {
+			return err
+		}
+		for _, token := range FindTokens(content) {
+			if !IsSupportedToken(cloudProvider, token) {
+				findings = append(findings, Finding{SeverityWarning, filepath.ToSlash(rel), fmt.Sprintf("token %s is not detokenized for %s", token, cloudProvider)})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return findings, nil
+}
+
+// isBinary uses the same heuristic as git, content with a NUL byte in the first 8000 bytes is binary
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) != -1
+}
+
+// FormatFindings renders findings one per line
+func FormatFindings(findings []Finding) string {
+	var b strings.Builder
+	for _, f := range findings {
+		b.WriteString(f.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,120 @@
+package gitopsTemplate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTemplateFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func k3dGithubTemplate(t *testing.T) string {
+	root := t.TempDir()
+	writeTemplateFile(t, root, "k3d-github/terraform/github/main.tf", `owner = "<GITHUB_OWNER>"`)
+	writeTemplateFile(t, root, "k3d-github/terraform/github/remote-backend.md", "")
+	writeTemplateFile(t, root, "k3d-github/terraform/vault/main.tf", "")
+	writeTemplateFile(t, root, "k3d-github/terraform/users/main.tf", "")
+	writeTemplateFile(t, root, ".kubefirst/clusters/mgmt-template/app.yaml", "name: <CLUSTER_NAME>")
+	writeTemplateFile(t, root, ".kubefirst/ci/.argo/ci.yaml", "")
+	writeTemplateFile(t, root, ".kubefirst/ci/.github/workflows/main.yml", "")
+	writeTemplateFile(t, root, ManifestFile, "kubefirstVersion: \">=2.0.0\"\nrequiredTokens:\n  - <CLUSTER_NAME>\n")
+	return root
+}
+
+func TestLint(t *testing.T) {
+	opts := LintOptions{CloudProvider: "k3d", GitProvider: "github", ClusterType: "mgmt", KubefirstVersion: "2.0.1"}
+
+	tests := []struct {
+		name       string
+		mutate     func(t *testing.T, root string)
+		wantPaths  []string
+		wantErrors bool
+	}{
+		{
+			name:   "valid template",
+			mutate: func(t *testing.T, root string) {},
+		},
+		{
+			name: "missing driver directory",
+			mutate: func(t *testing.T, root string) {
+				os.RemoveAll(filepath.Join(root, "k3d-github"))
+			},
+			wantPaths: []string{
+				"k3d-github",
+				"k3d-github/terraform/github",
+				"k3d-github/terraform/github/remote-backend.md",
+				"k3d-github/terraform/users",
+				"k3d-github/terraform/vault",
+			},
+			wantErrors: true,
+		},
+		{
+			name: "missing remote backend",
+			mutate: func(t *testing.T, root string) {
+				os.Remove(filepath.Join(root, "k3d-github/terraform/github/remote-backend.md"))
+			},
+			wantPaths:  []string{"k3d-github/terraform/github/remote-backend.md"},
+			wantErrors: true,
+		},
+		{
+			name: "unknown token",
+			mutate: func(t *testing.T, root string) {
+				writeTemplateFile(t, root, ".kubefirst/clusters/mgmt-template/extra.yaml", "<AWS_ACCOUNT_ID>")
+			},
+			wantPaths: []string{".kubefirst/clusters/mgmt-template/extra.yaml"},
+		},
+		{
+			name: "incompatible manifest",
+			mutate: func(t *testing.T, root string) {
+				writeTemplateFile(t, root, ManifestFile, "kubefirstVersion: \">=2.1.0\"\n")
+			},
+			wantPaths:  []string{ManifestFile},
+			wantErrors: true,
+		},
+		{
+			name: "binary files are not scanned",
+			mutate: func(t *testing.T, root string) {
+				writeTemplateFile(t, root, "k3d-github/logo.png", "\x00<AWS_ACCOUNT_ID>")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := k3dGithubTemplate(t)
+			tt.mutate(t, root)
+
+			findings, err := Lint(root, opts)
+			if err != nil {
+				t.Fatalf("Lint() error = %v", err)
+			}
+			if len(findings) != len(tt.wantPaths) {
+				t.Fatalf("Lint() findings = %v, want paths %v", findings, tt.wantPaths)
+			}
+			for i, f := range findings {
+				if f.Path != tt.wantPaths[i] {
+					t.Errorf("Lint() finding %d path = %q, want %q", i, f.Path, tt.wantPaths[i])
+				}
+			}
+			if HasErrors(findings) != tt.wantErrors {
+				t.Errorf("HasErrors() = %v, want %v", HasErrors(findings), tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestLintUnsupportedProvider(t *testing.T) {
+	_, err := Lint(t.TempDir(), LintOptions{CloudProvider: "civo", GitProvider: "gitlab", ClusterType: "mgmt"})
+	if err == nil {
+		t.Error("Lint() expected an error for civo with gitlab")
+	}
+}
@@ -0,0 +1,187 @@
+package gitopsTemplate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v2"
+)
+
+// ManifestFile is the path of the compatibility manifest relative to the template root
+const ManifestFile = ".kubefirst/compatibility.yaml"
+
+// Manifest declares which kubefirst versions a template supports and which tokens it expects
+//
+// Example:
+//
+//	kubefirstVersion: ">=2.0.0 <2.1.0"
+//	requiredTokens:
+//	  - <CLUSTER_NAME>
+//	  - <GITOPS_REPO_GIT_URL>
+type Manifest struct {
+	KubefirstVersion string   `yaml:"kubefirstVersion"`
+	RequiredTokens   []string `yaml:"requiredTokens"`
+}
+
+// ReadManifest reads the compatibility manifest of a template, it returns nil when the template has none
+func ReadManifest(templateDir string) (*Manifest, error) {
+	content, err := os.ReadFile(filepath.Join(templateDir, ManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := Manifest{}
+	err = yaml.Unmarshal(content, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %s", ManifestFile, err)
+	}
+
+	return &manifest, nil
+}
+
+// Check validates the manifest of a gitops template against the running cli version and the tokens provided for
+// a cloud provider, including user defined tokens. Development builds skip the version range check.
+func (m *Manifest) Check(cliVersion, cloudProvider string, extraTokens map[string]string) error {
+	return m.check(cliVersion, cloudProvider, IsSupportedToken, extraTokens)
+}
+
+// CheckMetaphor validates the manifest of a metaphor template like Check, against the tokens the metaphor
+// detokenizer of a cloud provider replaces
+func (m *Manifest) CheckMetaphor(cliVersion, cloudProvider string, extraTokens map[string]string) error {
+	return m.check(cliVersion, cloudProvider, IsSupportedMetaphorToken, extraTokens)
+}
+
+func (m *Manifest) check(cliVersion, cloudProvider string, isSupported func(cloudProvider, token string) bool, extraTokens map[string]string) error {
+	problems := []string{}
+
+	if m.KubefirstVersion != "" && cliVersion != "development" {
+		ok, err := versionInRange(cliVersion, m.KubefirstVersion)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("template requires kubefirst %s, this is kubefirst %s", m.KubefirstVersion, cliVersion))
+		}
+	}
+
+	missing := []string{}
+	for _, token := range m.RequiredTokens {
+		if _, ok := extraTokens[token]; !ok && !isSupported(cloudProvider, token) {
+			missing = append(missing, token)
+		}
+	}
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("template requires tokens that kubefirst does not provide for %s: %s", cloudProvider, strings.Join(missing, ", ")))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// CheckTemplateCompatibility checks a gitops templateDir against the layout create expects for opts, the checks of
+// `kubefirst template lint`, and checks its manifest. Templates without a manifest only have their layout checked.
+func CheckTemplateCompatibility(templateDir string, opts LintOptions, extraTokens map[string]string) error {
+	findings, err := lintLayout(templateDir, opts)
+	if err != nil {
+		return err
+	}
+	problems := []string{}
+	for _, finding := range findings {
+		if finding.Severity == SeverityError {
+			problems = append(problems, fmt.Sprintf("%s %s", finding.Path, finding.Message))
+		}
+	}
+
+	return checkTemplateCompatibility(templateDir, opts.KubefirstVersion, problems, func(manifest *Manifest) error {
+		return manifest.Check(opts.KubefirstVersion, opts.CloudProvider, extraTokens)
+	})
+}
+
+// CheckMetaphorTemplateCompatibility reads the manifest from a metaphor templateDir and checks it with CheckMetaphor
+func CheckMetaphorTemplateCompatibility(templateDir, cliVersion, cloudProvider string, extraTokens map[string]string) error {
+	return checkTemplateCompatibility(templateDir, cliVersion, nil, func(manifest *Manifest) error {
+		return manifest.CheckMetaphor(cliVersion, cloudProvider, extraTokens)
+	})
+}
+
+func checkTemplateCompatibility(templateDir, cliVersion string, problems []string, check func(manifest *Manifest) error) error {
+	manifest, err := ReadManifest(templateDir)
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		log.Warn().Msgf("no %s found in %s, skipping the template version and token checks", ManifestFile, templateDir)
+	} else {
+		err = check(manifest)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("template at %s is not compatible with this kubefirst cli: %s. use a compatible template version or pass --skip-template-compat-check to continue anyway", templateDir, strings.Join(problems, "; "))
+	}
+
+	log.Info().Msgf("template at %s is compatible with kubefirst %s", templateDir, cliVersion)
+	return nil
+}
+
+// versionInRange evaluates a space separated list of constraints such as ">=2.0.0 <2.1.0", all of them must match
+func versionInRange(version, constraints string) (bool, error) {
+	v := canonicalVersion(version)
+	if !semver.IsValid(v) {
+		return false, fmt.Errorf("kubefirst version %q is not a valid semantic version", version)
+	}
+
+	for _, constraint := range strings.Fields(constraints) {
+		operator := ""
+		for _, op := range []string{">=", "<=", ">", "<", "="} {
+			if strings.HasPrefix(constraint, op) {
+				operator = op
+				break
+			}
+		}
+		target := canonicalVersion(strings.TrimPrefix(constraint, operator))
+		if !semver.IsValid(target) {
+			return false, fmt.Errorf("invalid version constraint %q in %s", constraint, ManifestFile)
+		}
+
+		compare := semver.Compare(v, target)
+		var ok bool
+		switch operator {
+		case ">=":
+			ok = compare >= 0
+		case ">":
+			ok = compare > 0
+		case "<=":
+			ok = compare <= 0
+		case "<":
+			ok = compare < 0
+		case "=", "":
+			ok = compare == 0
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// canonicalVersion adds the `v` prefix kubefirst tags omit but semver requires
+func canonicalVersion(version string) string {
+	if strings.HasPrefix(version, "v") {
+		return version
+	}
+	return "v" + version
+}
@@ -0,0 +1,113 @@
+package gitopsTemplate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManifestCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		manifest    Manifest
+		cliVersion  string
+		extraTokens map[string]string
+		wantErr     bool
+	}{
+		{
+			name:       "version in range",
+			manifest:   Manifest{KubefirstVersion: ">=2.0.0 <2.1.0"},
+			cliVersion: "2.0.4",
+		},
+		{
+			name:       "version below range",
+			manifest:   Manifest{KubefirstVersion: ">=2.0.0 <2.1.0"},
+			cliVersion: "1.11.2",
+			wantErr:    true,
+		},
+		{
+			name:       "version above range",
+			manifest:   Manifest{KubefirstVersion: ">=2.0.0 <2.1.0"},
+			cliVersion: "2.1.0",
+			wantErr:    true,
+		},
+		{
+			name:       "exact version",
+			manifest:   Manifest{KubefirstVersion: "2.0.4"},
+			cliVersion: "v2.0.4",
+		},
+		{
+			name:       "development builds skip the version check",
+			manifest:   Manifest{KubefirstVersion: ">=9.0.0"},
+			cliVersion: "development",
+		},
+		{
+			name:       "invalid constraint",
+			manifest:   Manifest{KubefirstVersion: ">=two"},
+			cliVersion: "2.0.4",
+			wantErr:    true,
+		},
+		{
+			name:       "supported tokens",
+			manifest:   Manifest{RequiredTokens: []string{"<CLUSTER_NAME>", "<DOMAIN_NAME>"}},
+			cliVersion: "2.0.4",
+		},
+		{
+			name:       "unsupported tokens",
+			manifest:   Manifest{RequiredTokens: []string{"<CLUSTER_NAME>", "<COST_CENTER>"}},
+			cliVersion: "2.0.4",
+			wantErr:    true,
+		},
+		{
+			name:        "user defined tokens",
+			manifest:    Manifest{RequiredTokens: []string{"<CLUSTER_NAME>", "<COST_CENTER>"}},
+			cliVersion:  "2.0.4",
+			extraTokens: map[string]string{"<COST_CENTER>": "platform"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.manifest.Check(tt.cliVersion, "k3d", tt.extraTokens)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManifestCheckMetaphor(t *testing.T) {
+	manifest := Manifest{RequiredTokens: []string{"<CLUSTER_NAME>", "<CONTAINER_REGISTRY>"}}
+	if err := manifest.CheckMetaphor("2.0.4", "k3d", nil); err != nil {
+		t.Errorf("CheckMetaphor() error = %v, want the metaphor tokens supported", err)
+	}
+
+	// the gitops detokenizer replaces <GITOPS_REPO_GIT_URL>, the metaphor detokenizer does not
+	manifest = Manifest{RequiredTokens: []string{"<GITOPS_REPO_GIT_URL>"}}
+	if err := manifest.Check("2.0.4", "k3d", nil); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if err := manifest.CheckMetaphor("2.0.4", "k3d", nil); err == nil {
+		t.Error("CheckMetaphor() expected an error for a token the metaphor detokenizer does not replace")
+	}
+}
+
+func TestCheckTemplateCompatibility(t *testing.T) {
+	opts := LintOptions{CloudProvider: "k3d", GitProvider: "github", ClusterType: "mgmt", KubefirstVersion: "2.0.1"}
+
+	root := k3dGithubTemplate(t)
+	if err := CheckTemplateCompatibility(root, opts, nil); err != nil {
+		t.Errorf("CheckTemplateCompatibility() error = %v, want a valid template accepted", err)
+	}
+
+	// the layout is checked with and without a manifest
+	os.RemoveAll(filepath.Join(root, ".kubefirst/clusters/mgmt-template"))
+	err := CheckTemplateCompatibility(root, opts, nil)
+	if err == nil || !strings.Contains(err.Error(), ".kubefirst/clusters/mgmt-template") {
+		t.Errorf("CheckTemplateCompatibility() error = %v, want the missing cluster template reported", err)
+	}
+	os.Remove(filepath.Join(root, ManifestFile))
+	if err := CheckTemplateCompatibility(root, opts, nil); err == nil {
+		t.Error("CheckTemplateCompatibility() accepted a template without a manifest and a cluster template")
+	}
+}
@@ -0,0 +1,324 @@
+package gitopsTemplate
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxMergeLines bounds the size of the line matrix used to merge a single file, larger files
+// that changed on both sides are reported as conflicts instead
+const maxMergeLines = 4000000
+
+// MergeResult summarises a three-way merge of template trees into a gitops repository
+type MergeResult struct {
+	Added     []string
+	Updated   []string
+	Deleted   []string
+	Conflicts []string
+}
+
+// HasChanges reports whether the merge modified the gitops repository
+func (r MergeResult) HasChanges() bool {
+	return len(r.Added)+len(r.Updated)+len(r.Deleted)+len(r.Conflicts) > 0
+}
+
+// MergeTrees applies the changes between the rendered baseDir and theirsDir templates to oursDir, the
+// live gitops repository. Files changed on both sides are merged line by line, overlapping changes are
+// written with conflict markers and reported as conflicts.
+func MergeTrees(baseDir, oursDir, theirsDir, theirsLabel string) (*MergeResult, error) {
+	base, err := readTree(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	ours, err := readTree(oursDir)
+	if err != nil {
+		return nil, err
+	}
+	theirs, err := readTree(theirsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := map[string]bool{}
+	for _, tree := range []map[string][]byte{base, ours, theirs} {
+		for path := range tree {
+			paths[path] = true
+		}
+	}
+	sortedPaths := []string{}
+	for path := range paths {
+		sortedPaths = append(sortedPaths, path)
+	}
+	sort.Strings(sortedPaths)
+
+	result := &MergeResult{}
+	for _, path := range sortedPaths {
+		baseContent, inBase := base[path]
+		oursContent, inOurs := ours[path]
+		theirsContent, inTheirs := theirs[path]
+		target := filepath.Join(oursDir, path)
+
+		switch {
+		// upstream did not change the file, keep whatever the repository has
+		case inBase == inTheirs && bytes.Equal(baseContent, theirsContent):
+			continue
+		// both sides made the same change
+		case inOurs == inTheirs && bytes.Equal(oursContent, theirsContent):
+			continue
+		// upstream deleted a file the repository did not modify
+		case !inTheirs && inOurs && bytes.Equal(baseContent, oursContent):
+			err = os.Remove(target)
+			if err != nil {
+				return nil, err
+			}
+			result.Deleted = append(result.Deleted, path)
+		// upstream deleted a file the repository modified or the repository deleted a file upstream changed
+		case !inTheirs || (inBase && !inOurs):
+			result.Conflicts = append(result.Conflicts, path)
+		// upstream added or changed a file the repository did not touch
+		case !inOurs || (inBase && bytes.Equal(baseContent, oursContent)):
+			err = writeMergedFile(oursDir, path, theirsContent, theirsDir)
+			if err != nil {
+				return nil, err
+			}
+			if inOurs {
+				result.Updated = append(result.Updated, path)
+			} else {
+				result.Added = append(result.Added, path)
+			}
+		// both sides changed the file
+		default:
+			merged, conflict := MergeFile(baseContent, oursContent, theirsContent, theirsLabel)
+			err = writeMergedFile(oursDir, path, merged, oursDir)
+			if err != nil {
+				return nil, err
+			}
+			if conflict {
+				result.Conflicts = append(result.Conflicts, path)
+			} else {
+				result.Updated = append(result.Updated, path)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// MergeFile performs a line based three-way merge, overlapping changes are wrapped in conflict markers
+func MergeFile(base, ours, theirs []byte, theirsLabel string) ([]byte, bool) {
+	if isBinary(base) || isBinary(ours) || isBinary(theirs) {
+		return ours, true
+	}
+
+	baseLines := splitLines(base)
+	oursLines := splitLines(ours)
+	theirsLines := splitLines(theirs)
+
+	if len(baseLines)*len(oursLines) > maxMergeLines || len(baseLines)*len(theirsLines) > maxMergeLines {
+		return joinLines(conflictLines(oursLines, theirsLines, theirsLabel), ours), true
+	}
+
+	matchOurs := matchLines(baseLines, oursLines)
+	matchTheirs := matchLines(baseLines, theirsLines)
+
+	var merged []string
+	conflict := false
+	i, a, b := 0, 0, 0
+	for i < len(baseLines) || a < len(oursLines) || b < len(theirsLines) {
+		// stable line, unchanged on both sides
+		if i < len(baseLines) && matchOurs[i] == a && matchTheirs[i] == b {
+			merged = append(merged, baseLines[i])
+			i, a, b = i+1, a+1, b+1
+			continue
+		}
+
+		// find the next base line kept by both sides
+		next := i
+		for next < len(baseLines) && (matchOurs[next] == -1 || matchTheirs[next] == -1) {
+			next++
+		}
+		nextA, nextB := len(oursLines), len(theirsLines)
+		if next < len(baseLines) {
+			nextA, nextB = matchOurs[next], matchTheirs[next]
+		}
+
+		baseChunk := baseLines[i:next]
+		oursChunk := oursLines[a:nextA]
+		theirsChunk := theirsLines[b:nextB]
+
+		switch {
+		case equalLines(oursChunk, baseChunk):
+			merged = append(merged, theirsChunk...)
+		case equalLines(theirsChunk, baseChunk), equalLines(oursChunk, theirsChunk):
+			merged = append(merged, oursChunk...)
+		default:
+			conflict = true
+			merged = append(merged, conflictLines(oursChunk, theirsChunk, theirsLabel)...)
+		}
+		i, a, b = next, nextA, nextB
+	}
+
+	return joinLines(merged, ours), conflict
+}
+
+// matchLines returns for every base line the index of the matching line in other, or -1, using the longest common subsequence
+func matchLines(base, other []string) []int {
+	n, m := len(base), len(other)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if base[i] == other[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	matches := make([]int, n)
+	for i := range matches {
+		matches[i] = -1
+	}
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case base[i] == other[j]:
+			matches[i] = j
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return matches
+}
+
+// conflictLines wraps both sides of an overlapping change in git style conflict markers
+func conflictLines(ours, theirs []string, theirsLabel string) []string {
+	lines := []string{"<<<<<<< gitops"}
+	lines = append(lines, ours...)
+	lines = append(lines, "=======")
+	lines = append(lines, theirs...)
+	return append(lines, fmt.Sprintf(">>>>>>> %s", theirsLabel))
+}
+
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+// joinLines rebuilds file content, keeping the trailing newline convention of reference
+func joinLines(lines []string, reference []byte) []byte {
+	if len(lines) == 0 {
+		return []byte{}
+	}
+	content := strings.Join(lines, "\n")
+	if len(reference) == 0 || bytes.HasSuffix(reference, []byte("\n")) {
+		content += "\n"
+	}
+	return []byte(content)
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// readTree reads every regular file under root keyed by its slash separated relative path, skipping .git
+func readTree(root string) (map[string][]byte, error) {
+	tree := map[string][]byte{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		tree[filepath.ToSlash(rel)] = content
+		return nil
+	})
+	return tree, err
+}
+
+// writeMergedFile writes content to path under oursDir using the file mode of path under modeDir
+func writeMergedFile(oursDir, path string, content []byte, modeDir string) error {
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(filepath.Join(modeDir, path)); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	target := filepath.Join(oursDir, path)
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(target, content, mode)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(target, mode)
+}
+
+// Summary renders the merge result as a markdown pull request body
+func (r MergeResult) Summary(fromVersion, toVersion string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Upgrades the gitops repository from gitops-template %s to %s.\n", fromVersion, toVersion)
+
+	sections := []struct {
+		title string
+		paths []string
+	}{
+		{"Conflicts", r.Conflicts},
+		{"Added", r.Added},
+		{"Updated", r.Updated},
+		{"Deleted", r.Deleted},
+	}
+	for _, section := range sections {
+		if len(section.paths) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s (%d)\n\n", section.title, len(section.paths))
+		if section.title == "Conflicts" {
+			b.WriteString("These files changed in both the gitops repository and the template. Resolve the conflict markers, or compare against the template when the file was deleted on one side, before merging.\n\n")
+		}
+		for _, path := range section.paths {
+			fmt.Fprintf(&b, "- `%s`\n", path)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,134 @@
+package gitopsTemplate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMergeFile(t *testing.T) {
+	base := "replicas: 1\nimage: app:1.0\nport: 80\n"
+
+	tests := []struct {
+		name         string
+		ours         string
+		theirs       string
+		want         string
+		wantConflict bool
+	}{
+		{
+			name:   "only upstream changed",
+			ours:   base,
+			theirs: "replicas: 1\nimage: app:2.0\nport: 80\n",
+			want:   "replicas: 1\nimage: app:2.0\nport: 80\n",
+		},
+		{
+			name:   "non overlapping changes",
+			ours:   "replicas: 3\nimage: app:1.0\nport: 80\n",
+			theirs: "replicas: 1\nimage: app:1.0\nport: 8080\n",
+			want:   "replicas: 3\nimage: app:1.0\nport: 8080\n",
+		},
+		{
+			name:   "upstream appended lines",
+			ours:   "replicas: 3\nimage: app:1.0\nport: 80\n",
+			theirs: base + "debug: false\n",
+			want:   "replicas: 3\nimage: app:1.0\nport: 80\ndebug: false\n",
+		},
+		{
+			name:         "overlapping changes",
+			ours:         "replicas: 1\nimage: app:1.5\nport: 80\n",
+			theirs:       "replicas: 1\nimage: app:2.0\nport: 80\n",
+			want:         "replicas: 1\n<<<<<<< gitops\nimage: app:1.5\n=======\nimage: app:2.0\n>>>>>>> gitops-template 2.0.0\nport: 80\n",
+			wantConflict: true,
+		},
+		{
+			name:         "binary content",
+			ours:         "\x00ours",
+			theirs:       "\x00theirs",
+			want:         "\x00ours",
+			wantConflict: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflict := MergeFile([]byte(base), []byte(tt.ours), []byte(tt.theirs), "gitops-template 2.0.0")
+			if string(got) != tt.want {
+				t.Errorf("MergeFile() = %q, want %q", got, tt.want)
+			}
+			if conflict != tt.wantConflict {
+				t.Errorf("MergeFile() conflict = %v, want %v", conflict, tt.wantConflict)
+			}
+		})
+	}
+}
+
+func TestMergeTrees(t *testing.T) {
+	baseDir, oursDir, theirsDir := t.TempDir(), t.TempDir(), t.TempDir()
+
+	// unchanged locally, updated upstream
+	writeTemplateFile(t, baseDir, "registry/argocd.yaml", "version: 1\n")
+	writeTemplateFile(t, oursDir, "registry/argocd.yaml", "version: 1\n")
+	writeTemplateFile(t, theirsDir, "registry/argocd.yaml", "version: 2\n")
+	// added upstream
+	writeTemplateFile(t, theirsDir, "registry/new.yaml", "new: true\n")
+	// unchanged locally, deleted upstream
+	writeTemplateFile(t, baseDir, "registry/old.yaml", "old: true\n")
+	writeTemplateFile(t, oursDir, "registry/old.yaml", "old: true\n")
+	// modified locally, deleted upstream
+	writeTemplateFile(t, baseDir, "registry/custom.yaml", "custom: 1\n")
+	writeTemplateFile(t, oursDir, "registry/custom.yaml", "custom: 2\n")
+	// changed on both sides
+	writeTemplateFile(t, baseDir, "registry/vault.yaml", "a: 1\nkind: vault\nb: 1\n")
+	writeTemplateFile(t, oursDir, "registry/vault.yaml", "a: 2\nkind: vault\nb: 1\n")
+	writeTemplateFile(t, theirsDir, "registry/vault.yaml", "a: 1\nkind: vault\nb: 2\n")
+	// only in the repository
+	writeTemplateFile(t, oursDir, "registry/app.yaml", "app: true\n")
+	writeTemplateFile(t, oursDir, ".git/HEAD", "ref: refs/heads/main\n")
+
+	result, err := MergeTrees(baseDir, oursDir, theirsDir, "gitops-template 2.0.0")
+	if err != nil {
+		t.Fatalf("MergeTrees() error = %v", err)
+	}
+
+	want := &MergeResult{
+		Added:     []string{"registry/new.yaml"},
+		Updated:   []string{"registry/argocd.yaml", "registry/vault.yaml"},
+		Deleted:   []string{"registry/old.yaml"},
+		Conflicts: []string{"registry/custom.yaml"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("MergeTrees() = %+v, want %+v", result, want)
+	}
+
+	content, err := os.ReadFile(filepath.Join(oursDir, "registry/vault.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "a: 2\nkind: vault\nb: 2\n" {
+		t.Errorf("registry/vault.yaml = %q, want %q", content, "a: 2\nkind: vault\nb: 2\n")
+	}
+	if _, err := os.Stat(filepath.Join(oursDir, "registry/old.yaml")); !os.IsNotExist(err) {
+		t.Error("registry/old.yaml was not deleted")
+	}
+	if _, err := os.Stat(filepath.Join(oursDir, "registry/app.yaml")); err != nil {
+		t.Error("registry/app.yaml was removed")
+	}
+}
+
+func TestMergeResultSummary(t *testing.T) {
+	result := MergeResult{
+		Added:     []string{"registry/new.yaml"},
+		Conflicts: []string{"registry/vault.yaml"},
+	}
+
+	want := "Upgrades the gitops repository from gitops-template 2.0.0 to 2.1.0.\n" +
+		"\n### Conflicts (1)\n\n" +
+		"These files changed in both the gitops repository and the template. Resolve the conflict markers, or compare against the template when the file was deleted on one side, before merging.\n\n" +
+		"- `registry/vault.yaml`\n" +
+		"\n### Added (1)\n\n" +
+		"- `registry/new.yaml`\n"
+	if got := result.Summary("2.0.0", "2.1.0"); got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
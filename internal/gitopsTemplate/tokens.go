@@ -0,0 +1,155 @@
+package gitopsTemplate
+
+import (
+	"regexp"
+	"sort"
+)
+
+// tokenPattern matches the `<TOKEN_NAME>` placeholders used across the gitops and metaphor templates
+var tokenPattern = regexp.MustCompile(`<[A-Z][A-Z0-9_]*>`)
+
+// supportedTokens are the tokens each cloud provider detokenizes during create
+var supportedTokens = map[string][]string{
+	"civo": {
+		"<ADMIN_EMAIL_ADDRESS>",
+		"<ARGOCD_INGRESS_NO_HTTP_URL>",
+		"<ARGO_CD_INGRESS_URL>",
+		"<ARGO_WORKFLOWS_INGRESS_NO_HTTPS_URL>",
+		"<ARGO_WORKFLOWS_INGRESS_URL>",
+		"<ATLANTIS_ALLOW_LIST>",
+		"<ATLANTIS_INGRESS_NO_HTTPS_URL>",
+		"<ATLANTIS_INGRESS_URL>",
+		"<CHARTMUSEUM_INGRESS_URL>",
+		"<CHECKOUT_CWFT_TEMPLATE>",
+		"<CLOUD_PROVIDER>",
+		"<CLOUD_REGION>",
+		"<CLUSTER_ID>",
+		"<CLUSTER_NAME>",
+		"<CLUSTER_TYPE>",
+		"<COMMIT_CWFT_TEMPLATE>",
+		"<CONTAINER_REGISTRY_URL>",
+		"<DOMAIN_NAME>",
+		"<GITHUB_HOST>",
+		"<GITHUB_OWNER>",
+		"<GITHUB_USER>",
+		"<GITOPS_REPO_ATLANTIS_WEBHOOK_URL>",
+		"<GITOPS_REPO_GIT_URL>",
+		"<GITOPS_REPO_NO_HTTPS_URL>",
+		"<GIT_DESCRIPTION>",
+		"<GIT_NAMESPACE>",
+		"<GIT_PROVIDER>",
+		"<GIT_RUNNER>",
+		"<GIT_RUNNER_DESCRIPTION>",
+		"<GIT_RUNNER_NS>",
+		"<GIT_URL>",
+		"<KUBEFIRST_STATE_STORE_BUCKET>",
+		"<KUBEFIRST_TEAM>",
+		"<KUBEFIRST_VERSION>",
+		"<KUBE_CONFIG_PATH>",
+		"<METAPHOR_DEVELOPMENT_INGRESS_URL>",
+		"<METAPHOR_FRONT_DEVELOPMENT_INGRESS_URL>",
+		"<METAPHOR_FRONT_PRODUCTION_INGRESS_URL>",
+		"<METAPHOR_FRONT_STAGING_INGRESS_URL>",
+		"<METAPHOR_PRODUCTION_INGRESS_URL>",
+		"<METAPHOR_STAGING_INGRESS_URL>",
+		"<USE_TELEMETRY>",
+		"<VAULT_INGRESS_NO_HTTPS_URL>",
+		"<VAULT_INGRESS_URL>",
+		"<VOUCH_INGRESS_URL>",
+	},
+	"k3d": {
+		"<ALERTS_EMAIL>",
+		"<ARGO_CD_INGRESS_URL>",
+		"<ARGO_WORKFLOWS_INGRESS_URL>",
+		"<ATLANTIS_ALLOW_LIST>",
+		"<ATLANTIS_INGRESS_URL>",
+		"<CLOUD_PROVIDER>",
+		"<CLOUD_REGION>",
+		"<CLUSTER_ID>",
+		"<CLUSTER_NAME>",
+		"<CLUSTER_TYPE>",
+		"<CONTAINER_REGISTRY>",
+		"<DOMAIN_NAME>",
+		"<GITHUB_HOST>",
+		"<GITHUB_OWNER>",
+		"<GITHUB_USER>",
+		"<GITLAB_HOST>",
+		"<GITLAB_OWNER>",
+		"<GITLAB_OWNER_GROUP_ID>",
+		"<GITOPS_REPO_GIT_URL>",
+		"<GIT_PROVIDER>",
+		"<KUBEFIRST_TEAM>",
+		"<KUBEFIRST_VERSION>",
+		"<METAPHOR_DEVELOPMENT_INGRESS_URL>",
+		"<METAPHOR_PRODUCTION_INGRESS_URL>",
+		"<METAPHOR_STAGING_INGRESS_URL>",
+		"<NGROK_HOST>",
+		"<USE_TELEMETRY>",
+		"<VAULT_INGRESS_URL>",
+	},
+}
+
+// supportedMetaphorTokens are the tokens each cloud provider detokenizes in a metaphor template, they have to
+// match the metaphor detokenizers of internal/k3d and internal/civo
+var supportedMetaphorTokens = map[string][]string{
+	"civo": {
+		"<CHECKOUT_CWFT_TEMPLATE>",
+		"<CLOUD_REGION>",
+		"<CLUSTER_NAME>",
+		"<COMMIT_CWFT_TEMPLATE>",
+		"<CONTAINER_REGISTRY_URL>",
+		"<DOMAIN_NAME>",
+		"<METAPHOR_FRONT_DEVELOPMENT_INGRESS_URL>",
+		"<METAPHOR_FRONT_PRODUCTION_INGRESS_URL>",
+		"<METAPHOR_FRONT_STAGING_INGRESS_URL>",
+	},
+	"k3d": {
+		"<CLOUD_REGION>",
+		"<CLUSTER_NAME>",
+		"<CONTAINER_REGISTRY>",
+		"<DOMAIN_NAME>",
+		"<METAPHOR_DEVELOPMENT_INGRESS_URL>",
+		"<METAPHOR_PRODUCTION_INGRESS_URL>",
+		"<METAPHOR_STAGING_INGRESS_URL>",
+	},
+}
+
+// SupportedTokens returns the sorted list of tokens detokenized for a cloud provider
+func SupportedTokens(cloudProvider string) []string {
+	tokens := append([]string{}, supportedTokens[cloudProvider]...)
+	sort.Strings(tokens)
+	return tokens
+}
+
+// IsSupportedToken reports whether a token is detokenized for a cloud provider
+func IsSupportedToken(cloudProvider, token string) bool {
+	return containsToken(supportedTokens[cloudProvider], token)
+}
+
+// IsSupportedMetaphorToken reports whether a token is detokenized in a metaphor template for a cloud provider
+func IsSupportedMetaphorToken(cloudProvider, token string) bool {
+	return containsToken(supportedMetaphorTokens[cloudProvider], token)
+}
+
+func containsToken(tokens []string, token string) bool {
+	for _, t := range tokens {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+// FindTokens returns the distinct tokens found in content
+func FindTokens(content []byte) []string {
+	seen := map[string]bool{}
+	tokens := []string{}
+	for _, match := range tokenPattern.FindAll(content, -1) {
+		token := string(match)
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
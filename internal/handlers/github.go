@@ -11,6 +11,7 @@ import (
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/internal/reports"
 	"github.com/kubefirst/kubefirst/internal/services"
 	"github.com/kubefirst/kubefirst/pkg"
@@ -64,7 +65,7 @@ func (handler GitHubHandler) AuthenticateUser() (string, error) {
 	req.Header.Add("Content-Type", pkg.JSONContentType)
 	req.Header.Add("Accept", pkg.JSONContentType)
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := httpCommon.NewClient().Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -133,7 +134,7 @@ func (handler GitHubHandler) GetGitHubUser(gitHubAccessToken string) (string, er
 	req.Header.Add("Accept", "application/vnd.github+json")
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", gitHubAccessToken))
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := httpCommon.NewClient().Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -178,7 +179,7 @@ func (handler GitHubHandler) CheckGithubOrganizationPermissions(githubToken, git
 	req.Header.Add("Accept", "application/vnd.github+json")
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", githubToken))
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := httpCommon.NewClient().Do(req)
 	if err != nil {
 		return err
 	}
@@ -73,6 +73,8 @@ type HelmRepo struct {
 	RepoURL      string
 	Namespace    string
 	ChartVersion string
+	// ChartArchive installs from a local chart archive instead of the repository, i.e. from an offline bundle
+	ChartArchive string
 }
 
 func AddRepoAndUpdateRepo(dryRun bool, helmClientPath string, helmRepo HelmRepo, kubeconfigPath string) error {
@@ -105,10 +107,15 @@ func Install(dryRun bool, helmClientPath string, helmRepo HelmRepo, kubeconfigPa
 		return nil
 	}
 
+	chart := fmt.Sprintf("%s/%s", helmRepo.RepoName, helmRepo.ChartName)
+	if helmRepo.ChartArchive != "" {
+		chart = helmRepo.ChartArchive
+	}
+
 	log.Info().Msgf("executing `helm install %s` and waiting for completion ", helmRepo.ChartName)
 	// todo remove `"--set", "fullnameOverride=argocd", "--set", "nameOverride=argocd"` see type ConfigRepo
 	//! , "--values", argoCDInitValuesYamlPath,
-	a, b, err := pkg.ExecShellReturnStrings(helmClientPath, "--kubeconfig", kubeconfigPath, "upgrade", "--install", helmRepo.ChartName, "--namespace", helmRepo.Namespace, "--create-namespace", "--version", helmRepo.ChartVersion, "--wait", "--set", "fullnameOverride=argocd", "--set", "nameOverride=argocd", fmt.Sprintf("%s/%s", helmRepo.RepoName, helmRepo.ChartName))
+	a, b, err := pkg.ExecShellReturnStrings(helmClientPath, "--kubeconfig", kubeconfigPath, "upgrade", "--install", helmRepo.ChartName, "--namespace", helmRepo.Namespace, "--create-namespace", "--version", helmRepo.ChartVersion, "--wait", "--set", "fullnameOverride=argocd", "--set", "nameOverride=argocd", chart)
 	log.Info().Msg(a)
 	log.Info().Msg(b)
 	if err != nil {
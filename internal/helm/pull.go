@@ -0,0 +1,138 @@
+package helm
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubefirst/kubefirst/internal/downloadManager"
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
+	"github.com/kubefirst/kubefirst/pkg"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+// repoIndex is the part of a helm repository index.yaml needed to locate a chart archive
+type repoIndex struct {
+	Entries map[string][]struct {
+		Version string   `yaml:"version"`
+		URLs    []string `yaml:"urls"`
+	} `yaml:"entries"`
+}
+
+// ChartArchiveURL reads the repository index of helmRepo and returns the download url of its chart version
+func ChartArchiveURL(helmRepo HelmRepo) (string, error) {
+	indexURL := strings.TrimSuffix(helmRepo.RepoURL, "/") + "/index.yaml"
+	resp, err := httpCommon.NewClient().Get(indexURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to read %s, the HTTP return status is: %s", indexURL, resp.Status)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return findChartArchiveURL(content, helmRepo)
+}
+
+func findChartArchiveURL(index []byte, helmRepo HelmRepo) (string, error) {
+	var repo repoIndex
+	err := yaml.Unmarshal(index, &repo)
+	if err != nil {
+		return "", fmt.Errorf("could not parse the index of %s: %w", helmRepo.RepoURL, err)
+	}
+
+	for _, entry := range repo.Entries[helmRepo.ChartName] {
+		if entry.Version != helmRepo.ChartVersion || len(entry.URLs) == 0 {
+			continue
+		}
+		chartURL, err := url.Parse(entry.URLs[0])
+		if err != nil {
+			return "", err
+		}
+		if chartURL.IsAbs() {
+			return chartURL.String(), nil
+		}
+		// chart urls are allowed to be relative to the repository
+		base, err := url.Parse(strings.TrimSuffix(helmRepo.RepoURL, "/") + "/")
+		if err != nil {
+			return "", err
+		}
+		return base.ResolveReference(chartURL).String(), nil
+	}
+	return "", fmt.Errorf("chart %s %s not found in %s", helmRepo.ChartName, helmRepo.ChartVersion, helmRepo.RepoURL)
+}
+
+// PullChart downloads the chart archive of helmRepo to destinationDir without a helm client
+func PullChart(helmRepo HelmRepo, destinationDir string) (string, error) {
+	chartURL, err := ChartArchiveURL(helmRepo)
+	if err != nil {
+		return "", err
+	}
+
+	chartPath := filepath.Join(destinationDir, fmt.Sprintf("%s-%s.tgz", helmRepo.ChartName, helmRepo.ChartVersion))
+	log.Info().Msgf("downloading chart %s %s from %s", helmRepo.ChartName, helmRepo.ChartVersion, chartURL)
+
+	err = downloadManager.DownloadFile(chartPath, chartURL)
+	if err != nil {
+		return "", err
+	}
+	return chartPath, nil
+}
+
+// ChartImages renders a chart archive with the values kubefirst installs it with and returns the images it runs
+func ChartImages(helmClientPath string, helmRepo HelmRepo) ([]string, error) {
+	out, _, err := pkg.ExecShellReturnStrings(helmClientPath, "template", helmRepo.ChartName, helmRepo.ChartArchive, "--namespace", helmRepo.Namespace, "--set", "fullnameOverride=argocd", "--set", "nameOverride=argocd")
+	if err != nil {
+		return nil, fmt.Errorf("error rendering chart %s: %w", helmRepo.ChartArchive, err)
+	}
+	return RenderedImages(out), nil
+}
+
+// TemplateImages renders a chart archive with values, such as the helm values of an argocd application, and
+// returns the images it runs
+func TemplateImages(helmClientPath string, helmRepo HelmRepo, values string) ([]string, error) {
+	valuesFile, err := os.CreateTemp("", "kubefirst-values-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(valuesFile.Name())
+	_, err = valuesFile.WriteString(values)
+	valuesFile.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	out, _, err := pkg.ExecShellReturnStrings(helmClientPath, "template", helmRepo.ChartName, helmRepo.ChartArchive, "--namespace", helmRepo.Namespace, "--values", valuesFile.Name())
+	if err != nil {
+		return nil, fmt.Errorf("error rendering chart %s: %w", helmRepo.ChartArchive, err)
+	}
+	return RenderedImages(out), nil
+}
+
+// RenderedImages collects the distinct `image:` values of rendered manifests in order of appearance
+func RenderedImages(manifests string) []string {
+	images := []string{}
+	seen := map[string]bool{}
+	for _, line := range strings.Split(manifests, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "- "))
+		if !strings.HasPrefix(line, "image:") {
+			continue
+		}
+		image := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "image:")), `"'`)
+		if image == "" || seen[image] {
+			continue
+		}
+		seen[image] = true
+		images = append(images, image)
+	}
+	return images
+}
@@ -0,0 +1,59 @@
+package helm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindChartArchiveURL(t *testing.T) {
+	index := []byte(`
+entries:
+  argo-cd:
+  - version: 4.10.6
+    urls:
+    - https://github.com/argoproj/argo-helm/releases/download/argo-cd-4.10.6/argo-cd-4.10.6.tgz
+  - version: 4.10.5
+    urls:
+    - charts/argo-cd-4.10.5.tgz
+`)
+
+	tests := []struct {
+		name    string
+		version string
+		want    string
+		wantErr bool
+	}{
+		{name: "absolute url", version: "4.10.6", want: "https://github.com/argoproj/argo-helm/releases/download/argo-cd-4.10.6/argo-cd-4.10.6.tgz"},
+		{name: "url relative to the repository", version: "4.10.5", want: "https://argoproj.github.io/argo-helm/charts/argo-cd-4.10.5.tgz"},
+		{name: "missing version", version: "1.0.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findChartArchiveURL(index, HelmRepo{RepoURL: "https://argoproj.github.io/argo-helm", ChartName: "argo-cd", ChartVersion: tt.version})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findChartArchiveURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("findChartArchiveURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderedImages(t *testing.T) {
+	manifests := `
+spec:
+  containers:
+  - image: quay.io/argoproj/argocd:v2.4.7
+  initContainers:
+    - name: copyutil
+      image: "quay.io/argoproj/argocd:v2.4.7"
+  - image: 'ghcr.io/dexidp/dex:v2.32.0'
+  - image:
+`
+	want := []string{"quay.io/argoproj/argocd:v2.4.7", "ghcr.io/dexidp/dex:v2.32.0"}
+	if got := RenderedImages(manifests); !reflect.DeepEqual(got, want) {
+		t.Errorf("RenderedImages() = %v, want %v", got, want)
+	}
+}
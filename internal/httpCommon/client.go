@@ -1,19 +1,110 @@
 package httpCommon
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"io"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 )
 
+var (
+	settingsMu sync.RWMutex
+	// rootCAs are the system roots and the --ca-bundle certificates, nil uses the system roots
+	rootCAs *x509.CertPool
+	// hostTimeouts are the --http-timeout request timeouts by host, * applies to every other host
+	hostTimeouts = map[string]time.Duration{}
+)
+
+// NewClient returns the http client every kubefirst integration uses. It honours the HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY environment variables, trusts the system roots and the --ca-bundle certificates and applies the
+// --http-timeout request timeouts, see Configure.
+func NewClient() *http.Client {
+	return &http.Client{
+		Transport: NewTransport(false),
+	}
+}
+
+// NewTransport returns the transport of NewClient for integrations that build their own client
+// allowInsecure defines: tls.Config{InsecureSkipVerify: allowInsecure}
+func NewTransport(allowInsecure bool) http.RoundTripper {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+
+	customTransport := http.DefaultTransport.(*http.Transport).Clone()
+	customTransport.Proxy = http.ProxyFromEnvironment
+	customTransport.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: allowInsecure,
+		RootCAs:            rootCAs,
+	}
+
+	if len(hostTimeouts) == 0 {
+		return customTransport
+	}
+	timeouts := map[string]time.Duration{}
+	for host, timeout := range hostTimeouts {
+		timeouts[host] = timeout
+	}
+	return &timeoutTransport{base: customTransport, timeouts: timeouts}
+}
+
 // CustomHttpClient - creates a http client based on k1 standards
 // allowInsecure defines: tls.Config{InsecureSkipVerify: allowInsecure}
 func CustomHttpClient(allowInsecure bool) *http.Client {
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	customTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: allowInsecure}
 	httpClient := http.Client{
-		Transport: customTransport,
+		Transport: NewTransport(allowInsecure),
 		Timeout:   time.Second * 90,
 	}
 	return &httpClient
 }
+
+// timeoutTransport bounds every request, including reading the response body, by the timeout of its host
+type timeoutTransport struct {
+	base     http.RoundTripper
+	timeouts map[string]time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timeout := HostTimeout(t.timeouts, req.URL.Hostname())
+	if timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// HostTimeout returns the timeout of host: an exact match, then the closest parent domain, then *
+func HostTimeout(timeouts map[string]time.Duration, host string) time.Duration {
+	host = strings.ToLower(host)
+	for domain := host; domain != ""; {
+		if timeout, ok := timeouts[domain]; ok {
+			return timeout
+		}
+		i := strings.Index(domain, ".")
+		if i < 0 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+	return timeouts["*"]
+}
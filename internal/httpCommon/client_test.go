@@ -0,0 +1,135 @@
+package httpCommon
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHostTimeout(t *testing.T) {
+	timeouts := map[string]time.Duration{
+		"api.github.com": 10 * time.Second,
+		"github.com":     20 * time.Second,
+		"*":              time.Minute,
+	}
+
+	tests := []struct {
+		host string
+		want time.Duration
+	}{
+		{host: "api.github.com", want: 10 * time.Second},
+		{host: "API.GitHub.com", want: 10 * time.Second},
+		{host: "objects.github.com", want: 20 * time.Second},
+		{host: "github.com", want: 20 * time.Second},
+		{host: "api.civo.com", want: time.Minute},
+		{host: "127.0.0.1", want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got := HostTimeout(timeouts, tt.host)
+			if got != tt.want {
+				t.Errorf("HostTimeout() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := HostTimeout(map[string]time.Duration{}, "github.com"); got != 0 {
+		t.Errorf("HostTimeout() without timeouts = %s, want 0", got)
+	}
+}
+
+func TestParseHostTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts map[string]string
+		want     map[string]time.Duration
+		wantErr  bool
+	}{
+		{name: "hosts", timeouts: map[string]string{"API.github.com": "30s", "*": "2m"}, want: map[string]time.Duration{"api.github.com": 30 * time.Second, "*": 2 * time.Minute}},
+		{name: "empty", timeouts: map[string]string{}, want: map[string]time.Duration{}},
+		{name: "not a duration", timeouts: map[string]string{"github.com": "30"}, wantErr: true},
+		{name: "negative", timeouts: map[string]string{"github.com": "-1s"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHostTimeouts(tt.timeouts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHostTimeouts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseHostTimeouts() = %v, want %v", got, tt.want)
+			}
+			for host, timeout := range tt.want {
+				if got[host] != timeout {
+					t.Errorf("ParseHostTimeouts()[%s] = %s, want %s", host, got[host], timeout)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	t.Setenv("SSL_CERT_FILE", "")
+	t.Cleanup(func() {
+		Configure("", nil, "")
+	})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	_, err := NewClient().Get(server.URL)
+	if err == nil {
+		t.Fatal("expected the test server certificate to be untrusted without --ca-bundle")
+	}
+
+	k1Dir := t.TempDir()
+	caBundle := filepath.Join(k1Dir, "test-ca.pem")
+	err = os.WriteFile(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Configure(caBundle, map[string]string{"127.0.0.1": "50ms"}, k1Dir)
+	if err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if os.Getenv("SSL_CERT_FILE") != filepath.Join(k1Dir, CABundleFile) {
+		t.Errorf("SSL_CERT_FILE = %s, want %s", os.Getenv("SSL_CERT_FILE"), filepath.Join(k1Dir, CABundleFile))
+	}
+
+	res, err := NewClient().Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected the --ca-bundle certificate to be trusted: %v", err)
+	}
+	res.Body.Close()
+
+	_, err = NewClient().Get(server.URL + "/slow")
+	if err == nil {
+		t.Error("expected the request to exceed the --http-timeout of 127.0.0.1")
+	}
+}
+
+func TestConfigureRejectsInvalidBundle(t *testing.T) {
+	k1Dir := t.TempDir()
+	caBundle := filepath.Join(k1Dir, "test-ca.pem")
+	err := os.WriteFile(caBundle, []byte("not a certificate"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Configure(caBundle, nil, k1Dir)
+	if err == nil {
+		t.Error("Configure() expected an error for a bundle without certificates")
+	}
+}
@@ -0,0 +1,115 @@
+package httpCommon
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	gitclient "github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/rs/zerolog/log"
+)
+
+// CABundleFile is written to the kubefirst directory with the system roots and the --ca-bundle certificates,
+// it is exported as SSL_CERT_FILE so terraform, helm, kubectl and other subprocesses trust the same roots
+const CABundleFile = "ca-bundle.pem"
+
+// systemCertFiles are the usual locations of the system roots, the same list the go runtime reads on linux
+var systemCertFiles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+}
+
+// Configure applies the --ca-bundle and --http-timeout settings to NewClient and to the https transport of the
+// git operations. It runs before any outbound call since the go runtime reads SSL_CERT_FILE only once.
+func Configure(caBundle string, timeouts map[string]string, k1Dir string) error {
+	parsedTimeouts, err := ParseHostTimeouts(timeouts)
+	if err != nil {
+		return err
+	}
+
+	var pool *x509.CertPool
+	if caBundle != "" {
+		pool, err = loadCABundle(caBundle, k1Dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	settingsMu.Lock()
+	rootCAs = pool
+	hostTimeouts = parsedTimeouts
+	settingsMu.Unlock()
+
+	gitclient.InstallProtocol("https", githttp.NewClient(NewClient()))
+	return nil
+}
+
+// ParseHostTimeouts parses --http-timeout values such as api.github.com=30s and *=2m
+func ParseHostTimeouts(timeouts map[string]string) (map[string]time.Duration, error) {
+	parsed := map[string]time.Duration{}
+	for host, value := range timeouts {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid --http-timeout %s=%s, expected a positive duration such as 30s", host, value)
+		}
+		parsed[strings.ToLower(host)] = timeout
+	}
+	return parsed, nil
+}
+
+// loadCABundle writes CABundleFile, exports it as SSL_CERT_FILE and returns the system roots with the bundle added
+func loadCABundle(caBundle, k1Dir string) (*x509.CertPool, error) {
+	bundle, err := os.ReadFile(caBundle)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read --ca-bundle: %w", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("--ca-bundle %s contains no PEM certificates", caBundle)
+	}
+
+	combined := systemRootsPEM()
+	combined = append(combined, '\n')
+	combined = append(combined, bundle...)
+	err = os.MkdirAll(k1Dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	combinedPath := filepath.Join(k1Dir, CABundleFile)
+	err = os.WriteFile(combinedPath, combined, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// also covers clients kubefirst cannot pass NewClient to, such as civogo
+	os.Setenv("SSL_CERT_FILE", combinedPath)
+	log.Info().Msgf("trusting the certificates of %s, subprocesses use %s", caBundle, combinedPath)
+
+	// the system pool is loaded after SSL_CERT_FILE is set, platforms ignoring it get the bundle appended
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	pool.AppendCertsFromPEM(bundle)
+	return pool, nil
+}
+
+func systemRootsPEM() []byte {
+	files := systemCertFiles
+	if sslCertFile := os.Getenv("SSL_CERT_FILE"); sslCertFile != "" {
+		files = []string{sslCertFile}
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err == nil {
+			return content
+		}
+	}
+	return []byte{}
+}
@@ -10,7 +10,8 @@ import (
 )
 
 const (
-	ArgocdPortForwardURL   = "http://localhost:8080"
+	ArgocdHelmChartVersion = "4.10.5"
+	ArgocdHelmRepoURL      = "https://argoproj.github.io/argo-helm"
 	ArgocdURL              = "https://argocd.localdev.me"
 	ArgoWorkflowsURL       = "https://argo.localdev.me"
 	AtlantisURL            = "https://atlantis.localdev.me"
@@ -30,7 +31,6 @@ const (
 	MetaphorProductionURL  = "https://metaphor-production.localdev.me"
 	MkCertVersion          = "v1.4.4"
 	TerraformVersion       = "1.3.8"
-	VaultPortForwardURL    = "http://localhost:8200"
 	VaultURL               = "https://vault.localdev.me"
 )
 
@@ -123,6 +123,8 @@ type GitopsTokenValues struct {
 	GitProvider										string
 	CloudProvider									string
 	ClusterId											string
+	// ExtraTokens are user defined tokens keyed by `<KEY>`
+	ExtraTokens map[string]string
 }
 
 type MetaphorTokenValues struct {
@@ -133,4 +135,6 @@ type MetaphorTokenValues struct {
 	MetaphorDevelopmentIngressURL string
 	MetaphorStagingIngressURL     string
 	MetaphorProductionIngressURL  string
+	// ExtraTokens are user defined tokens keyed by `<KEY>`
+	ExtraTokens map[string]string
 }
@@ -8,12 +8,14 @@ import (
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/kubefirst/kubefirst/configs"
 	"github.com/kubefirst/kubefirst/internal/gitClient"
+	"github.com/kubefirst/kubefirst/internal/gitopsTemplate"
 	"github.com/kubefirst/kubefirst/pkg"
 )
 
 // ClusterCreate create an k3d cluster
-func ClusterCreate(clusterName string, k1Dir string, k3dClient string, kubeconfig string) error {
+func ClusterCreate(clusterName string, k1Dir string, k3dClient string, kubeconfig string, spec ClusterSpec) error {
 	log.Info().Msg("creating K3d cluster...")
 
 	volumeDir := fmt.Sprintf("%s/minio-storage", k1Dir)
@@ -23,16 +25,20 @@ func ClusterCreate(clusterName string, k1Dir string, k3dClient string, kubeconfi
 			log.Info().Msgf("%s directory already exists, continuing", volumeDir)
 		}
 	}
-	_, _, err := pkg.ExecShellReturnStrings(k3dClient, "cluster", "create",
-		clusterName,
-		"--agents", "3",
-		"--agents-memory", "1024m",
-		"--registry-create", "k3d-"+clusterName+"-registry:63630",
-		"--k3s-arg", `--kubelet-arg=eviction-hard=imagefs.available<1%,nodefs.available<1%@agent:*`,
-		"--k3s-arg", `--kubelet-arg=eviction-minimum-reclaim=imagefs.available=1%,nodefs.available=1%@agent:*`,
-		"--port", "80:80@loadbalancer",
-		"--volume", volumeDir+":/tmp/minio-storage",
-		"--port", "443:443@loadbalancer")
+	log.Info().Msgf("k3d cluster %s topology: %s", clusterName, spec)
+	args := spec.CreateArgs(clusterName, volumeDir)
+	if spec.RegistryMirror {
+		err := EnsureMirrors(k3dClient)
+		if err != nil {
+			return err
+		}
+		registriesConfigPath, err := WriteRegistriesConfig(k1Dir)
+		if err != nil {
+			return err
+		}
+		args = append(args, MirrorArgs(registriesConfigPath)...)
+	}
+	_, _, err := pkg.ExecShellReturnStrings(k3dClient, args...)
 
 	if err != nil {
 		log.Info().Msg("error creating k3d cluster")
@@ -64,22 +70,38 @@ func PrepareGitopsRepository(
 	gitopsDir string,
 	gitopsTemplateBranch string,
 	gitopsTemplateURL string,
+	gitopsTemplatePath string,
 	k1Dir string,
+	skipTemplateCompatCheck bool,
 	tokens *GitopsTokenValues,
 ) error {
 
-	gitopsRepo, err := gitClient.CloneRefSetMain(gitopsTemplateBranch, gitopsDir, gitopsTemplateURL)
+	gitopsRepo, err := gitClient.CloneOrInitRepo(gitopsTemplatePath, gitopsTemplateBranch, gitopsDir, gitopsTemplateURL)
 	if err != nil {
 		log.Info().Msgf("error opening repo at: %s", gitopsDir)
+		return err
 	}
 	log.Info().Msg("gitops repository clone complete")
 
+	if !skipTemplateCompatCheck {
+		err = gitopsTemplate.CheckTemplateCompatibility(gitopsDir, gitopsTemplate.LintOptions{
+			CloudProvider:    CloudProvider,
+			GitProvider:      gitProvider,
+			ClusterType:      clusterType,
+			KubefirstVersion: configs.K1Version,
+		}, tokens.ExtraTokens)
+		if err != nil {
+			os.RemoveAll(gitopsDir)
+			return err
+		}
+	}
+
 	err = k3dGithubAdjustGitopsTemplateContent(CloudProvider, clusterName, clusterType, gitProvider, k1Dir, gitopsDir)
 	if err != nil {
 		return err
 	}
 
-	detokenizeGitGitops(gitopsDir, tokens)
+	err = detokenizeGitGitops(gitopsDir, tokens)
 	if err != nil {
 		return err
 	}
@@ -116,23 +138,37 @@ func PrepareMetaphorRepository(
 	metaphorDir string,
 	metaphorTemplateBranch string,
 	metaphorTemplateURL string,
+	metaphorTemplatePath string,
+	skipTemplateCompatCheck bool,
 	tokens *MetaphorTokenValues,
 ) error {
 
 	log.Info().Msg("generating your new metaphor-frontend repository")
-	metaphorRepo, err := gitClient.CloneRefSetMain(metaphorTemplateBranch, metaphorDir, metaphorTemplateURL)
+	metaphorRepo, err := gitClient.CloneOrInitRepo(metaphorTemplatePath, metaphorTemplateBranch, metaphorDir, metaphorTemplateURL)
 	if err != nil {
 		log.Info().Msgf("error opening repo at: %s", metaphorDir)
+		return err
 	}
 
 	log.Info().Msg("metaphor repository clone complete")
 
+	if !skipTemplateCompatCheck {
+		err = gitopsTemplate.CheckMetaphorTemplateCompatibility(metaphorDir, configs.K1Version, CloudProvider, tokens.ExtraTokens)
+		if err != nil {
+			os.RemoveAll(metaphorDir)
+			return err
+		}
+	}
+
 	err = k3dGithubAdjustMetaphorTemplateContent(gitProvider, k1Dir, metaphorDir)
 	if err != nil {
 		return err
 	}
 
-	detokenizeGitMetaphor(metaphorDir, tokens)
+	err = detokenizeGitMetaphor(metaphorDir, tokens)
+	if err != nil {
+		return err
+	}
 
 	err = gitClient.AddRemote(destinationMetaphorRepoGitURL, gitProvider, metaphorRepo)
 	if err != nil {
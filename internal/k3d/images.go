@@ -0,0 +1,187 @@
+package k3d
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubefirst/kubefirst/internal/helm"
+	"github.com/kubefirst/kubefirst/pkg"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+// ClusterImages returns the images k3d runs to create a cluster, read from `k3d version`
+func ClusterImages(k3dClient string) ([]string, error) {
+	out, _, err := pkg.ExecShellReturnStrings(k3dClient, "version")
+	if err != nil {
+		return nil, err
+	}
+	return parseClusterImages(out)
+}
+
+func parseClusterImages(versionOutput string) ([]string, error) {
+	var k3dVersion, k3sVersion string
+	for _, line := range strings.Split(versionOutput, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[1] != "version" {
+			continue
+		}
+		switch fields[0] {
+		case "k3d":
+			k3dVersion = strings.TrimPrefix(fields[2], "v")
+		case "k3s":
+			// k3s image tags replace the + of the release with -
+			k3sVersion = strings.ReplaceAll(fields[2], "+", "-")
+		}
+	}
+	if k3dVersion == "" || k3sVersion == "" {
+		return nil, fmt.Errorf("could not read the k3d and k3s versions from: %s", versionOutput)
+	}
+
+	return []string{
+		fmt.Sprintf("docker.io/rancher/k3s:%s", k3sVersion),
+		fmt.Sprintf("ghcr.io/k3d-io/k3d-proxy:%s", k3dVersion),
+		fmt.Sprintf("ghcr.io/k3d-io/k3d-tools:%s", k3dVersion),
+		"docker.io/library/registry:2",
+	}, nil
+}
+
+// ImportImages imports docker image archives into the nodes of a cluster
+func ImportImages(k3dClient, clusterName string, archives ...string) error {
+	log.Info().Msgf("importing %d image archives into k3d cluster %s", len(archives), clusterName)
+
+	args := append([]string{"image", "import", "--cluster", clusterName}, archives...)
+	_, _, err := pkg.ExecShellReturnStrings(k3dClient, args...)
+	if err != nil {
+		return fmt.Errorf("error importing images into k3d cluster %s: %w", clusterName, err)
+	}
+	return nil
+}
+
+// application is the part of an argocd application needed to render its helm chart
+type application struct {
+	Kind string `yaml:"kind"`
+	Spec struct {
+		Source      applicationSource   `yaml:"source"`
+		Sources     []applicationSource `yaml:"sources"`
+		Destination struct {
+			Namespace string `yaml:"namespace"`
+		} `yaml:"destination"`
+	} `yaml:"spec"`
+}
+
+type applicationSource struct {
+	RepoURL        string `yaml:"repoURL"`
+	Chart          string `yaml:"chart"`
+	TargetRevision string `yaml:"targetRevision"`
+	Helm           struct {
+		Values string `yaml:"values"`
+	} `yaml:"helm"`
+}
+
+// chartRelease is a helm chart an argocd application installs with its values
+type chartRelease struct {
+	repo   helm.HelmRepo
+	values string
+}
+
+// GitopsImages returns the images referenced in the detokenized gitops registry of a cluster, the `image:` values
+// of its manifests and the images of the helm charts its argocd applications install
+func GitopsImages(registryDir, helmClientPath string) ([]string, error) {
+	images := []string{}
+	seen := map[string]bool{}
+	add := func(found []string) {
+		for _, image := range found {
+			// skip values that are still templated
+			if seen[image] || strings.ContainsAny(image, "{}<>$ ") {
+				continue
+			}
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+
+	releases := []chartRelease{}
+	err := filepath.WalkDir(registryDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || (filepath.Ext(path) != ".yaml" && filepath.Ext(path) != ".yml") {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		add(helm.RenderedImages(string(content)))
+		releases = append(releases, chartReleases(content)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	chartsDir, err := os.MkdirTemp("", "kubefirst-charts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(chartsDir)
+
+	rendered := map[string]bool{}
+	for _, release := range releases {
+		key := strings.Join([]string{release.repo.RepoURL, release.repo.ChartName, release.repo.ChartVersion, release.values}, "|")
+		if rendered[key] {
+			continue
+		}
+		rendered[key] = true
+
+		// a chart that can't be pulled or rendered, i.e. from an oci registry, only misses the preload
+		chartArchive, err := helm.PullChart(release.repo, chartsDir)
+		if err != nil {
+			log.Warn().Msgf("skipping the images of chart %s %s: %s", release.repo.ChartName, release.repo.ChartVersion, err)
+			continue
+		}
+		release.repo.ChartArchive = chartArchive
+		chartImages, err := helm.TemplateImages(helmClientPath, release.repo, release.values)
+		if err != nil {
+			log.Warn().Msgf("skipping the images of chart %s %s: %s", release.repo.ChartName, release.repo.ChartVersion, err)
+			continue
+		}
+		add(chartImages)
+	}
+	return images, nil
+}
+
+// chartReleases returns the helm charts of the argocd applications in a manifest file
+func chartReleases(content []byte) []chartRelease {
+	releases := []chartRelease{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var app application
+		// files that are not plain yaml end the search, their images were collected already
+		if err := decoder.Decode(&app); err != nil {
+			return releases
+		}
+		if app.Kind != "Application" {
+			continue
+		}
+		for _, source := range append([]applicationSource{app.Spec.Source}, app.Spec.Sources...) {
+			if source.Chart == "" || source.RepoURL == "" {
+				continue
+			}
+			releases = append(releases, chartRelease{
+				repo: helm.HelmRepo{
+					ChartName:    source.Chart,
+					RepoURL:      source.RepoURL,
+					ChartVersion: source.TargetRevision,
+					Namespace:    app.Spec.Destination.Namespace,
+				},
+				values: source.Helm.Values,
+			})
+		}
+	}
+}
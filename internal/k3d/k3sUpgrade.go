@@ -0,0 +1,427 @@
+package k3d
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubefirst/kubefirst/internal/docker"
+	"github.com/kubefirst/kubefirst/internal/k8s"
+	"github.com/kubefirst/kubefirst/pkg"
+	"github.com/rs/zerolog/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var k3sVersionPartsRegex = regexp.MustCompile(`^v1\.(\d+)\.(\d+)-k3s(\d+)$`)
+
+// Node is a server or agent container of a k3d cluster, its name is the name of the kubernetes node
+type Node struct {
+	Name string
+	Role string
+}
+
+// K3sUpgrade rolls the nodes of a k3d cluster to the k3s binaries of another image. k3d can't change the image of a
+// node and a recreated server loses its datastore, so the binaries are installed into the node containers instead:
+// they keep their volumes, network addresses and the files k3d wrote into them and the new binaries survive a stop
+// and start, but docker still lists the image they were created from.
+type K3sUpgrade struct {
+	Client        *docker.Client
+	Kubeconfig    string
+	KubectlClient string
+	// NodeTimeout bounds the drain of a node and the wait for it to be ready on the new version
+	NodeTimeout time.Duration
+}
+
+// CheckK3sUpgrade validates an upgrade between two k3s versions, kubernetes upgrades one minor version at a time
+// and k3s does not support downgrades
+func CheckK3sUpgrade(current, target string) error {
+	from, err := k3sVersionParts(current)
+	if err != nil {
+		return err
+	}
+	to, err := k3sVersionParts(target)
+	if err != nil {
+		return err
+	}
+
+	switch compareK3sVersions(from, to) {
+	case 0:
+		return fmt.Errorf("the cluster already runs k3s %s", current)
+	case 1:
+		return fmt.Errorf("k3s %s is older than the k3s %s of the cluster, k3s does not support downgrades", target, current)
+	}
+	if to[0]-from[0] > 1 {
+		return fmt.Errorf("kubernetes upgrades one minor version at a time, upgrade from k3s %s to a v1.%d release first", current, from[0]+1)
+	}
+	return nil
+}
+
+// k3sVersionParts splits a k3s version into its kubernetes minor and patch and its k3s release
+func k3sVersionParts(version string) ([3]int, error) {
+	match := k3sVersionPartsRegex.FindStringSubmatch(version)
+	if match == nil {
+		return [3]int{}, fmt.Errorf("invalid k3s version %s, expected a rancher/k3s tag such as v1.25.7-k3s1", version)
+	}
+	var parts [3]int
+	for i := range parts {
+		parts[i], _ = strconv.Atoi(match[i+1])
+	}
+	return parts, nil
+}
+
+func compareK3sVersions(a, b [3]int) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// nodeK3sVersion is the k3s image tag of the kubelet version of a node, v1.25.7+k3s1 is tagged v1.25.7-k3s1
+func nodeK3sVersion(node *v1.Node) string {
+	return strings.ReplaceAll(node.Status.NodeInfo.KubeletVersion, "+", "-")
+}
+
+// ClusterK3sVersion is the oldest k3s version of the nodes, the nodes of an interrupted upgrade run different
+// versions
+func ClusterK3sVersion(kubeconfig string) (string, error) {
+	clientset, err := k8s.GetClientSet(false, kubeconfig)
+	if err != nil {
+		return "", err
+	}
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error listing the cluster nodes: %w", err)
+	}
+
+	var oldest string
+	var oldestParts [3]int
+	for i := range nodes.Items {
+		version := nodeK3sVersion(&nodes.Items[i])
+		parts, err := k3sVersionParts(version)
+		if err != nil {
+			return "", fmt.Errorf("node %s runs %s: %w", nodes.Items[i].Name, version, err)
+		}
+		if oldest == "" || compareK3sVersions(parts, oldestParts) < 0 {
+			oldest, oldestParts = version, parts
+		}
+	}
+	if oldest == "" {
+		return "", errors.New("the cluster has no nodes")
+	}
+	return oldest, nil
+}
+
+// ClusterNodes returns the servers of a cluster followed by its agents, kubelets must not be newer than the api
+// server so servers are upgraded first
+func ClusterNodes(client *docker.Client, clusterName string) ([]Node, error) {
+	containers, err := client.Containers(fmt.Sprintf("k3d-%s-", clusterName))
+	if err != nil {
+		return nil, err
+	}
+
+	servers, agents := []Node{}, []Node{}
+	for _, container := range containers {
+		if container.Labels["k3d.cluster"] != clusterName {
+			continue
+		}
+		switch role := container.Labels["k3d.role"]; role {
+		case "server":
+			servers = append(servers, Node{Name: container.Name, Role: role})
+		case "agent":
+			agents = append(agents, Node{Name: container.Name, Role: role})
+		}
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Name < agents[j].Name })
+	return append(servers, agents...), nil
+}
+
+// RollNodes installs the k3s binaries of image on the nodes one at a time and waits for each node to be ready on
+// version. Nodes already on version are skipped so an interrupted upgrade resumes. The nodes that were changed,
+// the failing one included, are returned to be rolled back.
+func (u K3sUpgrade) RollNodes(nodes []Node, image, version string) ([]Node, error) {
+	log.Info().Msgf("pulling %s", image)
+	err := u.Client.PullImage(image)
+	if err != nil {
+		return nil, err
+	}
+
+	// the binaries are copied out of a container of the image that is never started
+	binaries, err := u.Client.CreateContainer(fmt.Sprintf("kubefirst-k3s-%d", time.Now().Unix()), image)
+	if err != nil {
+		return nil, fmt.Errorf("error creating a container of %s: %w", image, err)
+	}
+	defer u.Client.RemoveContainer(binaries)
+
+	archive, err := u.Client.CopyFromContainer(binaries, "/bin")
+	if err != nil {
+		return nil, err
+	}
+	imagePaths, err := archivePaths(archive)
+	archive.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading /bin of %s: %w", image, err)
+	}
+
+	rolled := []Node{}
+	for _, node := range nodes {
+		current, err := u.nodeVersion(node.Name)
+		if err == nil && current == version {
+			log.Info().Msgf("%s %s already runs k3s %s", node.Role, node.Name, version)
+			continue
+		}
+
+		rolled = append(rolled, node)
+		err = u.rollNode(node, binaries, imagePaths, version)
+		if err != nil {
+			return rolled, fmt.Errorf("error rolling %s %s to k3s %s: %w", node.Role, node.Name, version, err)
+		}
+	}
+	return rolled, nil
+}
+
+func (u K3sUpgrade) rollNode(node Node, binaries string, imagePaths map[string]bool, version string) error {
+	log.Info().Msgf("rolling %s %s to k3s %s", node.Role, node.Name, version)
+
+	// a single server can't be drained, its workloads restart with it
+	if node.Role == "agent" {
+		_, _, err := pkg.ExecShellReturnStrings(u.KubectlClient, "--kubeconfig", u.Kubeconfig, "drain", node.Name,
+			"--ignore-daemonsets", "--delete-emptydir-data", "--timeout", u.NodeTimeout.String())
+		if err != nil {
+			return fmt.Errorf("error draining node: %w", err)
+		}
+	}
+
+	err := u.Client.StopContainer(node.Name)
+	if err != nil {
+		return err
+	}
+
+	// the archive api only adds files, the binaries of the previous version that the image lacks are removed once
+	// the node runs again
+	archive, err := u.Client.CopyFromContainer(node.Name, "/bin")
+	if err != nil {
+		return err
+	}
+	nodePaths, err := archivePaths(archive)
+	archive.Close()
+	if err != nil {
+		return fmt.Errorf("error reading /bin: %w", err)
+	}
+	stale := stalePaths(nodePaths, imagePaths)
+
+	for _, path := range []struct {
+		source string
+		dir    string
+	}{
+		{source: "/bin", dir: "/"},
+		{source: "/etc/os-release", dir: "/etc"},
+	} {
+		archive, err := u.Client.CopyFromContainer(binaries, path.source)
+		if err != nil {
+			return err
+		}
+		err = u.Client.CopyToContainer(node.Name, path.dir, archive)
+		archive.Close()
+		if err != nil {
+			return fmt.Errorf("error copying %s: %w", path.source, err)
+		}
+	}
+	err = u.Client.StartContainer(node.Name)
+	if err != nil {
+		return err
+	}
+
+	err = u.waitForNodeVersion(node.Name, version)
+	if err != nil {
+		return err
+	}
+	if len(stale) > 0 {
+		exitCode, err := u.Client.Exec(node.Name, append([]string{"rm", "-rf"}, stale...))
+		if err != nil {
+			return fmt.Errorf("error removing the previous binaries: %w", err)
+		}
+		if exitCode != 0 {
+			return fmt.Errorf("error removing the previous binaries: rm exited with %d", exitCode)
+		}
+	}
+	if node.Role == "agent" {
+		_, _, err = pkg.ExecShellReturnStrings(u.KubectlClient, "--kubeconfig", u.Kubeconfig, "uncordon", node.Name)
+		if err != nil {
+			return fmt.Errorf("error uncordoning node: %w", err)
+		}
+	}
+	return nil
+}
+
+// archivePaths lists the absolute paths of an archive of the docker archive api, bin/k3s is /bin/k3s
+func archivePaths(archive io.Reader) (map[string]bool, error) {
+	paths := map[string]bool{}
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return paths, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		paths["/"+strings.TrimSuffix(header.Name, "/")] = true
+	}
+}
+
+// stalePaths are the paths of a node that the image lacks, without the paths inside a stale directory
+func stalePaths(nodePaths, imagePaths map[string]bool) []string {
+	candidates := []string{}
+	for path := range nodePaths {
+		if !imagePaths[path] {
+			candidates = append(candidates, path)
+		}
+	}
+	sort.Strings(candidates)
+
+	stale := []string{}
+	for _, path := range candidates {
+		if len(stale) > 0 && strings.HasPrefix(path, stale[len(stale)-1]+"/") {
+			continue
+		}
+		stale = append(stale, path)
+	}
+	return stale
+}
+
+func (u K3sUpgrade) nodeVersion(nodeName string) (string, error) {
+	clientset, err := k8s.GetClientSet(false, u.Kubeconfig)
+	if err != nil {
+		return "", err
+	}
+	node, err := clientset.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady && condition.Status != v1.ConditionTrue {
+			return "", fmt.Errorf("node %s is not ready", nodeName)
+		}
+	}
+	return nodeK3sVersion(node), nil
+}
+
+// waitForNodeVersion waits for a restarted node to be ready on version, the api server is unreachable while a
+// server restarts
+func (u K3sUpgrade) waitForNodeVersion(nodeName, version string) error {
+	for deadline := time.Now().Add(u.NodeTimeout); time.Now().Before(deadline); time.Sleep(5 * time.Second) {
+		current, err := u.nodeVersion(nodeName)
+		if err == nil && current == version {
+			return nil
+		}
+	}
+	return fmt.Errorf("node %s was not ready on k3s %s after %s", nodeName, version, u.NodeTimeout)
+}
+
+// ArgocdApplication is the sync and health status of an argocd application
+type ArgocdApplication struct {
+	Name   string
+	Sync   string
+	Health string
+}
+
+// Ready reports whether the application is Synced and Healthy
+func (a ArgocdApplication) Ready() bool {
+	return a.Sync == "Synced" && a.Health == "Healthy"
+}
+
+// ArgocdApplications reads the status of the argocd applications from the cluster
+func ArgocdApplications(kubeconfig, kubectlClient string) ([]ArgocdApplication, error) {
+	// the application list is too long for the command log of pkg.ExecShellReturnStrings
+	out, err := exec.Command(kubectlClient, "--kubeconfig", kubeconfig, "-n", "argocd", "get", "applications.argoproj.io", "-o", "json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("error listing the argocd applications: %w", err)
+	}
+	return parseArgocdApplications(out)
+}
+
+func parseArgocdApplications(out []byte) ([]ArgocdApplication, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Sync struct {
+					Status string `json:"status"`
+				} `json:"sync"`
+				Health struct {
+					Status string `json:"status"`
+				} `json:"health"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	err := json.Unmarshal(out, &list)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the argocd applications: %w", err)
+	}
+
+	applications := []ArgocdApplication{}
+	for _, item := range list.Items {
+		applications = append(applications, ArgocdApplication{
+			Name:   item.Metadata.Name,
+			Sync:   item.Status.Sync.Status,
+			Health: item.Status.Health.Status,
+		})
+	}
+	return applications, nil
+}
+
+// WaitForArgocdApplications waits for the named applications to be Synced and Healthy
+func WaitForArgocdApplications(kubeconfig, kubectlClient string, names []string, timeout time.Duration) error {
+	var pending []string
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(10 * time.Second) {
+		applications, err := ArgocdApplications(kubeconfig, kubectlClient)
+		if err != nil {
+			log.Info().Msgf("waiting for argocd: %s", err)
+			continue
+		}
+		pending = pendingApplications(applications, names)
+		if len(pending) == 0 {
+			return nil
+		}
+		log.Info().Msgf("waiting for argocd applications %s", strings.Join(pending, ", "))
+	}
+	return fmt.Errorf("argocd applications are not Synced and Healthy after %s: %s", timeout, strings.Join(pending, ", "))
+}
+
+// pendingApplications describes the named applications that are not ready, with their status
+func pendingApplications(applications []ArgocdApplication, names []string) []string {
+	status := map[string]ArgocdApplication{}
+	for _, application := range applications {
+		status[application.Name] = application
+	}
+
+	pending := []string{}
+	for _, name := range names {
+		application, ok := status[name]
+		switch {
+		case !ok:
+			pending = append(pending, fmt.Sprintf("%s (missing)", name))
+		case !application.Ready():
+			pending = append(pending, fmt.Sprintf("%s (%s/%s)", name, application.Sync, application.Health))
+		}
+	}
+	return pending
+}
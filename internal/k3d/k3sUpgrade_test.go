@@ -0,0 +1,118 @@
+package k3d
+
+import (
+	"archive/tar"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckK3sUpgrade(t *testing.T) {
+	tests := []struct {
+		current string
+		target  string
+		wantErr string
+	}{
+		{current: "v1.25.7-k3s1", target: "v1.26.3-k3s1"},
+		{current: "v1.25.7-k3s1", target: "v1.25.8-k3s1"},
+		{current: "v1.25.7-k3s1", target: "v1.25.7-k3s2"},
+		{current: "v1.25.7-k3s1", target: "v1.25.7-k3s1", wantErr: "already runs"},
+		{current: "v1.26.3-k3s1", target: "v1.25.7-k3s1", wantErr: "does not support downgrades"},
+		{current: "v1.25.7-k3s1", target: "v1.27.1-k3s1", wantErr: "to a v1.26 release first"},
+		{current: "v1.25.7-k3s1", target: "v1.26.3+k3s1", wantErr: "invalid k3s version"},
+		{current: "latest", target: "v1.26.3-k3s1", wantErr: "invalid k3s version"},
+	}
+	for _, tt := range tests {
+		err := CheckK3sUpgrade(tt.current, tt.target)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("CheckK3sUpgrade(%q, %q) = %v, want no error", tt.current, tt.target, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("CheckK3sUpgrade(%q, %q) = %v, want an error containing %q", tt.current, tt.target, err, tt.wantErr)
+		}
+	}
+}
+
+func TestClusterNodes(t *testing.T) {
+	client := fakeDocker(t, map[string]string{
+		"/containers/json": `[
+			{"Id":"a1","Names":["/k3d-kubefirst-agent-1"],"Labels":{"k3d.cluster":"kubefirst","k3d.role":"agent"}},
+			{"Id":"a2","Names":["/k3d-kubefirst-agent-0"],"Labels":{"k3d.cluster":"kubefirst","k3d.role":"agent"}},
+			{"Id":"b1","Names":["/k3d-kubefirst-serverlb"],"Labels":{"k3d.cluster":"kubefirst","k3d.role":"loadbalancer"}},
+			{"Id":"c1","Names":["/k3d-kubefirst-server-0"],"Labels":{"k3d.cluster":"kubefirst","k3d.role":"server"}},
+			{"Id":"d1","Names":["/k3d-kubefirst-mirror-docker-io"],"Labels":{"app":"k3d","k3d.role":"registry"}},
+			{"Id":"e1","Names":["/k3d-kubefirst-other-server-0"],"Labels":{"k3d.cluster":"kubefirst-other","k3d.role":"server"}}
+		]`,
+	})
+
+	nodes, err := ClusterNodes(client, "kubefirst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Node{
+		{Name: "k3d-kubefirst-server-0", Role: "server"},
+		{Name: "k3d-kubefirst-agent-0", Role: "agent"},
+		{Name: "k3d-kubefirst-agent-1", Role: "agent"},
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Errorf("ClusterNodes() = %v, want %v", nodes, want)
+	}
+}
+
+func TestPendingApplications(t *testing.T) {
+	applications, err := parseArgocdApplications([]byte(`{"items":[
+		{"metadata":{"name":"argocd"},"status":{"sync":{"status":"Synced"},"health":{"status":"Healthy"}}},
+		{"metadata":{"name":"vault"},"status":{"sync":{"status":"Synced"},"health":{"status":"Progressing"}}},
+		{"metadata":{"name":"minio"},"status":{"sync":{"status":"OutOfSync"},"health":{"status":"Healthy"}}}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applications) != 3 || !applications[0].Ready() || applications[1].Ready() {
+		t.Fatalf("parseArgocdApplications() = %v", applications)
+	}
+
+	pending := pendingApplications(applications, []string{"argocd", "vault", "minio", "atlantis"})
+	want := []string{"vault (Synced/Progressing)", "minio (OutOfSync/Healthy)", "atlantis (missing)"}
+	if !reflect.DeepEqual(pending, want) {
+		t.Errorf("pendingApplications() = %v, want %v", pending, want)
+	}
+	if pending := pendingApplications(applications, []string{"argocd"}); len(pending) != 0 {
+		t.Errorf("pendingApplications() = %v, want none", pending)
+	}
+}
+
+func TestStalePaths(t *testing.T) {
+	archive := func(names ...string) map[string]bool {
+		var buffer bytes.Buffer
+		writer := tar.NewWriter(&buffer)
+		for _, name := range names {
+			header := &tar.Header{Name: name, Mode: 0755, Typeflag: tar.TypeReg}
+			if strings.HasSuffix(name, "/") {
+				header.Typeflag = tar.TypeDir
+			}
+			writer.WriteHeader(header)
+		}
+		writer.Close()
+
+		paths, err := archivePaths(&buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return paths
+	}
+
+	nodePaths := archive("bin/", "bin/k3s", "bin/containerd-shim-runc-v1", "bin/aux/", "bin/aux/wg-add.sh", "bin/aux/iptables")
+	imagePaths := archive("bin/", "bin/k3s", "bin/containerd-shim-runc-v2")
+	want := []string{"/bin/aux", "/bin/containerd-shim-runc-v1"}
+	if stale := stalePaths(nodePaths, imagePaths); !reflect.DeepEqual(stale, want) {
+		t.Errorf("stalePaths() = %v, want %v", stale, want)
+	}
+	if stale := stalePaths(imagePaths, imagePaths); len(stale) != 0 {
+		t.Errorf("stalePaths() = %v, want none for the same binaries", stale)
+	}
+}
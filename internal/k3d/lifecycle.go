@@ -0,0 +1,187 @@
+package k3d
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/kubefirst/kubefirst/internal/k8s"
+	"github.com/kubefirst/kubefirst/pkg"
+	"github.com/rs/zerolog/log"
+)
+
+// atlantisVaultPath is the vault secret the atlantis-secrets ExternalSecret syncs from
+const atlantisVaultPath = "atlantis"
+
+// coreWorkload is a workload of the platform that has to be ready before the port-forwards are opened, a workload
+// that ignores ready only has to run
+type coreWorkload struct {
+	kind           string
+	matchLabel     string
+	matchLabelVal  string
+	namespace      string
+	timeoutSeconds int64
+	ignoreReady    bool
+}
+
+var coreWorkloads = []coreWorkload{
+	{kind: "statefulset", matchLabel: "app.kubernetes.io/part-of", matchLabelVal: "argocd", namespace: "argocd", timeoutSeconds: 300},
+	// a sealed vault is not ready until it is unsealed through its port-forward
+	{kind: "statefulset", matchLabel: "app.kubernetes.io/instance", matchLabelVal: "vault", namespace: "vault", timeoutSeconds: 300, ignoreReady: true},
+	{kind: "deployment", matchLabel: "app.kubernetes.io/instance", matchLabelVal: "kubefirst-console", namespace: "kubefirst", timeoutSeconds: 300},
+}
+
+// ClusterStop stops the nodes of a k3d cluster, the cluster keeps its state and is resumed with ClusterStart
+func ClusterStop(clusterName string, k3dClient string) error {
+	log.Info().Msgf("stopping k3d cluster %s", clusterName)
+	_, _, err := pkg.ExecShellReturnStrings(k3dClient, "cluster", "stop", clusterName)
+	if err != nil {
+		return fmt.Errorf("error stopping k3d cluster %s: %w", clusterName, err)
+	}
+	return nil
+}
+
+// ClusterStart starts the nodes of a stopped k3d cluster and refreshes its kubeconfig
+func ClusterStart(clusterName string, k3dClient string, kubeconfig string) error {
+	log.Info().Msgf("starting k3d cluster %s", clusterName)
+	_, _, err := pkg.ExecShellReturnStrings(k3dClient, "cluster", "start", clusterName, "--wait")
+	if err != nil {
+		return fmt.Errorf("error starting k3d cluster %s: %w", clusterName, err)
+	}
+
+	kConfigString, _, err := pkg.ExecShellReturnStrings(k3dClient, "kubeconfig", "get", clusterName)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(kubeconfig, []byte(kConfigString), 0644)
+	if err != nil {
+		log.Error().Err(err).Msg("error updating config")
+		return errors.New("error updating config")
+	}
+	return nil
+}
+
+// WaitForCoreWorkloads waits for argocd and the console to be ready and for vault to run after the cluster is
+// started, vault is ready once it is unsealed
+func WaitForCoreWorkloads(kubeconfig string) error {
+	for _, workload := range coreWorkloads {
+		log.Info().Msgf("waiting for %s %s in namespace %s", workload.kind, workload.matchLabelVal, workload.namespace)
+
+		var err error
+		switch workload.kind {
+		case "statefulset":
+			statefulSet, lookupErr := k8s.ReturnStatefulSetObject(kubeconfig, workload.matchLabel, workload.matchLabelVal, workload.namespace, 60)
+			if lookupErr != nil {
+				return fmt.Errorf("error finding %s statefulset: %w", workload.matchLabelVal, lookupErr)
+			}
+			_, err = k8s.WaitForStatefulSetReady(kubeconfig, statefulSet, workload.timeoutSeconds, workload.ignoreReady)
+		case "deployment":
+			deployment, lookupErr := k8s.ReturnDeploymentObject(kubeconfig, workload.matchLabel, workload.matchLabelVal, workload.namespace, 60)
+			if lookupErr != nil {
+				return fmt.Errorf("error finding %s deployment: %w", workload.matchLabelVal, lookupErr)
+			}
+			_, err = k8s.WaitForDeploymentReady(kubeconfig, deployment, workload.timeoutSeconds)
+		}
+		if err != nil {
+			return fmt.Errorf("error waiting for %s to be ready: %w", workload.matchLabelVal, err)
+		}
+	}
+	return nil
+}
+
+// WaitForVault waits for the vault statefulset, a sealed vault runs without being ready so ignoreReady waits for it
+// to run only
+func WaitForVault(kubeconfig string, ignoreReady bool) error {
+	statefulSet, err := k8s.ReturnStatefulSetObject(kubeconfig, "app.kubernetes.io/instance", "vault", "vault", 60)
+	if err != nil {
+		return fmt.Errorf("error finding vault statefulset: %w", err)
+	}
+	_, err = k8s.WaitForStatefulSetReady(kubeconfig, statefulSet, 300, ignoreReady)
+	if err != nil {
+		return fmt.Errorf("error waiting for vault to be ready: %w", err)
+	}
+	return nil
+}
+
+// AtlantisWebhookURL is the webhook url stored in the atlantis secrets, the url the repository webhook was last
+// pointed at
+func AtlantisWebhookURL(kubeconfig string) (string, error) {
+	secret, err := k8s.ReadSecretV2(kubeconfig, "atlantis", "atlantis-secrets")
+	if err != nil {
+		return "", fmt.Errorf("error reading secret atlantis/atlantis-secrets: %w", err)
+	}
+	return secret["TF_VAR_atlantis_repo_webhook_url"], nil
+}
+
+// UpdateAtlantisWebhookURL stores a new ngrok webhook url in the atlantis vault secret and restarts atlantis once
+// the atlantis-secrets ExternalSecret synced it, the atlantis terraform runs would otherwise point the webhook back at
+// the previous url. The kubernetes secret is not written since the ExternalSecret reverts it to the vault secret.
+func UpdateAtlantisWebhookURL(kubeconfig, kubectlClient, vaultAddress, atlantisWebhookURL string) error {
+	current, err := AtlantisWebhookURL(kubeconfig)
+	if err != nil {
+		return err
+	}
+	if current == atlantisWebhookURL {
+		return nil
+	}
+
+	vaultClient, err := newVaultClient(vaultAddress)
+	if err != nil {
+		return err
+	}
+	stored, err := storeAtlantisWebhookURL(vaultClient, atlantisWebhookURL)
+	if err != nil {
+		return err
+	}
+	if !stored {
+		return fmt.Errorf("vault has no %s/%s secret, atlantis keeps the webhook url %s", vaultKVMount, atlantisVaultPath, current)
+	}
+
+	// the annotation makes external-secrets sync the secret now instead of at its refresh interval
+	_, _, err = pkg.ExecShellReturnStrings(kubectlClient, "--kubeconfig", kubeconfig, "-n", "atlantis", "annotate", "externalsecret", "atlantis-secrets",
+		fmt.Sprintf("force-sync=%d", time.Now().Unix()), "--overwrite")
+	if err != nil {
+		return fmt.Errorf("error syncing the atlantis-secrets external secret: %w", err)
+	}
+	for deadline := time.Now().Add(2 * time.Minute); current != atlantisWebhookURL; time.Sleep(5 * time.Second) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the atlantis-secrets external secret did not sync the webhook url %s after 2m", atlantisWebhookURL)
+		}
+		current, err = AtlantisWebhookURL(kubeconfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, _, err = pkg.ExecShellReturnStrings(kubectlClient, "--kubeconfig", kubeconfig, "-n", "atlantis", "rollout", "restart", "statefulset", "atlantis")
+	if err != nil {
+		return fmt.Errorf("error restarting atlantis: %w", err)
+	}
+	return nil
+}
+
+// storeAtlantisWebhookURL writes the webhook url to the atlantis vault secret, false when vault has no such secret
+func storeAtlantisWebhookURL(vaultClient *vaultapi.Client, atlantisWebhookURL string) (bool, error) {
+	secret, err := vaultClient.KVv2(vaultKVMount).Get(context.TODO(), atlantisVaultPath)
+	if errors.Is(err, vaultapi.ErrSecretNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading vault secret %s/%s: %w", vaultKVMount, atlantisVaultPath, err)
+	}
+	// the latest version of a deleted secret has no data
+	if secret.Data == nil {
+		return false, nil
+	}
+
+	secret.Data["TF_VAR_atlantis_repo_webhook_url"] = atlantisWebhookURL
+	_, err = vaultClient.KVv2(vaultKVMount).Put(context.TODO(), atlantisVaultPath, secret.Data)
+	if err != nil {
+		return false, fmt.Errorf("error writing vault secret %s/%s: %w", vaultKVMount, atlantisVaultPath, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,28 @@
+package k3d
+
+import (
+	"testing"
+)
+
+func TestStoreAtlantisWebhookURL(t *testing.T) {
+	secrets := map[string]map[string]interface{}{
+		"atlantis": {"ATLANTIS_GH_TOKEN": "ghp_token", "TF_VAR_atlantis_repo_webhook_url": "https://old.ngrok.io/events"},
+	}
+	stored, err := storeAtlantisWebhookURL(fakeVault(t, secrets), "https://new.ngrok.io/events")
+	if err != nil || !stored {
+		t.Fatalf("storeAtlantisWebhookURL() = %t, %v, want the url stored", stored, err)
+	}
+	if secrets["atlantis"]["TF_VAR_atlantis_repo_webhook_url"] != "https://new.ngrok.io/events" || secrets["atlantis"]["ATLANTIS_GH_TOKEN"] != "ghp_token" {
+		t.Errorf("storeAtlantisWebhookURL() wrote %v, want the new url and the other keys kept", secrets["atlantis"])
+	}
+
+	// a vault without the atlantis secret is not written, a partial secret would drop the other keys
+	secrets = map[string]map[string]interface{}{}
+	stored, err = storeAtlantisWebhookURL(fakeVault(t, secrets), "https://new.ngrok.io/events")
+	if err != nil || stored {
+		t.Errorf("storeAtlantisWebhookURL() = %t, %v, want nothing stored", stored, err)
+	}
+	if len(secrets) != 0 {
+		t.Errorf("storeAtlantisWebhookURL() wrote %v to an empty vault", secrets)
+	}
+}
@@ -0,0 +1,209 @@
+package k3d
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kubefirst/kubefirst/pkg"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+// Mirror is a pull-through cache of a public registry, a docker registry proxies a single upstream so every
+// registry gets its own mirror
+type Mirror struct {
+	// Registry is the registry as it appears in image references
+	Registry  string
+	RemoteURL string
+}
+
+// Mirrors are the registries the platform images are pulled from
+var Mirrors = []Mirror{
+	{Registry: "docker.io", RemoteURL: "https://registry-1.docker.io"},
+	{Registry: "ghcr.io", RemoteURL: "https://ghcr.io"},
+	{Registry: "quay.io", RemoteURL: "https://quay.io"},
+}
+
+// Name is the k3d registry name of the mirror
+func (m Mirror) Name() string {
+	return "kubefirst-mirror-" + strings.ReplaceAll(m.Registry, ".", "-")
+}
+
+// Container is the docker container of the mirror, k3d prefixes the registry name with k3d-
+func (m Mirror) Container() string {
+	return "k3d-" + m.Name()
+}
+
+// Volume is the docker volume holding the cached images, it outlives the mirror container and the clusters
+func (m Mirror) Volume() string {
+	return m.Name()
+}
+
+// Endpoint is the address the cluster nodes pull from on the k3d network
+func (m Mirror) Endpoint() string {
+	return fmt.Sprintf("http://%s:5000", m.Container())
+}
+
+// HostPort is the random localhost port k3d published the mirror on
+func (m Mirror) HostPort() (int, error) {
+	out, err := exec.Command("docker", "port", m.Container(), "5000/tcp").Output()
+	if err != nil {
+		return 0, fmt.Errorf("error reading the port of registry mirror %s: %w", m.Container(), err)
+	}
+	// one line per published address, i.e. 0.0.0.0:49153
+	address := strings.TrimSpace(strings.Split(string(out), "\n")[0])
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected port %q of registry mirror %s", address, m.Container())
+	}
+	return strconv.Atoi(port)
+}
+
+// EnsureMirrors creates the registry mirrors, or starts the ones of a previous install
+func EnsureMirrors(k3dClient string) error {
+	for _, mirror := range Mirrors {
+		running, exists := containerState(mirror.Container())
+		switch {
+		case running:
+			log.Info().Msgf("reusing registry mirror %s of %s", mirror.Container(), mirror.Registry)
+		case exists:
+			log.Info().Msgf("starting registry mirror %s of %s", mirror.Container(), mirror.Registry)
+			_, _, err := pkg.ExecShellReturnStrings("docker", "start", mirror.Container())
+			if err != nil {
+				return fmt.Errorf("error starting registry mirror %s: %w", mirror.Container(), err)
+			}
+		default:
+			log.Info().Msgf("creating registry mirror %s of %s", mirror.Container(), mirror.Registry)
+			_, _, err := pkg.ExecShellReturnStrings(k3dClient, "registry", "create", mirror.Name(),
+				"--proxy-remote-url", mirror.RemoteURL,
+				"--volume", mirror.Volume()+":/var/lib/registry",
+			)
+			if err != nil {
+				return fmt.Errorf("error creating registry mirror %s: %w", mirror.Container(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// containerState reports whether a docker container exists and is running
+func containerState(container string) (bool, bool) {
+	out, err := exec.Command("docker", "inspect", "--format", "{{.State.Running}}", container).Output()
+	if err != nil {
+		return false, false
+	}
+	return strings.TrimSpace(string(out)) == "true", true
+}
+
+// registriesConfig is the k3s registries.yaml
+type registriesConfig struct {
+	Mirrors map[string]registryEndpoints `yaml:"mirrors"`
+}
+
+type registryEndpoints struct {
+	Endpoint []string `yaml:"endpoint"`
+}
+
+// WriteRegistriesConfig writes the registries.yaml pointing the cluster nodes at the mirrors, containerd falls
+// back to the registry itself when a mirror fails
+func WriteRegistriesConfig(k1Dir string) (string, error) {
+	config := registriesConfig{Mirrors: map[string]registryEndpoints{}}
+	for _, mirror := range Mirrors {
+		config.Mirrors[mirror.Registry] = registryEndpoints{Endpoint: []string{mirror.Endpoint()}}
+	}
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(k1Dir, "registries.yaml")
+	err = os.WriteFile(path, content, 0644)
+	if err != nil {
+		return "", fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// MirrorArgs are the `k3d cluster create` arguments connecting the mirrors to the cluster
+func MirrorArgs(registriesConfigPath string) []string {
+	args := []string{"--registry-config", registriesConfigPath}
+	for _, mirror := range Mirrors {
+		args = append(args, "--registry-use", mirror.Container())
+	}
+	return args
+}
+
+// PreloadImages pulls images through the mirrors so the cluster nodes find them in the cache, images of
+// registries without a mirror are skipped
+func PreloadImages(images []string) error {
+	hostPorts := map[string]int{}
+	for _, mirror := range Mirrors {
+		port, err := mirror.HostPort()
+		if err != nil {
+			return err
+		}
+		hostPorts[mirror.Registry] = port
+	}
+
+	skipped := []string{}
+	failed := []string{}
+	for _, image := range images {
+		registry, repository := imageReference(image)
+		port, ok := hostPorts[registry]
+		if !ok {
+			skipped = append(skipped, image)
+			continue
+		}
+
+		log.Info().Msgf("preloading image %s", image)
+		mirrorImage := fmt.Sprintf("localhost:%d/%s", port, repository)
+		_, _, err := pkg.ExecShellReturnStrings("docker", "pull", mirrorImage)
+		if err != nil {
+			failed = append(failed, image)
+			continue
+		}
+		// the mirror keeps the image, the copy in the local docker daemon is not needed
+		_, _, err = pkg.ExecShellReturnStrings("docker", "rmi", mirrorImage)
+		if err != nil {
+			log.Warn().Msgf("could not remove %s from the local docker daemon", mirrorImage)
+		}
+	}
+
+	if len(skipped) > 0 {
+		log.Info().Msgf("skipped %d images of registries without a mirror: %s", len(skipped), strings.Join(skipped, ", "))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d images could not be preloaded: %s", len(failed), len(images)-len(skipped), strings.Join(failed, ", "))
+	}
+	log.Info().Msgf("preloaded %d images into the registry mirrors", len(images)-len(skipped))
+	return nil
+}
+
+// imageReference splits an image into its registry and its repository with the tag or digest. Docker Hub images
+// without a registry get docker.io, and library/ when they have no namespace either.
+func imageReference(image string) (string, string) {
+	registry, repository := "docker.io", image
+	if i := strings.Index(image, "/"); i > 0 {
+		host := image[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			registry, repository = host, image[i+1:]
+		}
+	}
+	if registry == "index.docker.io" || registry == "registry-1.docker.io" {
+		registry = "docker.io"
+	}
+	if registry == "docker.io" && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	name := repository[strings.LastIndex(repository, "/")+1:]
+	if !strings.ContainsAny(name, ":@") {
+		repository += ":latest"
+	}
+	return registry, repository
+}
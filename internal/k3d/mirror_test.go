@@ -0,0 +1,153 @@
+package k3d
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImageReference(t *testing.T) {
+	tests := []struct {
+		image      string
+		registry   string
+		repository string
+	}{
+		{image: "nginx", registry: "docker.io", repository: "library/nginx:latest"},
+		{image: "bitnami/redis:7.0", registry: "docker.io", repository: "bitnami/redis:7.0"},
+		{image: "docker.io/library/registry:2", registry: "docker.io", repository: "library/registry:2"},
+		{image: "index.docker.io/hashicorp/vault:1.12.1", registry: "docker.io", repository: "hashicorp/vault:1.12.1"},
+		{image: "quay.io/argoproj/argocd:v2.6.4", registry: "quay.io", repository: "argoproj/argocd:v2.6.4"},
+		{image: "ghcr.io/dexidp/dex@sha256:abc", registry: "ghcr.io", repository: "dexidp/dex@sha256:abc"},
+		{image: "localhost:5000/team/app", registry: "localhost:5000", repository: "team/app:latest"},
+		{image: "registry.k8s.io/pause:3.9", registry: "registry.k8s.io", repository: "pause:3.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			registry, repository := imageReference(tt.image)
+			if registry != tt.registry || repository != tt.repository {
+				t.Errorf("imageReference() = %s, %s, want %s, %s", registry, repository, tt.registry, tt.repository)
+			}
+		})
+	}
+}
+
+func TestWriteRegistriesConfig(t *testing.T) {
+	k1Dir := t.TempDir()
+
+	path, err := WriteRegistriesConfig(k1Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `mirrors:
+  docker.io:
+    endpoint:
+    - http://k3d-kubefirst-mirror-docker-io:5000
+  ghcr.io:
+    endpoint:
+    - http://k3d-kubefirst-mirror-ghcr-io:5000
+  quay.io:
+    endpoint:
+    - http://k3d-kubefirst-mirror-quay-io:5000
+`
+	if string(content) != want {
+		t.Errorf("WriteRegistriesConfig() wrote\n%s\nwant\n%s", content, want)
+	}
+
+	args := strings.Join(MirrorArgs(path), " ")
+	wantArgs := "--registry-config " + filepath.Join(k1Dir, "registries.yaml") +
+		" --registry-use k3d-kubefirst-mirror-docker-io --registry-use k3d-kubefirst-mirror-ghcr-io --registry-use k3d-kubefirst-mirror-quay-io"
+	if args != wantArgs {
+		t.Errorf("MirrorArgs() = %s, want %s", args, wantArgs)
+	}
+}
+
+func TestGitopsImages(t *testing.T) {
+	registryDir := t.TempDir()
+	files := map[string]string{
+		"components/console/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - image: docker.io/kubefirst/console:2.0.0
+      - image: "{{ .Values.image }}"
+`,
+		"components/chartmuseum/job.yml": `containers:
+  - image: ghcr.io/helm/chartmuseum:v0.15.0
+  - image: docker.io/kubefirst/console:2.0.0
+`,
+		"README.md": "image: not/a-manifest:1.0\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(registryDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	images, err := GitopsImages(registryDir, "helm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ghcr.io/helm/chartmuseum:v0.15.0", "docker.io/kubefirst/console:2.0.0"}
+	if !reflect.DeepEqual(images, want) {
+		t.Errorf("GitopsImages() = %v, want %v", images, want)
+	}
+}
+
+func TestChartReleases(t *testing.T) {
+	content := `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: vault
+spec:
+  source:
+    repoURL: https://helm.releases.hashicorp.com
+    chart: vault
+    targetRevision: 0.22.0
+    helm:
+      values: |-
+        server:
+          dev:
+            enabled: true
+  destination:
+    namespace: vault
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: registry
+spec:
+  source:
+    repoURL: https://github.com/kubefirst/gitops.git
+    path: registry/kubefirst
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: vault
+`
+	releases := chartReleases([]byte(content))
+	if len(releases) != 1 {
+		t.Fatalf("chartReleases() = %v, want the vault chart", releases)
+	}
+	release := releases[0]
+	if release.repo.ChartName != "vault" || release.repo.ChartVersion != "0.22.0" || release.repo.Namespace != "vault" ||
+		release.repo.RepoURL != "https://helm.releases.hashicorp.com" {
+		t.Errorf("chartReleases() = %+v, want vault 0.22.0 in namespace vault", release.repo)
+	}
+	if release.values != "server:\n  dev:\n    enabled: true" {
+		t.Errorf("chartReleases() values = %q", release.values)
+	}
+}
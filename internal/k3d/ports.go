@@ -0,0 +1,295 @@
+package k3d
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+const (
+	DefaultArgocdPort  = 8080
+	DefaultVaultPort   = 8200
+	DefaultMinioPort   = 9000
+	DefaultConsolePort = 9094
+	// maxPortSearch is how far above a taken port RemapHostPorts looks for a free one
+	maxPortSearch = 100
+)
+
+// PortForwards are the host ports of the port-forwards kubefirst opens to argocd, vault, minio and the console
+type PortForwards struct {
+	Argocd  int
+	Vault   int
+	Minio   int
+	Console int
+}
+
+// DefaultPortForwards are the port-forward ports used when no flags are set
+func DefaultPortForwards() PortForwards {
+	return PortForwards{
+		Argocd:  DefaultArgocdPort,
+		Vault:   DefaultVaultPort,
+		Minio:   DefaultMinioPort,
+		Console: DefaultConsolePort,
+	}
+}
+
+// Save stores the port-forward ports in the kubefirst config
+func (p PortForwards) Save() {
+	viper.Set("k3d.port-forwards.argocd", p.Argocd)
+	viper.Set("k3d.port-forwards.vault", p.Vault)
+	viper.Set("k3d.port-forwards.minio", p.Minio)
+	viper.Set("k3d.port-forwards.console", p.Console)
+}
+
+// ReadPortForwards returns the port-forward ports stored in the kubefirst config, or the defaults
+func ReadPortForwards() PortForwards {
+	if !viper.IsSet("k3d.port-forwards.argocd") {
+		return DefaultPortForwards()
+	}
+	return PortForwards{
+		Argocd:  viper.GetInt("k3d.port-forwards.argocd"),
+		Vault:   viper.GetInt("k3d.port-forwards.vault"),
+		Minio:   viper.GetInt("k3d.port-forwards.minio"),
+		Console: viper.GetInt("k3d.port-forwards.console"),
+	}
+}
+
+// ArgocdPortForwardURL is the argocd port-forward of the cluster
+func ArgocdPortForwardURL() string {
+	return fmt.Sprintf("http://localhost:%d", ReadPortForwards().Argocd)
+}
+
+// ArgocdAPIURL is the argocd api behind the argocd port-forward
+func ArgocdAPIURL() string {
+	return fmt.Sprintf("https://localhost:%d/api/v1", ReadPortForwards().Argocd)
+}
+
+// VaultPortForwardURL is the vault port-forward of the cluster, terraform configures vault through it
+func VaultPortForwardURL() string {
+	return fmt.Sprintf("http://localhost:%d", ReadPortForwards().Vault)
+}
+
+// MinioPortForwardEndpoint is the minio port-forward of the cluster
+func MinioPortForwardEndpoint() string {
+	return fmt.Sprintf("localhost:%d", ReadPortForwards().Minio)
+}
+
+// ConsolePortForwardURL is the kubefirst console port-forward of the cluster
+func ConsolePortForwardURL() string {
+	return fmt.Sprintf("http://localhost:%d", ReadPortForwards().Console)
+}
+
+// IngressHostPort is the host port mapped to a loadbalancer port of the spec, 0 when it is not mapped
+func (s ClusterSpec) IngressHostPort(containerPort int) int {
+	for _, port := range s.Ports {
+		host, container, err := ParsePortMapping(port)
+		if err == nil && container == containerPort {
+			return host
+		}
+	}
+	return 0
+}
+
+// IngressURL adds the https host port of the cluster to an ingress url such as ArgocdURL when it is not 443
+func IngressURL(url string) string {
+	port := ReadClusterSpec().IngressHostPort(443)
+	if port == 0 || port == 443 {
+		return url
+	}
+	return fmt.Sprintf("%s:%d", url, port)
+}
+
+// IngressHTTPAddress is the local address of the http ingress, the ngrok tunnel forwards to it
+func IngressHTTPAddress() string {
+	port := ReadClusterSpec().IngressHostPort(80)
+	if port == 0 {
+		port = 80
+	}
+	return fmt.Sprintf(":%d", port)
+}
+
+// HostPort is a host port kubefirst binds and the flag selecting another one, Container is the loadbalancer port
+// a --port mapping forwards to
+type HostPort struct {
+	Name      string
+	Flag      string
+	Port      int
+	Container int
+	// docker binds the port rather than kubefirst, a port kubefirst may not bind can still be free for docker
+	docker bool
+	set    func(port int)
+}
+
+// PortConflict is a host port that is already in use, Process names the listener when it can be found
+type PortConflict struct {
+	HostPort
+	Process string
+}
+
+func (c PortConflict) String() string {
+	holder := "another process"
+	if c.Process != "" {
+		holder = c.Process
+	}
+	return fmt.Sprintf("port %d of the %s is in use by %s", c.Port, c.Name, holder)
+}
+
+// HostPorts lists the host ports of the cluster and of the port-forwards, remapping a port updates spec or forwards.
+// The cluster ports are left out when the cluster already exists since k3d holds them.
+func HostPorts(spec *ClusterSpec, forwards *PortForwards, includeCluster bool) []HostPort {
+	ports := []HostPort{}
+	if includeCluster {
+		for i := range spec.Ports {
+			i := i
+			host, container, err := ParsePortMapping(spec.Ports[i])
+			if err != nil {
+				continue
+			}
+			ports = append(ports, HostPort{
+				Name:      fmt.Sprintf("k3d loadbalancer (port %d)", container),
+				Flag:      "--port",
+				Port:      host,
+				Container: container,
+				docker:    true,
+				set:       func(port int) { spec.Ports[i] = fmt.Sprintf("%d:%d", port, container) },
+			})
+		}
+		ports = append(ports, HostPort{Name: "k3d registry", Flag: "--registry-port", Port: spec.RegistryPort, docker: true, set: func(port int) { spec.RegistryPort = port }})
+	}
+
+	return append(ports,
+		HostPort{Name: "argocd port-forward", Flag: "--argocd-port", Port: forwards.Argocd, set: func(port int) { forwards.Argocd = port }},
+		HostPort{Name: "vault port-forward", Flag: "--vault-port", Port: forwards.Vault, set: func(port int) { forwards.Vault = port }},
+		HostPort{Name: "minio port-forward", Flag: "--minio-port", Port: forwards.Minio, set: func(port int) { forwards.Minio = port }},
+		HostPort{Name: "console port-forward", Flag: "--console-port", Port: forwards.Console, set: func(port int) { forwards.Console = port }},
+	)
+}
+
+// CheckHostPorts returns the ports that are already in use or that are used twice
+func CheckHostPorts(ports []HostPort) ([]PortConflict, error) {
+	conflicts := []PortConflict{}
+	seen := map[int]string{}
+	for _, port := range ports {
+		if name, ok := seen[port.Port]; ok {
+			conflicts = append(conflicts, PortConflict{HostPort: port, Process: fmt.Sprintf("the %s", name)})
+			continue
+		}
+		seen[port.Port] = port.Name
+
+		inUse, err := portInUse(port.Port)
+		// privileged ports such as 80 and 443 can't be probed without root, the docker daemon binds them
+		if err != nil && port.docker && errors.Is(err, os.ErrPermission) {
+			log.Debug().Msgf("skipping the check of port %d of the %s: %s", port.Port, port.Name, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error checking port %d of the %s: %w", port.Port, port.Name, err)
+		}
+		if inUse {
+			conflicts = append(conflicts, PortConflict{HostPort: port, Process: portProcess(port.Port)})
+		}
+	}
+	return conflicts, nil
+}
+
+// RemapHostPorts moves every conflicting port to the next free port above it
+func RemapHostPorts(ports []HostPort, conflicts []PortConflict) error {
+	taken := map[int]bool{}
+	for _, port := range ports {
+		taken[port.Port] = true
+	}
+
+	for _, conflict := range conflicts {
+		free := FreePort(conflict.Port, taken)
+		if free == 0 {
+			return fmt.Errorf("no free port found for the %s above %d", conflict.Name, conflict.Port)
+		}
+		taken[free] = true
+		conflict.set(free)
+		log.Info().Msgf("%s, using port %d instead", conflict, free)
+	}
+	return nil
+}
+
+// FreePort returns the first port above port that is neither taken nor in use, 0 when there is none
+func FreePort(port int, taken map[int]bool) int {
+	for candidate := port + 1; candidate <= port+maxPortSearch && candidate <= 65535; candidate++ {
+		if taken[candidate] {
+			continue
+		}
+		if inUse, err := portInUse(candidate); err == nil && !inUse {
+			return candidate
+		}
+	}
+	return 0
+}
+
+// PortConflictsError describes the conflicts and the flags selecting free ports
+func PortConflictsError(ports []HostPort, conflicts []PortConflict) error {
+	taken := map[int]bool{}
+	for _, port := range ports {
+		taken[port.Port] = true
+	}
+
+	lines := []string{}
+	for _, conflict := range conflicts {
+		line := conflict.String()
+		if free := FreePort(conflict.Port, taken); free != 0 {
+			taken[free] = true
+			suggestion := strconv.Itoa(free)
+			if conflict.Container != 0 {
+				suggestion = fmt.Sprintf("%d:%d", free, conflict.Container)
+			}
+			line = fmt.Sprintf("%s, use %s %s", line, conflict.Flag, suggestion)
+		}
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+	return fmt.Errorf("host ports are in use, free them, choose other ports or rerun with --remap-ports:\n  %s", strings.Join(lines, "\n  "))
+}
+
+// portInUse probes a port the way docker and the port-forwards bind it, errors other than the port being in use
+// are returned
+func portInUse(port int) (bool, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	listener.Close()
+	return false, nil
+}
+
+// portProcess names the process listening on a port with lsof, empty when lsof is not available
+func portProcess(port int) string {
+	out, err := exec.Command("lsof", "-nP", fmt.Sprintf("-iTCP:%d", port), "-sTCP:LISTEN", "-Fpc").Output()
+	if err != nil {
+		return ""
+	}
+
+	// -F prints one field per line, p is the pid and c the command
+	var pid, command string
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, "p") && pid == "":
+			pid = line[1:]
+		case strings.HasPrefix(line, "c") && command == "":
+			command = line[1:]
+		}
+	}
+	if command == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s (pid %s)", command, pid)
+}
@@ -0,0 +1,130 @@
+package k3d
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// listen holds a free local port for the duration of the test
+func listen(t *testing.T) int {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestCheckHostPorts(t *testing.T) {
+	taken := listen(t)
+
+	spec := DefaultClusterSpec()
+	spec.Ports = []string{"0:80", "0:443"}
+	forwards := PortForwards{Argocd: taken, Vault: taken, Minio: FreePort(taken, nil), Console: FreePort(taken+20, nil)}
+
+	conflicts, err := CheckHostPorts(HostPorts(&spec, &forwards, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 2 {
+		t.Fatalf("CheckHostPorts() = %v, want the argocd port in use and the vault port used twice", conflicts)
+	}
+	if conflicts[0].Flag != "--argocd-port" || conflicts[1].Flag != "--vault-port" {
+		t.Errorf("CheckHostPorts() = %v, want conflicts of --argocd-port and --vault-port", conflicts)
+	}
+	if conflicts[1].Process != "the argocd port-forward" {
+		t.Errorf("CheckHostPorts() process = %s, want the argocd port-forward", conflicts[1].Process)
+	}
+}
+
+func TestPortInUse(t *testing.T) {
+	taken := listen(t)
+	inUse, err := portInUse(taken)
+	if err != nil || !inUse {
+		t.Errorf("portInUse(%d) = %t, %v, want in use", taken, inUse, err)
+	}
+
+	free := FreePort(taken, nil)
+	inUse, err = portInUse(free)
+	if err != nil || inUse {
+		t.Errorf("portInUse(%d) = %t, %v, want free", free, inUse, err)
+	}
+
+	_, err = portInUse(70000)
+	if err == nil {
+		t.Error("portInUse(70000) returned no error, want the invalid port error")
+	}
+}
+
+func TestRemapHostPorts(t *testing.T) {
+	taken := listen(t)
+
+	spec := DefaultClusterSpec()
+	spec.Ports = []string{"80:80", strconv.Itoa(taken) + ":443"}
+	spec.RegistryPort = FreePort(taken+40, nil)
+	forwards := PortForwards{Argocd: taken, Vault: FreePort(taken+60, nil), Minio: FreePort(taken+70, nil), Console: FreePort(taken+80, nil)}
+
+	ports := HostPorts(&spec, &forwards, true)
+	found, err := CheckHostPorts(ports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conflicts := []PortConflict{}
+	for _, conflict := range found {
+		// port 80 may be taken on the machine running the test
+		if conflict.Port == taken {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	if len(conflicts) != 2 {
+		t.Fatalf("CheckHostPorts() = %v, want the ingress and argocd ports in use", conflicts)
+	}
+
+	err = RemapHostPorts(ports, conflicts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	https := spec.IngressHostPort(443)
+	if https == taken || https == 0 || forwards.Argocd == taken || forwards.Argocd == https {
+		t.Errorf("RemapHostPorts() moved the ingress to %d and argocd to %d, want two distinct free ports", https, forwards.Argocd)
+	}
+}
+
+func TestPortConflictsError(t *testing.T) {
+	taken := listen(t)
+
+	spec := DefaultClusterSpec()
+	spec.Ports = []string{"80:80", strconv.Itoa(taken) + ":443"}
+	forwards := DefaultPortForwards()
+	ports := HostPorts(&spec, &forwards, true)
+
+	err := PortConflictsError(ports, []PortConflict{{HostPort: ports[1], Process: "nginx (pid 42)"}})
+	if !strings.Contains(err.Error(), "is in use by nginx (pid 42), use --port") || !strings.Contains(err.Error(), ":443") {
+		t.Errorf("PortConflictsError() = %s, want the process and a --port HOST:443 suggestion", err)
+	}
+}
+
+func TestIngressURL(t *testing.T) {
+	t.Cleanup(viper.Reset)
+
+	if got := IngressURL(ArgocdURL); got != ArgocdURL {
+		t.Errorf("IngressURL() = %s without a stored spec, want %s", got, ArgocdURL)
+	}
+	if got := IngressHTTPAddress(); got != ":80" {
+		t.Errorf("IngressHTTPAddress() = %s, want :80", got)
+	}
+
+	spec := DefaultClusterSpec()
+	spec.Ports = []string{"8081:80", "8443:443"}
+	spec.Save()
+	if got := IngressURL(ArgocdURL); got != ArgocdURL+":8443" {
+		t.Errorf("IngressURL() = %s, want %s:8443", got, ArgocdURL)
+	}
+	if got := IngressHTTPAddress(); got != ":8081" {
+		t.Errorf("IngressHTTPAddress() = %s, want :8081", got)
+	}
+}
@@ -0,0 +1,235 @@
+package k3d
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/kubefirst/kubefirst/internal/docker"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MinDockerCPUs are the cpus the platform needs to become ready
+	MinDockerCPUs = 2
+	// serverMemory is the memory a k3s server needs next to the agents
+	serverMemory = 1024 * 1024 * 1024
+	// the inotify limits the k3s nodes and the platform workloads need, the distribution defaults are lower
+	minInotifyInstances = 512
+	minInotifyWatches   = 524288
+)
+
+// PreflightCheck is a container runtime check of the k3d preflight, Err is nil when it passed
+type PreflightCheck struct {
+	Name        string
+	Err         error
+	Remediation string
+}
+
+// RuntimePreflight checks docker can run the cluster of spec. Containers, volumes and networks left by a
+// previous cluster named clusterName are only checked when checkLeftovers is set, a created cluster owns them.
+func RuntimePreflight(client *docker.Client, clusterName string, spec ClusterSpec, checkLeftovers bool) []PreflightCheck {
+	err := client.Ping()
+	if err != nil {
+		return []PreflightCheck{{
+			Name:        "docker daemon",
+			Err:         fmt.Errorf("docker is not reachable at %s: %w", client.Host, err),
+			Remediation: "start Docker Desktop or the docker service (i.e. `sudo systemctl start docker`), or set DOCKER_HOST to the socket of your docker daemon",
+		}}
+	}
+	checks := []PreflightCheck{{Name: "docker daemon"}}
+
+	info, err := client.Info()
+	if err != nil {
+		return append(checks, PreflightCheck{
+			Name:        "docker info",
+			Err:         fmt.Errorf("could not read the docker system info: %w", err),
+			Remediation: "check `docker info` succeeds for your user",
+		})
+	}
+	checks = append(checks, resourceChecks(info, spec)...)
+	checks = append(checks, cgroupCheck(info))
+
+	if checkLeftovers {
+		checks = append(checks, leftoversCheck(client, clusterName))
+	}
+	if runtime.GOOS == "linux" {
+		checks = append(checks, inotifyCheck("/proc/sys/fs/inotify"))
+	}
+	return checks
+}
+
+// PreflightError logs the passed checks and describes the failed ones with their remediation
+func PreflightError(checks []PreflightCheck) error {
+	failed := []string{}
+	for _, check := range checks {
+		if check.Err == nil {
+			log.Info().Msgf("preflight %s: ok", check.Name)
+			continue
+		}
+		failed = append(failed, fmt.Sprintf("%s: %s\n    %s", check.Name, check.Err, check.Remediation))
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the container runtime preflight failed:\n  %s", strings.Join(failed, "\n  "))
+}
+
+// RequiredMemory is the docker memory the spec needs, a cluster without agents runs the workloads on its
+// servers with the memory of the default agents
+func (s ClusterSpec) RequiredMemory() int64 {
+	workloadMemory := int64(DefaultAgents) * memoryBytes(DefaultAgentMemory)
+	if s.Agents > 0 {
+		workloadMemory = int64(s.Agents) * memoryBytes(s.AgentMemory)
+	}
+	return int64(s.Servers)*serverMemory + workloadMemory
+}
+
+func resourceChecks(info docker.Info, spec ClusterSpec) []PreflightCheck {
+	cpus := PreflightCheck{Name: "docker cpus"}
+	if info.NCPU < MinDockerCPUs {
+		cpus.Err = fmt.Errorf("docker has %d cpus, %d are required", info.NCPU, MinDockerCPUs)
+		cpus.Remediation = fmt.Sprintf("allocate at least %d cpus to docker (Docker Desktop: Settings > Resources)", MinDockerCPUs)
+	}
+
+	memory := PreflightCheck{Name: "docker memory"}
+	if required := spec.RequiredMemory(); info.MemTotal < required {
+		memory.Err = fmt.Errorf("docker has %s of memory, %d server(s) and %d agent(s) with %s need %s",
+			humanize.IBytes(uint64(info.MemTotal)), spec.Servers, spec.Agents, spec.AgentMemory, humanize.IBytes(uint64(required)))
+		memory.Remediation = "allocate more memory to docker (Docker Desktop: Settings > Resources), or lower --agents or --agent-memory"
+	}
+	return []PreflightCheck{cpus, memory}
+}
+
+func cgroupCheck(info docker.Info) PreflightCheck {
+	check := PreflightCheck{Name: "cgroups"}
+	switch {
+	case !info.MemoryLimit:
+		check.Err = fmt.Errorf("the cgroup v%s memory controller is not enabled, k3s can't run", cgroupVersion(info))
+		check.Remediation = "add `cgroup_memory=1 cgroup_enable=memory` to the kernel command line (i.e. /boot/cmdline.txt) and reboot, or boot with cgroup v2"
+	case info.Rootless() && cgroupVersion(info) == "1":
+		check.Err = errors.New("rootless docker runs on cgroup v1, rootless k3s requires cgroup v2")
+		check.Remediation = "boot with cgroup v2 (`systemd.unified_cgroup_hierarchy=1` on the kernel command line) or use rootful docker"
+	}
+	return check
+}
+
+// cgroupVersion defaults to v1, daemons older than the CgroupVersion info only support cgroup v1
+func cgroupVersion(info docker.Info) string {
+	if info.CgroupVersion == "" {
+		return "1"
+	}
+	return info.CgroupVersion
+}
+
+// leftoversCheck finds the containers, volumes and networks of a cluster that was deleted outside of kubefirst
+// or failed to create, k3d refuses to create a cluster over them
+func leftoversCheck(client *docker.Client, clusterName string) PreflightCheck {
+	check := PreflightCheck{Name: "k3d leftovers"}
+	prefix := fmt.Sprintf("k3d-%s", clusterName)
+
+	mirrors := map[string]bool{}
+	for _, mirror := range Mirrors {
+		mirrors[mirror.Container()] = true
+	}
+	containers, err := client.Containers(prefix + "-")
+	if err != nil {
+		check.Err = fmt.Errorf("could not list docker containers: %w", err)
+		check.Remediation = "check `docker ps` succeeds for your user"
+		return check
+	}
+	containerNames := []string{}
+	for _, container := range containers {
+		// the registry mirrors are kept between clusters
+		if !mirrors[container.Name] {
+			containerNames = append(containerNames, container.Name)
+		}
+	}
+
+	volumes, err := client.Volumes(prefix + "-")
+	if err != nil {
+		check.Err = fmt.Errorf("could not list docker volumes: %w", err)
+		check.Remediation = "check `docker volume ls` succeeds for your user"
+		return check
+	}
+	networks, err := client.Networks(prefix)
+	if err != nil {
+		check.Err = fmt.Errorf("could not list docker networks: %w", err)
+		check.Remediation = "check `docker network ls` succeeds for your user"
+		return check
+	}
+	clusterNetworks := []string{}
+	for _, network := range networks {
+		if network == prefix || strings.HasPrefix(network, prefix+"-") {
+			clusterNetworks = append(clusterNetworks, network)
+		}
+	}
+
+	if len(containerNames)+len(volumes)+len(clusterNetworks) == 0 {
+		return check
+	}
+
+	leftovers := []string{}
+	remediation := []string{fmt.Sprintf("run `k3d cluster delete %s`", clusterName)}
+	if len(containerNames) > 0 {
+		leftovers = append(leftovers, fmt.Sprintf("containers %s", strings.Join(containerNames, ", ")))
+		remediation = append(remediation, fmt.Sprintf("`docker rm -f %s`", strings.Join(containerNames, " ")))
+	}
+	if len(volumes) > 0 {
+		leftovers = append(leftovers, fmt.Sprintf("volumes %s", strings.Join(volumes, ", ")))
+		remediation = append(remediation, fmt.Sprintf("`docker volume rm %s`", strings.Join(volumes, " ")))
+	}
+	if len(clusterNetworks) > 0 {
+		leftovers = append(leftovers, fmt.Sprintf("networks %s", strings.Join(clusterNetworks, ", ")))
+		remediation = append(remediation, fmt.Sprintf("`docker network rm %s`", strings.Join(clusterNetworks, " ")))
+	}
+	check.Err = fmt.Errorf("a previous k3d cluster %s left %s", clusterName, strings.Join(leftovers, "; "))
+	check.Remediation = fmt.Sprintf("%s, or remove them with %s", remediation[0], strings.Join(remediation[1:], " and "))
+	return check
+}
+
+// inotifyCheck reads the inotify limits of the kernel, docker shares them with the k3s nodes
+func inotifyCheck(inotifyDir string) PreflightCheck {
+	check := PreflightCheck{Name: "inotify limits"}
+
+	low := []string{}
+	limits := []struct {
+		setting string
+		minimum int
+	}{
+		{setting: "max_user_instances", minimum: minInotifyInstances},
+		{setting: "max_user_watches", minimum: minInotifyWatches},
+	}
+	for _, limit := range limits {
+		content, err := os.ReadFile(filepath.Join(inotifyDir, limit.setting))
+		if err != nil {
+			// the limits are not readable, i.e. in a container, the check is skipped
+			return check
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		if err == nil && value < limit.minimum {
+			low = append(low, fmt.Sprintf("fs.inotify.%s is %d, %d is required", limit.setting, value, limit.minimum))
+		}
+	}
+	if len(low) == 0 {
+		return check
+	}
+
+	check.Err = errors.New(strings.Join(low, ", "))
+	check.Remediation = fmt.Sprintf("run `sudo sysctl fs.inotify.max_user_instances=%d fs.inotify.max_user_watches=%d` and add both to /etc/sysctl.conf to keep them", minInotifyInstances, minInotifyWatches)
+	return check
+}
+
+// memoryBytes is the size of a validated memory flag
+func memoryBytes(memory string) int64 {
+	size, err := parseMemory(memory)
+	if err != nil {
+		return 0
+	}
+	return size
+}
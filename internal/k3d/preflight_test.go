@@ -0,0 +1,151 @@
+package k3d
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubefirst/kubefirst/internal/docker"
+)
+
+// fakeDocker answers docker api paths with fixed json bodies on a unix socket
+func fakeDocker(t *testing.T, responses map[string]string) *docker.Client {
+	dir, err := os.MkdirTemp("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	})}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	client, err := docker.NewClientWithHost("unix://" + socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func failedChecks(checks []PreflightCheck) map[string]string {
+	failed := map[string]string{}
+	for _, check := range checks {
+		if check.Err != nil {
+			failed[check.Name] = check.Err.Error()
+		}
+	}
+	return failed
+}
+
+func TestRuntimePreflight(t *testing.T) {
+	client := fakeDocker(t, map[string]string{
+		"/_ping":           "OK",
+		"/info":            `{"NCPU":1,"MemTotal":2147483648,"MemoryLimit":false,"CgroupVersion":"1"}`,
+		"/containers/json": `[{"Id":"a1","Names":["/k3d-kubefirst-server-0"]},{"Id":"b2","Names":["/k3d-kubefirst-mirror-docker-io"]}]`,
+		"/volumes":         `{"Volumes":[]}`,
+		"/networks":        `[{"Name":"k3d-kubefirst"},{"Name":"k3d-kubefirst2"}]`,
+	})
+
+	spec := DefaultClusterSpec()
+	failed := failedChecks(RuntimePreflight(client, "kubefirst", spec, true))
+	for _, name := range []string{"docker cpus", "docker memory", "cgroups", "k3d leftovers"} {
+		if _, ok := failed[name]; !ok {
+			t.Errorf("RuntimePreflight() passed %s, want it to fail", name)
+		}
+	}
+	if want := "docker has 2.0 GiB of memory, 1 server(s) and 3 agent(s) with 1024m need 4.0 GiB"; failed["docker memory"] != want {
+		t.Errorf("docker memory = %s, want %s", failed["docker memory"], want)
+	}
+	if want := "a previous k3d cluster kubefirst left containers k3d-kubefirst-server-0; networks k3d-kubefirst"; failed["k3d leftovers"] != want {
+		t.Errorf("k3d leftovers = %s, want %s", failed["k3d leftovers"], want)
+	}
+
+	// a created cluster owns its containers
+	failed = failedChecks(RuntimePreflight(client, "kubefirst", spec, false))
+	if _, ok := failed["k3d leftovers"]; ok {
+		t.Error("RuntimePreflight() checked the leftovers of a created cluster")
+	}
+
+	err := PreflightError(RuntimePreflight(client, "kubefirst", spec, true))
+	if err == nil || !strings.Contains(err.Error(), "k3d cluster delete kubefirst") || !strings.Contains(err.Error(), "Settings > Resources") {
+		t.Errorf("PreflightError() = %v, want the remediations", err)
+	}
+}
+
+func TestRuntimePreflightReady(t *testing.T) {
+	client := fakeDocker(t, map[string]string{
+		"/_ping":           "OK",
+		"/info":            `{"NCPU":8,"MemTotal":17179869184,"MemoryLimit":true,"CgroupVersion":"2"}`,
+		"/containers/json": `[]`,
+		"/volumes":         `{"Volumes":null}`,
+		"/networks":        `[]`,
+	})
+
+	for name, err := range failedChecks(RuntimePreflight(client, "kubefirst", DefaultClusterSpec(), true)) {
+		// the inotify limits are the ones of the machine running the test
+		if name != "inotify limits" {
+			t.Errorf("RuntimePreflight() %s failed: %s", name, err)
+		}
+	}
+}
+
+func TestRuntimePreflightUnreachable(t *testing.T) {
+	client, err := docker.NewClientWithHost("unix:///nonexistent/docker.sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := RuntimePreflight(client, "kubefirst", DefaultClusterSpec(), true)
+	if len(checks) != 1 || checks[0].Err == nil || !strings.Contains(checks[0].Remediation, "DOCKER_HOST") {
+		t.Errorf("RuntimePreflight() = %+v, want only the failed docker daemon check", checks)
+	}
+}
+
+func TestInotifyCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		instances string
+		watches   string
+		wantErr   string
+	}{
+		{name: "sufficient", instances: "512", watches: "524288"},
+		{name: "distribution defaults", instances: "128", watches: "8192",
+			wantErr: "fs.inotify.max_user_instances is 128, 512 is required, fs.inotify.max_user_watches is 8192, 524288 is required"},
+		{name: "low watches", instances: "8192", watches: "65536", wantErr: "fs.inotify.max_user_watches is 65536, 524288 is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			os.WriteFile(filepath.Join(dir, "max_user_instances"), []byte(tt.instances+"\n"), 0644)
+			os.WriteFile(filepath.Join(dir, "max_user_watches"), []byte(tt.watches+"\n"), 0644)
+
+			check := inotifyCheck(dir)
+			var got string
+			if check.Err != nil {
+				got = check.Err.Error()
+			}
+			if got != tt.wantErr {
+				t.Errorf("inotifyCheck() = %q, want %q", got, tt.wantErr)
+			}
+		})
+	}
+
+	if check := inotifyCheck(filepath.Join(t.TempDir(), "missing")); check.Err != nil {
+		t.Errorf("inotifyCheck() = %v, want unreadable limits to be skipped", check.Err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/rs/zerolog/log"
 
+	gitlab "github.com/kubefirst/kubefirst/internal/gitlabcloud"
 	"github.com/kubefirst/kubefirst/internal/k8s"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -231,3 +232,51 @@ func AddK3DSecrets(
 
 	return nil
 }
+
+// CreateProjectDeployTokenSecrets creates a gitlab deploy token for a project and the registry pull secrets
+// that use it in the environment namespaces and argo
+func CreateProjectDeployTokenSecrets(gl *gitlab.GitLabWrapper, kubeconfigPath string, project string) error {
+	var p = gitlab.DeployTokenCreateParameters{
+		Name:     fmt.Sprintf("%s-deploy", project),
+		Username: fmt.Sprintf("%s-deploy", project),
+		Scopes:   []string{"read_registry", "write_registry"},
+	}
+
+	log.Info().Msgf("creating project deploy token for project %s...", project)
+	token, err := gl.CreateProjectDeployToken(project, &p)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("creating secret for project deploy token for project %s...", project)
+	usernamePasswordString := fmt.Sprintf("%s:%s", p.Username, token)
+	usernamePasswordStringB64 := base64.StdEncoding.EncodeToString([]byte(usernamePasswordString))
+	dockerConfigString := fmt.Sprintf(`{"auths": {"%s": {"username": "%s", "password": "%s", "email": "%s", "auth": "%s"}}}`, "registry.gitlab.com", p.Username, token, "k-bot@example.com", usernamePasswordStringB64)
+
+	createInNamespace := []string{"development", "staging", "production"}
+	for _, namespace := range createInNamespace {
+		deployTokenSecret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-deploy", project), Namespace: namespace},
+			Data:       map[string][]byte{".dockerconfigjson": []byte(dockerConfigString)},
+			Type:       "kubernetes.io/dockerconfigjson",
+		}
+		err = k8s.CreateSecretV2(kubeconfigPath, deployTokenSecret)
+		if err != nil {
+			log.Error().Msgf("error while creating secret for project deploy token: %s", err)
+		}
+	}
+
+	// Create argo workflows pull secret
+	// This is formatted to work with buildkit
+	argoDeployTokenSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-deploy", project), Namespace: "argo"},
+		Data:       map[string][]byte{"config.json": []byte(dockerConfigString)},
+		Type:       "Opaque",
+	}
+	err = k8s.CreateSecretV2(kubeconfigPath, argoDeployTokenSecret)
+	if err != nil {
+		log.Error().Msgf("error while creating secret for project deploy token: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+package k3d
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/kubefirst/kubefirst/internal/k8s"
+	"github.com/kubefirst/kubefirst/internal/vault"
+	cp "github.com/otiai10/copy"
+	"github.com/rs/zerolog/log"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// vaultDevToken is the root token of the dev mode vault of k3d clusters
+	vaultDevToken = "k1_local_vault_token"
+	// vaultKVMount is the kv v2 mount kubefirst and the gitops terraform keep the platform secrets in
+	vaultKVMount = "secret"
+)
+
+// snapshotSecrets are the kubernetes secrets of the platform credentials, argocd creates its initial admin secret
+// once and kubefirst the vault token, a secret that is gone is skipped
+var snapshotSecrets = []struct {
+	namespace string
+	name      string
+	optional  bool
+}{
+	{namespace: "argocd", name: "argocd-secret"},
+	{namespace: "argocd", name: "argocd-initial-admin-secret", optional: true},
+	{namespace: "vault", name: "vault-token", optional: true},
+}
+
+// SnapshotPlatform copies the vault kv secrets, the minio state store and the argocd credentials of the cluster to
+// k1Dir/snapshots and returns the snapshot directory. The dev mode vault keeps its data in memory, vaultAddress is a
+// port-forward to it.
+func SnapshotPlatform(kubeconfig, vaultAddress, k1Dir string) (string, error) {
+	clientset, err := k8s.GetClientSet(false, kubeconfig)
+	if err != nil {
+		return "", err
+	}
+	vaultClient, err := newVaultClient(vaultAddress)
+	if err != nil {
+		return "", err
+	}
+	return snapshotPlatform(clientset, vaultClient, k1Dir)
+}
+
+func snapshotPlatform(clientset kubernetes.Interface, vaultClient *vaultapi.Client, k1Dir string) (string, error) {
+	snapshotDir := filepath.Join(k1Dir, "snapshots", time.Now().UTC().Format("20060102-150405"))
+	err := os.MkdirAll(snapshotDir, 0700)
+	if err != nil {
+		return "", err
+	}
+	log.Info().Msgf("snapshotting the platform state to %s", snapshotDir)
+
+	err = exportVault(vaultClient, snapshotDir)
+	if err != nil {
+		return "", err
+	}
+
+	kubernetesSecrets := []v1.Secret{}
+	for _, snapshotSecret := range snapshotSecrets {
+		secret, err := clientset.CoreV1().Secrets(snapshotSecret.namespace).Get(context.TODO(), snapshotSecret.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) && snapshotSecret.optional {
+			log.Info().Msgf("secret %s/%s not found, skipping it", snapshotSecret.namespace, snapshotSecret.name)
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("error snapshotting secret %s/%s: %w", snapshotSecret.namespace, snapshotSecret.name, err)
+		}
+		// the server set metadata is left out so the secret can be created again
+		kubernetesSecrets = append(kubernetesSecrets, v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        secret.Name,
+				Namespace:   secret.Namespace,
+				Labels:      secret.Labels,
+				Annotations: secret.Annotations,
+			},
+			Type: secret.Type,
+			Data: secret.Data,
+		})
+	}
+	err = writeSnapshotFile(filepath.Join(snapshotDir, "secrets.json"), kubernetesSecrets)
+	if err != nil {
+		return "", err
+	}
+
+	// minio stores the terraform state on the minio-storage volume of the nodes
+	err = cp.Copy(filepath.Join(k1Dir, "minio-storage"), filepath.Join(snapshotDir, "minio-storage"))
+	if err != nil {
+		return "", fmt.Errorf("error snapshotting the minio state store: %w", err)
+	}
+	return snapshotDir, nil
+}
+
+// exportVault writes the vault kv secrets to the vault-kv.json file of dir
+func exportVault(vaultClient *vaultapi.Client, dir string) error {
+	secrets, err := vault.ExportKV(vaultClient, vaultKVMount)
+	if err != nil {
+		return fmt.Errorf("error snapshotting the vault secrets: %w", err)
+	}
+	err = writeSnapshotFile(filepath.Join(dir, "vault-kv.json"), secrets)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("snapshotted %d vault secrets", len(secrets))
+	return nil
+}
+
+// stoppedVaultDir is where `kubefirst k3d stop` exports the vault secrets for `kubefirst k3d start`
+func stoppedVaultDir(k1Dir string) string {
+	return filepath.Join(k1Dir, "snapshots", "stopped")
+}
+
+// ExportStoppedVault exports the vault kv secrets of a cluster that is about to stop, the dev mode vault keeps them
+// in memory and starts empty
+func ExportStoppedVault(vaultAddress, k1Dir string) error {
+	vaultClient, err := newVaultClient(vaultAddress)
+	if err != nil {
+		return err
+	}
+	return exportStoppedVault(vaultClient, k1Dir)
+}
+
+func exportStoppedVault(vaultClient *vaultapi.Client, k1Dir string) error {
+	dir := stoppedVaultDir(k1Dir)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+	return exportVault(vaultClient, dir)
+}
+
+// RestoreStoppedVault writes the secrets exported by ExportStoppedVault to the restarted vault and removes the export.
+// A vault that kept its secrets is left as it is.
+func RestoreStoppedVault(vaultAddress, k1Dir string) error {
+	vaultClient, err := newVaultClient(vaultAddress)
+	if err != nil {
+		return err
+	}
+	return restoreStoppedVault(vaultClient, k1Dir)
+}
+
+func restoreStoppedVault(vaultClient *vaultapi.Client, k1Dir string) error {
+	dir := stoppedVaultDir(k1Dir)
+	_, err := os.Stat(filepath.Join(dir, "vault-kv.json"))
+	if os.IsNotExist(err) {
+		secrets, err := vault.ExportKV(vaultClient, vaultKVMount)
+		if err != nil {
+			return fmt.Errorf("error reading the vault secrets: %w", err)
+		}
+		if len(secrets) == 0 {
+			return fmt.Errorf("vault has no secrets and no export of them was found at %s, the cluster was stopped without `kubefirst k3d stop`", dir)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = restoreVault(vaultClient, dir)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// RestoreVault writes the vault kv secrets of a snapshot back, a restarted dev mode vault starts empty
+func RestoreVault(vaultAddress, snapshotDir string) error {
+	vaultClient, err := newVaultClient(vaultAddress)
+	if err != nil {
+		return err
+	}
+	return restoreVault(vaultClient, snapshotDir)
+}
+
+func restoreVault(vaultClient *vaultapi.Client, snapshotDir string) error {
+	secrets := map[string]map[string]interface{}{}
+	err := readSnapshotFile(filepath.Join(snapshotDir, "vault-kv.json"), &secrets)
+	if err != nil {
+		return err
+	}
+	err = vault.ImportKV(vaultClient, vaultKVMount, secrets)
+	if err != nil {
+		return fmt.Errorf("error restoring the vault secrets: %w", err)
+	}
+	log.Info().Msgf("restored %d vault secrets", len(secrets))
+	return nil
+}
+
+// RestorePlatform restores a snapshot taken by SnapshotPlatform: the vault kv secrets, the argocd credentials and the
+// minio state store
+func RestorePlatform(kubeconfig, vaultAddress, k1Dir, snapshotDir string) error {
+	clientset, err := k8s.GetClientSet(false, kubeconfig)
+	if err != nil {
+		return err
+	}
+	vaultClient, err := newVaultClient(vaultAddress)
+	if err != nil {
+		return err
+	}
+	return restorePlatform(clientset, vaultClient, k1Dir, snapshotDir)
+}
+
+func restorePlatform(clientset kubernetes.Interface, vaultClient *vaultapi.Client, k1Dir, snapshotDir string) error {
+	log.Info().Msgf("restoring the platform state from %s", snapshotDir)
+
+	err := restoreVault(vaultClient, snapshotDir)
+	if err != nil {
+		return err
+	}
+
+	kubernetesSecrets := []v1.Secret{}
+	err = readSnapshotFile(filepath.Join(snapshotDir, "secrets.json"), &kubernetesSecrets)
+	if err != nil {
+		return err
+	}
+	for i := range kubernetesSecrets {
+		err = restoreSecret(clientset, &kubernetesSecrets[i])
+		if err != nil {
+			return fmt.Errorf("error restoring secret %s/%s: %w", kubernetesSecrets[i].Namespace, kubernetesSecrets[i].Name, err)
+		}
+	}
+
+	// the directory is mounted into the nodes, its content is replaced rather than the directory
+	storageDir := filepath.Join(k1Dir, "minio-storage")
+	entries, err := os.ReadDir(storageDir)
+	if err != nil {
+		return fmt.Errorf("error restoring the minio state store: %w", err)
+	}
+	for _, entry := range entries {
+		err = os.RemoveAll(filepath.Join(storageDir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("error restoring the minio state store: %w", err)
+		}
+	}
+	err = cp.Copy(filepath.Join(snapshotDir, "minio-storage"), storageDir)
+	if err != nil {
+		return fmt.Errorf("error restoring the minio state store: %w", err)
+	}
+	return nil
+}
+
+func restoreSecret(clientset kubernetes.Interface, secret *v1.Secret) error {
+	secrets := clientset.CoreV1().Secrets(secret.Namespace)
+	current, err := secrets.Get(context.TODO(), secret.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = secrets.Create(context.TODO(), secret, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	current.Data = secret.Data
+	_, err = secrets.Update(context.TODO(), current, metav1.UpdateOptions{})
+	return err
+}
+
+func newVaultClient(vaultAddress string) (*vaultapi.Client, error) {
+	config := vaultapi.DefaultConfig()
+	config.Address = vaultAddress
+	client, err := vaultapi.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize vault client: %w", err)
+	}
+	client.SetToken(vaultDevToken)
+	return client, nil
+}
+
+// writeSnapshotFile writes v as json, snapshots hold secrets so only the user can read them
+func writeSnapshotFile(path string, v interface{}) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
+
+func readSnapshotFile(path string, v interface{}) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(content, v)
+	if err != nil {
+		return fmt.Errorf("error reading snapshot file %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package k3d
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeVault serves the kv v2 api of the secret mount of a dev mode vault from secrets
+func fakeVault(t *testing.T, secrets map[string]map[string]interface{}) *vaultapi.Client {
+	var lock sync.Mutex
+	// vault answers a missing path with an empty error list
+	notFound := func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors":[]}`))
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if r.Header.Get("X-Vault-Token") != vaultDevToken {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/secret/metadata") && r.URL.Query().Get("list") == "true":
+			// the client drops the trailing slash of directories
+			dir := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/secret/metadata"), "/")
+			if dir != "" {
+				dir += "/"
+			}
+			keys := map[string]bool{}
+			for path := range secrets {
+				if !strings.HasPrefix(path, dir) {
+					continue
+				}
+				key := strings.TrimPrefix(path, dir)
+				if i := strings.Index(key, "/"); i != -1 {
+					key = key[:i+1]
+				}
+				keys[key] = true
+			}
+			if len(keys) == 0 {
+				notFound(w)
+				return
+			}
+			list := []string{}
+			for key := range keys {
+				list = append(list, key)
+			}
+			sort.Strings(list)
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"keys": list}})
+		case strings.HasPrefix(r.URL.Path, "/v1/secret/data/") && r.Method == http.MethodGet:
+			data, ok := secrets[strings.TrimPrefix(r.URL.Path, "/v1/secret/data/")]
+			if !ok {
+				notFound(w)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": data, "metadata": map[string]interface{}{"version": 1}}})
+		case strings.HasPrefix(r.URL.Path, "/v1/secret/data/"):
+			var body struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			secrets[strings.TrimPrefix(r.URL.Path, "/v1/secret/data/")] = body.Data
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"version": 1}})
+		default:
+			notFound(w)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := newVaultClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestSnapshotPlatform(t *testing.T) {
+	k1Dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(k1Dir, "minio-storage", "kubefirst-state-store"), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statePath := filepath.Join(k1Dir, "minio-storage", "kubefirst-state-store", "terraform.tfstate")
+	err = os.WriteFile(statePath, []byte(`{"version":4}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the layout of a k3d platform: a dev mode vault without an unseal secret and argocd without its initial admin
+	// secret once it was deleted
+	vaultSecrets := map[string]map[string]interface{}{
+		"ci-secrets":      {"BASIC_AUTH_USER": "k-ray"},
+		"atlantis":        {"ATLANTIS_GH_TOKEN": "ghp_token"},
+		"oidc/argocd":     {"client_id": "argocd", "client_secret": "secret"},
+		"development/app": {"KEY": "value"},
+	}
+	vaultClient := fakeVault(t, vaultSecrets)
+	clientset := fake.NewSimpleClientset(
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "argocd-secret", Namespace: "argocd", ResourceVersion: "42"},
+			Data:       map[string][]byte{"admin.password": []byte("hash")},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "vault-token", Namespace: "vault"},
+			Data:       map[string][]byte{"token": []byte(vaultDevToken)},
+		},
+	)
+
+	snapshotDir, err := snapshotPlatform(clientset, vaultClient, k1Dir)
+	if err != nil {
+		t.Fatalf("snapshotPlatform() = %v, want the missing initial admin secret skipped", err)
+	}
+
+	exported := map[string]map[string]interface{}{}
+	err = readSnapshotFile(filepath.Join(snapshotDir, "vault-kv.json"), &exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exported, vaultSecrets) {
+		t.Errorf("snapshotPlatform() exported %v, want %v", exported, vaultSecrets)
+	}
+	secrets := []v1.Secret{}
+	err = readSnapshotFile(filepath.Join(snapshotDir, "secrets.json"), &secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 2 || secrets[0].Name != "argocd-secret" || secrets[0].ResourceVersion != "" || secrets[1].Name != "vault-token" {
+		t.Errorf("snapshotPlatform() secrets = %v, want argocd-secret and vault-token without server metadata", secrets)
+	}
+
+	// a restarted dev mode vault is empty and the state store and credentials changed since the snapshot
+	for path := range vaultSecrets {
+		delete(vaultSecrets, path)
+	}
+	err = os.WriteFile(statePath, []byte(`{"version":4,"serial":2}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(k1Dir, "minio-storage", "new.tfstate"), []byte("{}"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = clientset.CoreV1().Secrets("argocd").Delete(context.TODO(), "argocd-secret", metav1.DeleteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = restorePlatform(clientset, vaultClient, k1Dir, snapshotDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vaultSecrets, exported) {
+		t.Errorf("restorePlatform() restored vault %v, want %v", vaultSecrets, exported)
+	}
+	state, err := os.ReadFile(statePath)
+	if err != nil || string(state) != `{"version":4}` {
+		t.Errorf("restorePlatform() restored state %s, %v, want the snapshotted state", state, err)
+	}
+	if _, err := os.Stat(filepath.Join(k1Dir, "minio-storage", "new.tfstate")); !os.IsNotExist(err) {
+		t.Errorf("restorePlatform() kept a state file written after the snapshot")
+	}
+	secret, err := clientset.CoreV1().Secrets("argocd").Get(context.TODO(), "argocd-secret", metav1.GetOptions{})
+	if err != nil || string(secret.Data["admin.password"]) != "hash" {
+		t.Errorf("restorePlatform() restored argocd-secret %v, %v", secret, err)
+	}
+}
+
+func TestSnapshotPlatformRequiredSecret(t *testing.T) {
+	k1Dir := t.TempDir()
+	_, err := snapshotPlatform(fake.NewSimpleClientset(), fakeVault(t, map[string]map[string]interface{}{}), k1Dir)
+	if err == nil || !strings.Contains(err.Error(), "argocd/argocd-secret") {
+		t.Errorf("snapshotPlatform() = %v, want an error for the missing argocd-secret", err)
+	}
+}
+
+func TestStoppedVault(t *testing.T) {
+	k1Dir := t.TempDir()
+	stopped := map[string]map[string]interface{}{
+		"atlantis": {"TF_VAR_atlantis_repo_webhook_url": "https://old.ngrok.io/events"},
+	}
+	err := exportStoppedVault(fakeVault(t, stopped), k1Dir)
+	if err != nil {
+		t.Fatalf("exportStoppedVault() error = %v", err)
+	}
+
+	started := map[string]map[string]interface{}{}
+	err = restoreStoppedVault(fakeVault(t, started), k1Dir)
+	if err != nil {
+		t.Fatalf("restoreStoppedVault() error = %v", err)
+	}
+	if !reflect.DeepEqual(started, stopped) {
+		t.Errorf("restoreStoppedVault() restored %v, want %v", started, stopped)
+	}
+	if _, err := os.Stat(stoppedVaultDir(k1Dir)); !os.IsNotExist(err) {
+		t.Error("restoreStoppedVault() kept the export")
+	}
+
+	// a vault that kept its secrets needs no export, an empty one does
+	err = restoreStoppedVault(fakeVault(t, started), k1Dir)
+	if err != nil {
+		t.Errorf("restoreStoppedVault() error = %v, want a vault with secrets accepted", err)
+	}
+	err = restoreStoppedVault(fakeVault(t, map[string]map[string]interface{}{}), k1Dir)
+	if err == nil {
+		t.Error("restoreStoppedVault() expected an error for an empty vault without an export")
+	}
+}
@@ -0,0 +1,241 @@
+package k3d
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	DefaultServers      = 1
+	DefaultAgents       = 3
+	DefaultAgentMemory  = "1024m"
+	DefaultRegistryPort = 63630
+	// MinAgentMemory is the smallest --agent-memory in bytes k3s agents run with
+	MinAgentMemory = 512 * 1024 * 1024
+)
+
+// DefaultPorts are the loadbalancer ports of the ingress, the platform urls are served on 443
+var DefaultPorts = []string{"80:80", "443:443"}
+
+var (
+	k3sVersionRegex  = regexp.MustCompile(`^v1\.\d+\.\d+-k3s\d+$`)
+	agentMemoryRegex = regexp.MustCompile(`^(\d+)([kKmMgG])$`)
+)
+
+// evictionArgs let k3s run on laptops with little free disk space
+var evictionArgs = []string{
+	`--kubelet-arg=eviction-hard=imagefs.available<1%,nodefs.available<1%`,
+	`--kubelet-arg=eviction-minimum-reclaim=imagefs.available=1%,nodefs.available=1%`,
+}
+
+// ClusterSpec is the topology of the k3d cluster, it is stored in the kubefirst config under k3d so later
+// commands act on the cluster that was created
+type ClusterSpec struct {
+	Servers      int
+	Agents       int
+	AgentMemory  string
+	K3sImage     string
+	K3sArgs      []string
+	Ports        []string
+	RegistryPort int
+	// RegistryMirror pulls docker.io, ghcr.io and quay.io images through the persistent Mirrors
+	RegistryMirror bool
+}
+
+// DefaultClusterSpec is the topology created when no flags are set
+func DefaultClusterSpec() ClusterSpec {
+	return ClusterSpec{
+		Servers:      DefaultServers,
+		Agents:       DefaultAgents,
+		AgentMemory:  DefaultAgentMemory,
+		Ports:        append([]string(nil), DefaultPorts...),
+		RegistryPort: DefaultRegistryPort,
+	}
+}
+
+// K3sImage returns the rancher/k3s image of a k3s version such as v1.25.7-k3s1
+func K3sImage(k3sVersion string) (string, error) {
+	if !k3sVersionRegex.MatchString(k3sVersion) {
+		return "", fmt.Errorf("invalid k3s version %s, expected a rancher/k3s tag such as v1.25.7-k3s1", k3sVersion)
+	}
+	return fmt.Sprintf("rancher/k3s:%s", k3sVersion), nil
+}
+
+// Validate returns an error describing the first invalid value of the spec
+func (s ClusterSpec) Validate() error {
+	if s.Servers < 1 {
+		return fmt.Errorf("invalid --servers %d, at least 1 server is required", s.Servers)
+	}
+	if s.Agents < 0 {
+		return fmt.Errorf("invalid --agents %d, the number of agents can't be negative", s.Agents)
+	}
+
+	if s.Agents > 0 {
+		memory, err := parseMemory(s.AgentMemory)
+		if err != nil {
+			return err
+		}
+		if memory < MinAgentMemory {
+			return fmt.Errorf("invalid --agent-memory %s, k3s agents need at least 512m", s.AgentMemory)
+		}
+	}
+
+	if strings.ContainsAny(s.K3sImage, " \t") {
+		return fmt.Errorf("invalid --k3s-image %q", s.K3sImage)
+	}
+
+	for _, arg := range s.K3sArgs {
+		if !strings.HasPrefix(arg, "--") {
+			return fmt.Errorf("invalid --k3s-arg %s, expected a k3s flag such as --disable=traefik@server:*", arg)
+		}
+	}
+
+	if s.RegistryPort < 1 || s.RegistryPort > 65535 {
+		return fmt.Errorf("invalid --registry-port %d", s.RegistryPort)
+	}
+
+	hostPorts := map[int]bool{s.RegistryPort: true}
+	containerPorts := map[int]bool{}
+	for _, port := range s.Ports {
+		hostPort, containerPort, err := ParsePortMapping(port)
+		if err != nil {
+			return err
+		}
+		if hostPorts[hostPort] {
+			return fmt.Errorf("host port %d is mapped more than once, the registry uses %d", hostPort, s.RegistryPort)
+		}
+		hostPorts[hostPort] = true
+		containerPorts[containerPort] = true
+	}
+	for _, required := range []int{80, 443} {
+		if !containerPorts[required] {
+			return fmt.Errorf("the ingress port %d must be mapped with --port, i.e. --port %d:%d", required, required, required)
+		}
+	}
+	return nil
+}
+
+// ParsePortMapping parses a --port mapping of a host port to a loadbalancer port such as 8443:443
+func ParsePortMapping(port string) (int, int, error) {
+	invalid := fmt.Errorf("invalid --port %s, expected HOST:CONTAINER such as 8443:443", port)
+
+	hostPort, containerPort, found := strings.Cut(port, ":")
+	if !found {
+		return 0, 0, invalid
+	}
+	host, err := strconv.Atoi(hostPort)
+	if err != nil || host < 1 || host > 65535 {
+		return 0, 0, invalid
+	}
+	container, err := strconv.Atoi(containerPort)
+	if err != nil || container < 1 || container > 65535 {
+		return 0, 0, invalid
+	}
+	return host, container, nil
+}
+
+// RegistryName is the name of the registry k3d creates next to the cluster
+func RegistryName(clusterName string) string {
+	return fmt.Sprintf("k3d-%s-registry", clusterName)
+}
+
+// CreateArgs are the `k3d cluster create` arguments of the spec
+func (s ClusterSpec) CreateArgs(clusterName, volumeDir string) []string {
+	args := []string{"cluster", "create", clusterName,
+		"--servers", strconv.Itoa(s.Servers),
+		"--agents", strconv.Itoa(s.Agents),
+		"--registry-create", fmt.Sprintf("%s:%d", RegistryName(clusterName), s.RegistryPort),
+		"--volume", volumeDir + ":/tmp/minio-storage",
+	}
+	if s.Agents > 0 {
+		args = append(args, "--agents-memory", s.AgentMemory)
+	}
+	if s.K3sImage != "" {
+		args = append(args, "--image", s.K3sImage)
+	}
+
+	// the workloads run on the agents, a cluster without agents schedules them on the servers
+	nodeFilter := "agent:*"
+	if s.Agents == 0 {
+		nodeFilter = "server:*"
+	}
+	for _, arg := range evictionArgs {
+		args = append(args, "--k3s-arg", fmt.Sprintf("%s@%s", arg, nodeFilter))
+	}
+	for _, arg := range s.K3sArgs {
+		args = append(args, "--k3s-arg", arg)
+	}
+
+	for _, port := range s.Ports {
+		args = append(args, "--port", port+"@loadbalancer")
+	}
+	return args
+}
+
+// Save stores the spec in the kubefirst config
+func (s ClusterSpec) Save() {
+	viper.Set("k3d.servers", s.Servers)
+	viper.Set("k3d.agents", s.Agents)
+	viper.Set("k3d.agent-memory", s.AgentMemory)
+	viper.Set("k3d.k3s-image", s.K3sImage)
+	viper.Set("k3d.k3s-args", s.K3sArgs)
+	viper.Set("k3d.ports", s.Ports)
+	viper.Set("k3d.registry-port", s.RegistryPort)
+	viper.Set("k3d.registry-mirror", s.RegistryMirror)
+}
+
+// ReadClusterSpec returns the spec stored in the kubefirst config, clusters created before the spec was stored
+// get the default topology they were created with
+func ReadClusterSpec() ClusterSpec {
+	if !viper.IsSet("k3d.servers") {
+		return DefaultClusterSpec()
+	}
+	return ClusterSpec{
+		Servers:        viper.GetInt("k3d.servers"),
+		Agents:         viper.GetInt("k3d.agents"),
+		AgentMemory:    viper.GetString("k3d.agent-memory"),
+		K3sImage:       viper.GetString("k3d.k3s-image"),
+		K3sArgs:        viper.GetStringSlice("k3d.k3s-args"),
+		Ports:          viper.GetStringSlice("k3d.ports"),
+		RegistryPort:   viper.GetInt("k3d.registry-port"),
+		RegistryMirror: viper.GetBool("k3d.registry-mirror"),
+	}
+}
+
+// String describes the topology for logs and reports
+func (s ClusterSpec) String() string {
+	image := s.K3sImage
+	if image == "" {
+		image = "the k3d default k3s image"
+	}
+	description := fmt.Sprintf("%d server(s), %d agent(s) with %s memory, %s, ports %s, registry port %d",
+		s.Servers, s.Agents, s.AgentMemory, image, strings.Join(s.Ports, ","), s.RegistryPort)
+	if s.RegistryMirror {
+		description += ", registry mirror"
+	}
+	return description
+}
+
+func parseMemory(memory string) (int64, error) {
+	match := agentMemoryRegex.FindStringSubmatch(memory)
+	if match == nil {
+		return 0, fmt.Errorf("invalid --agent-memory %s, expected a size such as 1024m or 2g", memory)
+	}
+	size, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToLower(match[2]) {
+	case "k":
+		size *= 1024
+	case "m":
+		size *= 1024 * 1024
+	case "g":
+		size *= 1024 * 1024 * 1024
+	}
+	return size, nil
+}
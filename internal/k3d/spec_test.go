@@ -0,0 +1,98 @@
+package k3d
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClusterSpecValidate(t *testing.T) {
+	withSpec := func(change func(s *ClusterSpec)) ClusterSpec {
+		spec := DefaultClusterSpec()
+		change(&spec)
+		return spec
+	}
+
+	tests := []struct {
+		name    string
+		spec    ClusterSpec
+		wantErr string
+	}{
+		{name: "default", spec: DefaultClusterSpec()},
+		{name: "no agents ignores agent memory", spec: withSpec(func(s *ClusterSpec) { s.Agents = 0; s.AgentMemory = "" })},
+		{name: "three servers", spec: withSpec(func(s *ClusterSpec) { s.Servers = 3; s.AgentMemory = "4g" })},
+		{name: "remapped ports", spec: withSpec(func(s *ClusterSpec) { s.Ports = []string{"8080:80", "8443:443", "5432:5432"} })},
+		{name: "no servers", spec: withSpec(func(s *ClusterSpec) { s.Servers = 0 }), wantErr: "--servers"},
+		{name: "negative agents", spec: withSpec(func(s *ClusterSpec) { s.Agents = -1 }), wantErr: "--agents"},
+		{name: "memory without unit", spec: withSpec(func(s *ClusterSpec) { s.AgentMemory = "1024" }), wantErr: "--agent-memory"},
+		{name: "too little memory", spec: withSpec(func(s *ClusterSpec) { s.AgentMemory = "256m" }), wantErr: "at least 512m"},
+		{name: "k3s arg without dashes", spec: withSpec(func(s *ClusterSpec) { s.K3sArgs = []string{"disable=traefik"} }), wantErr: "--k3s-arg"},
+		{name: "invalid port", spec: withSpec(func(s *ClusterSpec) { s.Ports = []string{"80:80", "443"} }), wantErr: "--port 443"},
+		{name: "duplicate host port", spec: withSpec(func(s *ClusterSpec) { s.Ports = []string{"80:80", "443:443", "443:8443"} }), wantErr: "host port 443"},
+		{name: "registry port taken", spec: withSpec(func(s *ClusterSpec) { s.RegistryPort = 443 }), wantErr: "host port 443"},
+		{name: "missing ingress port", spec: withSpec(func(s *ClusterSpec) { s.Ports = []string{"80:80"} }), wantErr: "ingress port 443"},
+		{name: "invalid registry port", spec: withSpec(func(s *ClusterSpec) { s.RegistryPort = 70000 }), wantErr: "--registry-port"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestK3sImage(t *testing.T) {
+	image, err := K3sImage("v1.25.7-k3s1")
+	if err != nil || image != "rancher/k3s:v1.25.7-k3s1" {
+		t.Errorf("K3sImage() = %s, %v", image, err)
+	}
+
+	for _, version := range []string{"1.25.7", "v1.25.7", "v1.25.7+k3s1", "latest"} {
+		_, err := K3sImage(version)
+		if err == nil {
+			t.Errorf("K3sImage(%s) expected an error", version)
+		}
+	}
+}
+
+func TestClusterSpecCreateArgs(t *testing.T) {
+	spec := DefaultClusterSpec()
+	got := strings.Join(spec.CreateArgs("kubefirst", "/k1/minio-storage"), " ")
+	want := "cluster create kubefirst --servers 1 --agents 3 --registry-create k3d-kubefirst-registry:63630 " +
+		"--volume /k1/minio-storage:/tmp/minio-storage --agents-memory 1024m " +
+		"--k3s-arg --kubelet-arg=eviction-hard=imagefs.available<1%,nodefs.available<1%@agent:* " +
+		"--k3s-arg --kubelet-arg=eviction-minimum-reclaim=imagefs.available=1%,nodefs.available=1%@agent:* " +
+		"--port 80:80@loadbalancer --port 443:443@loadbalancer"
+	if got != want {
+		t.Errorf("CreateArgs() =\n%s\nwant\n%s", got, want)
+	}
+
+	spec.Agents = 0
+	spec.K3sImage = "rancher/k3s:v1.25.7-k3s1"
+	spec.K3sArgs = []string{"--disable=metrics-server@server:*"}
+	got = strings.Join(spec.CreateArgs("kubefirst", "/k1/minio-storage"), " ")
+	for _, arg := range []string{"--image rancher/k3s:v1.25.7-k3s1", "nodefs.available<1%@server:*", "--k3s-arg --disable=metrics-server@server:*"} {
+		if !strings.Contains(got, arg) {
+			t.Errorf("CreateArgs() = %s, missing %s", got, arg)
+		}
+	}
+	if strings.Contains(got, "--agents-memory") {
+		t.Errorf("CreateArgs() = %s, no --agents-memory expected without agents", got)
+	}
+}
+
+func TestDefaultClusterSpecPorts(t *testing.T) {
+	spec := DefaultClusterSpec()
+	spec.Ports[1] = "8443:443"
+	if DefaultPorts[1] != "443:443" {
+		t.Errorf("DefaultClusterSpec() shares its ports with DefaultPorts, remapping changed them to %v", DefaultPorts)
+	}
+}
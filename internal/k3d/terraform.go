@@ -1,12 +1,31 @@
 package k3d
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
 )
 
+// gitProviderToken reads the token for the git provider from GITHUB_TOKEN or GITLAB_TOKEN
+func gitProviderToken(gitProvider string) string {
+	return os.Getenv(fmt.Sprintf("%s_TOKEN", strings.ToUpper(gitProvider)))
+}
+
+// atlantisWebhookURL is the ngrok tunnel git provider webhooks are delivered to
+func atlantisWebhookURL() string {
+	return fmt.Sprintf("%s/events", viper.GetString("ngrok.host"))
+}
+
 func GetGithubTerraformEnvs(envs map[string]string) map[string]string {
 
 	envs["GITHUB_TOKEN"] = os.Getenv("GITHUB_TOKEN")
+	envs["GITHUB_OWNER"] = viper.GetString("flags.github-owner")
+	envs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
+	envs["TF_VAR_atlantis_repo_webhook_url"] = atlantisWebhookURL()
+	envs["TF_VAR_kubefirst_bot_ssh_public_key"] = viper.GetString("kbot.public-key")
 	envs["AWS_ACCESS_KEY_ID"] = "kray"
 	envs["AWS_SECRET_ACCESS_KEY"] = "feedkraystars"
 	envs["TF_VAR_aws_access_key_id"] = "kray"
@@ -15,29 +34,51 @@ func GetGithubTerraformEnvs(envs map[string]string) map[string]string {
 	return envs
 }
 
+func GetGitlabTerraformEnvs(envs map[string]string, gid int) map[string]string {
+
+	envs["GITLAB_TOKEN"] = os.Getenv("GITLAB_TOKEN")
+	envs["GITLAB_OWNER"] = viper.GetString("flags.gitlab-owner")
+	envs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
+	envs["TF_VAR_atlantis_repo_webhook_url"] = atlantisWebhookURL()
+	envs["TF_VAR_owner_group_id"] = strconv.Itoa(gid)
+
+	return envs
+}
+
 func GetUsersTerraformEnvs(config *K3dConfig, envs map[string]string) map[string]string {
 
+	gitToken := gitProviderToken(config.GitProvider)
 	envs["TF_VAR_email_address"] = "your@email.com"
-	envs["TF_VAR_github_token"] = os.Getenv("GITHUB_TOKEN")
-	envs["TF_VAR_vault_addr"] = VaultPortForwardURL
+	envs[fmt.Sprintf("TF_VAR_%s_token", strings.ToUpper(config.GitProvider))] = gitToken
+	envs["TF_VAR_vault_addr"] = VaultPortForwardURL()
 	envs["TF_VAR_vault_token"] = "k1_local_vault_token"
-	envs["VAULT_ADDR"] = VaultPortForwardURL
+	envs["VAULT_ADDR"] = VaultPortForwardURL()
 	envs["VAULT_TOKEN"] = "k1_local_vault_token"
-	envs["GITHUB_TOKEN"] = os.Getenv("GITHUB_TOKEN")
+	envs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
+	envs["TF_VAR_atlantis_repo_webhook_url"] = atlantisWebhookURL()
+	envs[fmt.Sprintf("%s_TOKEN", strings.ToUpper(config.GitProvider))] = gitToken
+	envs[fmt.Sprintf("%s_OWNER", strings.ToUpper(config.GitProvider))] = viper.GetString(fmt.Sprintf("flags.%s-owner", config.GitProvider))
 
 	return envs
 }
 
+// GetVaultTerraformEnvs returns the vault entrypoint environment, gitlab installs also need TF_VAR_owner_group_id
 func GetVaultTerraformEnvs(config *K3dConfig, envs map[string]string) map[string]string {
 
 	envs["TF_VAR_email_address"] = "your@email.com"
 	envs["TF_VAR_github_token"] = os.Getenv("GITHUB_TOKEN")
-	envs["TF_VAR_vault_addr"] = VaultPortForwardURL
+	envs[fmt.Sprintf("TF_VAR_%s_token", config.GitProvider)] = gitProviderToken(config.GitProvider)
+	envs["TF_VAR_vault_addr"] = VaultPortForwardURL()
 	envs["TF_VAR_vault_token"] = "k1_local_vault_token"
-	envs["VAULT_ADDR"] = VaultPortForwardURL
+	envs["VAULT_ADDR"] = VaultPortForwardURL()
 	envs["VAULT_TOKEN"] = "k1_local_vault_token"
 	envs["TF_VAR_aws_access_key_id"] = "kray"
 	envs["TF_VAR_aws_secret_access_key"] = "feedkraystars"
+	envs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
+	envs["TF_VAR_atlantis_repo_webhook_url"] = atlantisWebhookURL()
+	envs["TF_VAR_kubefirst_bot_ssh_private_key"] = viper.GetString("kbot.private-key")
+	envs["TF_VAR_kubefirst_bot_ssh_public_key"] = viper.GetString("kbot.public-key")
+	envs["GITHUB_OWNER"] = viper.GetString("flags.github-owner")
 
 	return envs
 }
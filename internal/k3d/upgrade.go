@@ -0,0 +1,35 @@
+package k3d
+
+import (
+	"fmt"
+	"os"
+)
+
+// RenderGitopsTemplate turns a cloned gitops-template into the content create pushes to the gitops
+// repository, including the post run changes, so template releases can be compared against it
+func RenderGitopsTemplate(gitProvider, clusterName, clusterType, k1Dir, templateDir string, tokens *GitopsTokenValues) error {
+	err := k3dGithubAdjustGitopsTemplateContent(CloudProvider, clusterName, clusterType, gitProvider, k1Dir, templateDir)
+	if err != nil {
+		return err
+	}
+
+	err = detokenizeGitGitops(templateDir, tokens)
+	if err != nil {
+		return err
+	}
+
+	err = postRunDetokenizeGitGitops(templateDir, tokens)
+	if err != nil {
+		return err
+	}
+
+	// templates without a remote backend keep their state where it is
+	err = os.Rename(
+		fmt.Sprintf("%s/terraform/%s/remote-backend.md", templateDir, gitProvider),
+		fmt.Sprintf("%s/terraform/%s/remote-backend.tf", templateDir, gitProvider),
+	)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/itchyny/gojq"
 	"github.com/kubefirst/kubefirst/configs"
 	"github.com/kubefirst/kubefirst/internal/argocd"
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/pkg"
 	"github.com/spf13/viper"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -449,7 +450,7 @@ func LoopUntilPodIsReady(dryRun bool, kubeconfigPath, kubectlClientPath string)
 
 			req.Header.Add("Content-Type", "application/json")
 
-			res, err := http.DefaultClient.Do(req)
+			res, err := (&http.Client{Transport: httpCommon.NewTransport(true)}).Do(req)
 			if err != nil {
 				log.Warn().Err(err).Msg("error with http request Do, vault is not available")
 				// todo: temporary code
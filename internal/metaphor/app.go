@@ -0,0 +1,137 @@
+package metaphor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kubefirst/kubefirst/internal/gitopsTemplate"
+)
+
+// TemplateAppName is the application name used throughout the metaphor templates
+const TemplateAppName = "metaphor-frontend"
+
+// Environments are the namespaces every application is deployed to
+var Environments = []string{"development", "staging", "production"}
+
+// appNamePattern keeps app names valid as repository names, kubernetes resource names and dns labels
+var appNamePattern = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+
+// ValidateAppName checks an app name can be used for the repository, namespaced resources and ingress hosts
+func ValidateAppName(name string) error {
+	// leave room for the `-development` ingress host suffix within a 63 character dns label
+	if len(name) > 50 {
+		return fmt.Errorf("app name %q must be 50 characters or less", name)
+	}
+	if !appNamePattern.MatchString(name) {
+		return fmt.Errorf("app name %q must be lower case letters, digits and dashes and start with a letter", name)
+	}
+	if name == TemplateAppName || name == "gitops" {
+		return fmt.Errorf("app name %q is reserved by kubefirst", name)
+	}
+	return nil
+}
+
+// RenameTemplateApp replaces the template application name with appName in file content and file and
+// directory names under dir
+func RenameTemplateApp(dir, templateName, appName string) error {
+	err := gitopsTemplate.DetokenizeDirectory(dir, func(content string) string {
+		return strings.Replace(content, templateName, appName, -1)
+	})
+	if err != nil {
+		return err
+	}
+
+	renames := []string{}
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() && fi.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if path != dir && strings.Contains(fi.Name(), templateName) {
+			renames = append(renames, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// rename the deepest paths first so parent directories are still in place
+	sort.Slice(renames, func(i, j int) bool {
+		return strings.Count(renames[i], string(os.PathSeparator)) > strings.Count(renames[j], string(os.PathSeparator))
+	})
+	for _, path := range renames {
+		newName := strings.Replace(filepath.Base(path), templateName, appName, -1)
+		err = os.Rename(path, filepath.Join(filepath.Dir(path), newName))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// argoApplicationTemplate deploys the helm chart of an application repository to an environment namespace
+const argoApplicationTemplate = `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: %[1]s-%[2]s
+  namespace: argocd
+  finalizers:
+    - resources-finalizer.argocd.argoproj.io
+  annotations:
+    argocd.argoproj.io/sync-wave: "45"
+spec:
+  project: default
+  source:
+    repoURL: %[3]s
+    path: charts/%[1]s
+    targetRevision: HEAD
+    helm:
+      valueFiles:
+        - values.yaml
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: %[2]s
+  syncPolicy:
+    automated:
+      prune: true
+      selfHeal: true
+    syncOptions:
+      - CreateNamespace=true
+`
+
+// ArgoApplications returns the ArgoCD Application manifest for every environment keyed by its path in the gitops repository
+func ArgoApplications(appName, clusterName, repoGitURL string) map[string]string {
+	applications := map[string]string{}
+	for _, environment := range Environments {
+		path := fmt.Sprintf("registry/%s/%s/%s.yaml", clusterName, appName, environment)
+		applications[path] = fmt.Sprintf(argoApplicationTemplate, appName, environment, repoGitURL)
+	}
+	return applications
+}
+
+// WriteArgoApplications adds the ArgoCD Applications for appName to the registry of a gitops repository
+func WriteArgoApplications(gitopsDir, appName, clusterName, repoGitURL string) error {
+	for path, content := range ArgoApplications(appName, clusterName, repoGitURL) {
+		fullPath := filepath.Join(gitopsDir, path)
+		if _, err := os.Stat(fullPath); err == nil {
+			return fmt.Errorf("%s already exists in the gitops repository", path)
+		}
+		err := os.MkdirAll(filepath.Dir(fullPath), 0755)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(fullPath, []byte(content), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
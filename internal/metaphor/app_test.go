@@ -0,0 +1,93 @@
+package metaphor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateAppName(t *testing.T) {
+	tests := []struct {
+		name    string
+		appName string
+		wantErr bool
+	}{
+		{name: "valid", appName: "payments-api"},
+		{name: "upper case", appName: "Payments", wantErr: true},
+		{name: "leading digit", appName: "1payments", wantErr: true},
+		{name: "trailing dash", appName: "payments-", wantErr: true},
+		{name: "too long", appName: strings.Repeat("a", 51), wantErr: true},
+		{name: "reserved", appName: "gitops", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAppName(tt.appName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAppName(%q) error = %v, wantErr %v", tt.appName, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenameTemplateApp(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"charts/metaphor-frontend/Chart.yaml":                       "name: metaphor-frontend\n",
+		"charts/metaphor-frontend/templates/metaphor-frontend.yaml": "app: metaphor-frontend\n",
+		".git/config": "url = metaphor-frontend\n",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := RenameTemplateApp(dir, TemplateAppName, "payments")
+	if err != nil {
+		t.Fatalf("RenameTemplateApp() error = %v", err)
+	}
+
+	want := map[string]string{
+		"charts/payments/Chart.yaml":              "name: payments\n",
+		"charts/payments/templates/payments.yaml": "app: payments\n",
+		".git/config": "url = metaphor-frontend\n",
+	}
+	for path, content := range want {
+		got, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if string(got) != content {
+			t.Errorf("%s = %q, want %q", path, got, content)
+		}
+	}
+}
+
+func TestWriteArgoApplications(t *testing.T) {
+	dir := t.TempDir()
+
+	err := WriteArgoApplications(dir, "payments", "kubefirst", "git@github.com:acme/payments.git")
+	if err != nil {
+		t.Fatalf("WriteArgoApplications() error = %v", err)
+	}
+	for _, environment := range Environments {
+		content, err := os.ReadFile(filepath.Join(dir, "registry/kubefirst/payments", environment+".yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), "name: payments-"+environment) || !strings.Contains(string(content), "namespace: "+environment) {
+			t.Errorf("%s application = %s", environment, content)
+		}
+	}
+
+	err = WriteArgoApplications(dir, "payments", "kubefirst", "git@github.com:acme/payments.git")
+	if err == nil {
+		t.Error("WriteArgoApplications() expected an error for an existing app")
+	}
+}
@@ -26,6 +26,9 @@ type progressPrinter struct {
 var instance *progressPrinter
 var once sync.Once
 
+// trackersMu guards Trackers for trackers added from concurrent downloads
+var trackersMu sync.Mutex
+
 // GetInstance  Function used to initialize the component once in the execution.
 // Usually called from the `cmd`  `init` func or as early as possible on the execution.
 //
@@ -104,10 +107,62 @@ func LogMessage(message string) {
 //
 // no need to instanciate, it is a singleton, only one instance already started before use.
 func AddTracker(key string, title string, total int64) string {
+	trackersMu.Lock()
+	defer trackersMu.Unlock()
 	instance.Trackers[key] = &ActionTracker{Tracker: CreateTracker(title, total)}
 	return key
 }
 
+// AddBytesTracker Add a tracker counting bytes, i.e. for a download of total bytes.
+// It is a no-op until the progress printer is initialized so library code can report progress unconditionally.
+func AddBytesTracker(key string, title string, total int64) string {
+	if instance == nil {
+		return key
+	}
+	trackersMu.Lock()
+	defer trackersMu.Unlock()
+	tracker := &progress.Tracker{
+		Message: title,
+		Total:   total,
+		Units:   progress.UnitsBytes,
+	}
+	instance.pw.AppendTracker(tracker)
+	instance.Trackers[key] = &ActionTracker{Tracker: tracker}
+	return key
+}
+
+// SetTrackerValue Sets the value of a tracker added with AddBytesTracker, unknown keys are ignored
+func SetTrackerValue(key string, value int64) {
+	if tracker := bytesTracker(key); tracker != nil {
+		tracker.SetValue(value)
+	}
+}
+
+// MarkTrackerDone Marks a tracker added with AddBytesTracker as done, or errored when err is not nil
+func MarkTrackerDone(key string, err error) {
+	tracker := bytesTracker(key)
+	if tracker == nil {
+		return
+	}
+	if err != nil {
+		tracker.MarkAsErrored()
+		return
+	}
+	tracker.MarkAsDone()
+}
+
+func bytesTracker(key string) *progress.Tracker {
+	if instance == nil {
+		return nil
+	}
+	trackersMu.Lock()
+	defer trackersMu.Unlock()
+	if actionTracker, ok := instance.Trackers[key]; ok {
+		return actionTracker.Tracker
+	}
+	return nil
+}
+
 // TotalOfTrackers Returns the number of initialized Trackers
 func TotalOfTrackers() int {
 	return len(instance.Trackers)
@@ -0,0 +1,36 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubefirst/kubefirst/internal/terraform"
+)
+
+// BuildDriftReport describes the drift found in every checked terraform entrypoint
+func BuildDriftReport(results []terraform.DriftResult) bytes.Buffer {
+	var report bytes.Buffer
+	drifted, failed := 0, 0
+
+	report.WriteString(strings.Repeat("-", 70))
+	report.WriteString("\nterraform drift report\n")
+	for _, result := range results {
+		name := filepath.Base(result.Entrypoint)
+		switch {
+		case result.Err != nil:
+			failed++
+			report.WriteString(fmt.Sprintf("\n%s: drift check failed\n %s\n", name, result.Err))
+		case result.Drifted:
+			drifted++
+			report.WriteString(fmt.Sprintf("\n%s: drift detected\n", name))
+			report.WriteString(terraform.RenderPlanSummary(result.Entrypoint, result.Output))
+		default:
+			report.WriteString(fmt.Sprintf("\n%s: no drift\n", name))
+		}
+	}
+	report.WriteString(fmt.Sprintf("\n%d of %d entrypoints drifted, %d could not be checked\n", drifted, len(results), failed))
+
+	return report
+}
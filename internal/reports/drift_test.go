@@ -0,0 +1,39 @@
+package reports
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kubefirst/kubefirst/internal/terraform"
+)
+
+func TestBuildDriftReport(t *testing.T) {
+	results := []terraform.DriftResult{
+		{Entrypoint: "/k1/gitops/terraform/github", Output: &terraform.Output{}},
+		{
+			Entrypoint: "/k1/gitops/terraform/vault",
+			Drifted:    true,
+			Output: &terraform.Output{
+				Changes: []terraform.PlannedChange{{Address: "vault_policy.admin", Action: "update"}},
+				Summary: terraform.ChangeSummary{Change: 1},
+			},
+		},
+		{Entrypoint: "/k1/gitops/terraform/users", Err: errors.New("connection refused")},
+	}
+
+	got := BuildDriftReport(results)
+
+	for _, want := range []string{
+		"github: no drift",
+		"vault: drift detected",
+		"~   vault_policy.admin",
+		"users: drift check failed",
+		"connection refused",
+		"1 of 3 entrypoints drifted, 1 could not be checked",
+	} {
+		if !strings.Contains(got.String(), want) {
+			t.Errorf("built buffer doesn't contain %q", want)
+		}
+	}
+}
@@ -57,11 +57,11 @@ func LocalHandoffScreenV2(argocdAdminPassword, clusterName, gitOwner string, con
 
 	handOffData.WriteString("\n--- Kubefirst Console ")
 	handOffData.WriteString(strings.Repeat("-", 48))
-	handOffData.WriteString(fmt.Sprintf("\n URL: %s", k3d.KubefirstConsoleURL))
+	handOffData.WriteString(fmt.Sprintf("\n URL: %s", k3d.IngressURL(k3d.KubefirstConsoleURL)))
 
 	handOffData.WriteString("\n--- ArgoCD ")
 	handOffData.WriteString(strings.Repeat("-", 59))
-	handOffData.WriteString(fmt.Sprintf("\n URL: %s", k3d.ArgocdURL))
+	handOffData.WriteString(fmt.Sprintf("\n URL: %s", k3d.IngressURL(k3d.ArgocdURL)))
 	handOffData.WriteString(fmt.Sprintf("\n username: %s", "admin"))
 	handOffData.WriteString(fmt.Sprintf("\n password: %s", argocdAdminPassword))
 
@@ -86,7 +86,7 @@ func LocalHandoffScreenV2(argocdAdminPassword, clusterName, gitOwner string, con
 
 	handOffData.WriteString("\n--- Vault ")
 	handOffData.WriteString(strings.Repeat("-", 60))
-	handOffData.WriteString(fmt.Sprintf("\n URL: %s", k3d.VaultURL))
+	handOffData.WriteString(fmt.Sprintf("\n URL: %s", k3d.IngressURL(k3d.VaultURL)))
 	handOffData.WriteString(fmt.Sprintf("\n Root token: %s", "k1_local_vault_token"))
 	handOffData.WriteString("\n" + strings.Repeat("-", 70))
 
@@ -0,0 +1,44 @@
+package reports
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kubefirst/kubefirst/internal/terraform"
+)
+
+// BuildTerraformErrorReport describes a failed terraform execution for the install summary
+func BuildTerraformErrorReport(tfErr *terraform.ExecutionError) bytes.Buffer {
+	var report bytes.Buffer
+
+	report.WriteString(strings.Repeat("-", 70))
+	report.WriteString(fmt.Sprintf("\nterraform %s failed for %s", tfErr.Action, tfErr.Entrypoint))
+	for _, diagnostic := range tfErr.Diagnostics {
+		report.WriteString("\n")
+		if diagnostic.Address != "" {
+			report.WriteString(fmt.Sprintf("\n Resource: %s", diagnostic.Address))
+		}
+		report.WriteString(fmt.Sprintf("\n Error: %s", diagnostic.Summary))
+		if detail := strings.TrimSpace(diagnostic.Detail); detail != "" {
+			report.WriteString(fmt.Sprintf("\n %s", detail))
+		}
+	}
+	if len(tfErr.Diagnostics) == 0 {
+		report.WriteString(fmt.Sprintf("\n Error: %s", tfErr.Err))
+	}
+	report.WriteString(fmt.Sprintf("\n\nThe full terraform output is available at %s\n", tfErr.LogFile))
+
+	return report
+}
+
+// TerraformErrorSummary prints the install summary for err when it is a failed terraform execution
+func TerraformErrorSummary(err error) {
+	var tfErr *terraform.ExecutionError
+	if !errors.As(err, &tfErr) {
+		return
+	}
+	report := BuildTerraformErrorReport(tfErr)
+	fmt.Println(StyleMessage(report.String()))
+}
@@ -0,0 +1,29 @@
+package reports
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kubefirst/kubefirst/internal/terraform"
+)
+
+func TestBuildTerraformErrorReport(t *testing.T) {
+	tfErr := &terraform.ExecutionError{
+		Entrypoint: "/k1/gitops/terraform/github",
+		Action:     "apply",
+		LogFile:    "/k1/logs/terraform_github_apply_1.log",
+		Diagnostics: []terraform.Diagnostic{
+			{Severity: "error", Address: "github_repository.gitops", Summary: "422 name already exists", Detail: "choose another name"},
+		},
+		Err: errors.New("exit status 1"),
+	}
+
+	got := BuildTerraformErrorReport(tfErr)
+
+	for _, want := range []string{"github_repository.gitops", "422 name already exists", "choose another name", tfErr.LogFile} {
+		if !strings.Contains(got.String(), want) {
+			t.Errorf("built buffer doesn't contain %q", want)
+		}
+	}
+}
@@ -39,7 +39,7 @@ func (service CivoService) ListKubernetesClusters(civoApiKey, clusterName string
 	req.Header.Add("Content-Type", pkg.JSONContentType)
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", civoApiKey))
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := service.httpClient.Do(req)
 	if err != nil {
 		return 0, err
 	}
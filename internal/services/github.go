@@ -50,7 +50,7 @@ func (service GitHubService) CheckUserCodeConfirmation(deviceCode string) (strin
 	req.Header.Add("Content-Type", pkg.JSONContentType)
 	req.Header.Add("Accept", pkg.JSONContentType)
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := service.httpClient.Do(req)
 	if err != nil {
 		return "", nil
 	}
@@ -0,0 +1,233 @@
+package terraform
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+
+	"github.com/kubefirst/kubefirst/configs"
+)
+
+const (
+	// ApprovalAuto applies every entrypoint with -auto-approve
+	ApprovalAuto = "auto"
+	// ApprovalPrompt shows the plan of every entrypoint and applies it once confirmed
+	ApprovalPrompt = "prompt"
+	// ApprovalPlanOnly saves the plan of the next entrypoint to apply under ~/.k1 and stops
+	ApprovalPlanOnly = "plan-only"
+)
+
+// ApprovalModes are the supported --terraform-approval values
+var ApprovalModes = []string{ApprovalAuto, ApprovalPrompt, ApprovalPlanOnly}
+
+var (
+	// ErrPlanNotApproved is returned when a plan is declined at the prompt
+	ErrPlanNotApproved = errors.New("terraform plan was not approved")
+	// ErrPlanSaved is returned in plan-only mode once a plan is saved for review
+	ErrPlanSaved = errors.New("terraform plan saved for review")
+)
+
+// approvalInput is where prompt mode reads confirmations from
+var approvalInput = bufio.NewReader(os.Stdin)
+
+// ValidateApprovalMode checks a --terraform-approval value
+func ValidateApprovalMode(approval string) error {
+	for _, mode := range ApprovalModes {
+		if approval == mode {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid terraform approval mode %q, must be one of: %s", approval, strings.Join(ApprovalModes, ", "))
+}
+
+// PlanFile is where the plan for an entrypoint of a cluster is saved under ~/.k1
+func PlanFile(clusterName, cloudProvider, tfEntrypoint string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%s-%s.tfplan", cloudProvider, clusterName, filepath.Base(tfEntrypoint))
+	return filepath.Join(homeDir, ".k1", "terraform-plans", name), nil
+}
+
+// planSummaryFile is where the summary of a saved plan is written next to it
+func planSummaryFile(planFile string) string {
+	return strings.TrimSuffix(planFile, ".tfplan") + ".txt"
+}
+
+// InitApplyWithApproval applies an entrypoint according to the approval mode. A plan saved by an earlier
+// plan-only run is applied instead of planning again after confirmation in prompt mode, auto mode discards it.
+// A saved plan terraform rejects as stale is discarded and planned again.
+func InitApplyWithApproval(dryRun bool, tfEntrypoint string, tfEnvs map[string]string, approval string) error {
+	err := ValidateApprovalMode(approval)
+	if err != nil {
+		return err
+	}
+
+	planFile, err := PlanFile(viper.GetString("flags.cluster-name"), viper.GetString("kubefirst.cloud-provider"), tfEntrypoint)
+	if err != nil {
+		return err
+	}
+	summaryFile := planSummaryFile(planFile)
+	_, err = os.Stat(planFile)
+	savedPlan := err == nil
+
+	if approval == ApprovalAuto {
+		if savedPlan && !dryRun {
+			log.Info().Msgf("discarding saved terraform plan %s, auto approval plans again", planFile)
+			os.Remove(planFile)
+			os.Remove(summaryFile)
+		}
+		return InitApplyAutoApprove(dryRun, tfEntrypoint, tfEnvs)
+	}
+
+	config := configs.ReadConfig()
+	log.Printf("initApplyWithApproval - approval: %s entrypoint: %s", approval, tfEntrypoint)
+
+	if dryRun {
+		log.Printf("[#99] Dry-run mode, approval: %s entrypoint: %s", approval, tfEntrypoint)
+		return nil
+	}
+
+	err = os.Chdir(tfEntrypoint)
+	if err != nil {
+		log.Info().Msg("error: could not change to directory " + tfEntrypoint)
+		return err
+	}
+
+	logFile, err := entrypointLogFile(tfEntrypoint, "apply")
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	log.Info().Msgf("terraform apply output for %s is logged to %s", tfEntrypoint, logFile.Name())
+
+	_, err = runTerraform(tfEnvs, config.TerraformClientPath, tfEntrypoint, logFile, "init", "-no-color")
+	if err != nil {
+		return err
+	}
+
+	for {
+		var summary string
+		if savedPlan {
+			content, err := os.ReadFile(summaryFile)
+			if err != nil {
+				return err
+			}
+			summary = string(content)
+			log.Info().Msgf("applying saved terraform plan %s", planFile)
+		} else {
+			err = os.MkdirAll(filepath.Dir(planFile), 0700)
+			if err != nil {
+				return err
+			}
+			output, err := runTerraform(tfEnvs, config.TerraformClientPath, tfEntrypoint, logFile, "plan", "-input=false", "-json", fmt.Sprintf("-out=%s", planFile))
+			if err != nil {
+				return err
+			}
+			summary = RenderPlanSummary(tfEntrypoint, output)
+			err = os.WriteFile(summaryFile, []byte(summary), 0600)
+			if err != nil {
+				return err
+			}
+		}
+
+		switch approval {
+		case ApprovalPlanOnly:
+			fmt.Print(summary)
+			return fmt.Errorf("%w: review %s, then run create with --terraform-approval prompt to apply %s", ErrPlanSaved, summaryFile, planFile)
+		case ApprovalPrompt:
+			approved, err := confirmApply(summary)
+			if err != nil {
+				return err
+			}
+			if !approved {
+				return fmt.Errorf("%w for %s, the plan is saved at %s", ErrPlanNotApproved, tfEntrypoint, planFile)
+			}
+		}
+
+		_, err = runTerraform(tfEnvs, config.TerraformClientPath, tfEntrypoint, logFile, "apply", "-input=false", "-json", planFile)
+		// a saved plan goes stale once the state changes, it is planned again instead of failing every later run
+		if savedPlan && IsStalePlan(err) {
+			log.Warn().Msgf("saved terraform plan %s is stale, planning again", planFile)
+			os.Remove(planFile)
+			os.Remove(summaryFile)
+			savedPlan = false
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		break
+	}
+
+	os.Remove(planFile)
+	os.Remove(summaryFile)
+	os.RemoveAll(fmt.Sprintf("%s/.terraform/", tfEntrypoint))
+	os.Remove(fmt.Sprintf("%s/.terraform.lock.hcl", tfEntrypoint))
+	return nil
+}
+
+// stalePlanDiagnostics are lower case fragments of the diagnostics terraform rejects an outdated saved plan with
+var stalePlanDiagnostics = []string{
+	"saved plan is stale",
+	"saved plan does not match the given state",
+}
+
+// IsStalePlan reports whether err is a failed terraform apply of a saved plan that no longer matches the state
+func IsStalePlan(err error) bool {
+	var tfErr *ExecutionError
+	if !errors.As(err, &tfErr) {
+		return false
+	}
+	for _, diagnostic := range tfErr.Diagnostics {
+		message := strings.ToLower(diagnostic.Summary)
+		for _, fragment := range stalePlanDiagnostics {
+			if strings.Contains(message, fragment) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RenderPlanSummary describes the resource adds, changes and destroys of a plan
+func RenderPlanSummary(tfEntrypoint string, output *Output) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "terraform plan for %s: %d to add, %d to change, %d to destroy\n", tfEntrypoint, output.Summary.Add, output.Summary.Change, output.Summary.Remove)
+
+	symbols := map[string]string{
+		"create":  "+",
+		"update":  "~",
+		"delete":  "-",
+		"replace": "-/+",
+		"read":    "<=",
+	}
+	for _, change := range output.Changes {
+		symbol, ok := symbols[change.Action]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&b, "  %-3s %s\n", symbol, change.Address)
+	}
+	return b.String()
+}
+
+// confirmApply shows a plan summary and waits for the plan to be approved
+func confirmApply(summary string) (bool, error) {
+	fmt.Print(summary)
+	fmt.Print("apply this plan? only 'yes' will be accepted: ")
+
+	answer, err := approvalInput.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return strings.TrimSpace(answer) == "yes", nil
+}
@@ -0,0 +1,95 @@
+package terraform
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+const planOutput = `{"@level":"info","@message":"github_repository.gitops: Plan to create","type":"planned_change","change":{"resource":{"addr":"github_repository.gitops"},"action":"create"}}
+{"@level":"info","@message":"github_team.admins: Plan to update","type":"planned_change","change":{"resource":{"addr":"github_team.admins"},"action":"update"}}
+{"@level":"info","@message":"github_repository.metaphor: Plan to replace","type":"planned_change","change":{"resource":{"addr":"github_repository.metaphor"},"action":"replace"}}
+{"@level":"info","@message":"Plan: 2 to add, 1 to change, 1 to destroy.","type":"change_summary","changes":{"add":2,"change":1,"remove":1,"operation":"plan"}}
+`
+
+func TestRenderPlanSummary(t *testing.T) {
+	output, err := ParseJSONOutput(strings.NewReader(planOutput), io.Discard)
+	if err != nil {
+		t.Fatalf("ParseJSONOutput() error = %v", err)
+	}
+
+	want := "terraform plan for terraform/github: 2 to add, 1 to change, 1 to destroy\n" +
+		"  +   github_repository.gitops\n" +
+		"  ~   github_team.admins\n" +
+		"  -/+ github_repository.metaphor\n"
+	if got := RenderPlanSummary("terraform/github", output); got != want {
+		t.Errorf("RenderPlanSummary() = %q, want %q", got, want)
+	}
+}
+
+func TestValidateApprovalMode(t *testing.T) {
+	for _, mode := range ApprovalModes {
+		if err := ValidateApprovalMode(mode); err != nil {
+			t.Errorf("ValidateApprovalMode(%q) error = %v", mode, err)
+		}
+	}
+	if err := ValidateApprovalMode("manual"); err == nil {
+		t.Error("ValidateApprovalMode(\"manual\") expected an error")
+	}
+}
+
+func TestConfirmApply(t *testing.T) {
+	defaultInput := approvalInput
+	defer func() { approvalInput = defaultInput }()
+
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{input: "yes\n", want: true},
+		{input: "y\n", want: false},
+		{input: "", want: false},
+	}
+	for _, tt := range tests {
+		approvalInput = bufio.NewReader(strings.NewReader(tt.input))
+		got, err := confirmApply("plan\n")
+		if err != nil {
+			t.Fatalf("confirmApply() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("confirmApply() with input %q = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestPlanFile(t *testing.T) {
+	github, err := PlanFile("kubefirst", "k3d", "/home/k1/gitops/terraform/github")
+	if err != nil {
+		t.Fatalf("PlanFile() error = %v", err)
+	}
+	if !strings.HasSuffix(github, "k3d-kubefirst-github.tfplan") {
+		t.Errorf("PlanFile() = %q, want it keyed by provider, cluster and entrypoint", github)
+	}
+
+	other, err := PlanFile("other", "k3d", "/home/k1/gitops/terraform/github")
+	if err != nil {
+		t.Fatalf("PlanFile() error = %v", err)
+	}
+	if other == github {
+		t.Errorf("PlanFile() = %q for two clusters", other)
+	}
+}
+
+func TestIsStalePlan(t *testing.T) {
+	stale := &ExecutionError{Action: "apply", Diagnostics: []Diagnostic{{Severity: "error", Summary: "Saved plan is stale"}}}
+	if !IsStalePlan(fmt.Errorf("error creating github resources: %w", stale)) {
+		t.Error("IsStalePlan() = false for a stale plan diagnostic")
+	}
+	failed := &ExecutionError{Action: "apply", Diagnostics: []Diagnostic{{Severity: "error", Summary: "Error creating repository"}}}
+	if IsStalePlan(failed) || IsStalePlan(errors.New("saved plan is stale")) {
+		t.Error("IsStalePlan() = true for an error without a stale plan diagnostic")
+	}
+}
@@ -0,0 +1,66 @@
+package terraform
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/kubefirst/kubefirst/configs"
+)
+
+// planDriftExitCode is the terraform plan -detailed-exitcode status for a plan with changes
+const planDriftExitCode = 2
+
+// DriftResult is the outcome of checking a single entrypoint for drift
+type DriftResult struct {
+	Entrypoint string
+	Drifted    bool
+	Output     *Output
+	Err        error
+}
+
+// PlanDrift runs terraform plan -detailed-exitcode for an installed entrypoint, a plan with changes means the
+// infrastructure no longer matches the terraform code. The state is read without taking the lock.
+func PlanDrift(tfEntrypoint string, tfEnvs map[string]string) DriftResult {
+	result := DriftResult{Entrypoint: tfEntrypoint}
+	config := configs.ReadConfig()
+
+	err := os.Chdir(tfEntrypoint)
+	if err != nil {
+		result.Err = fmt.Errorf("could not change to directory %s: %w", tfEntrypoint, err)
+		return result
+	}
+	defer func() {
+		os.RemoveAll(fmt.Sprintf("%s/.terraform/", tfEntrypoint))
+		os.Remove(fmt.Sprintf("%s/.terraform.lock.hcl", tfEntrypoint))
+	}()
+
+	logFile, err := entrypointLogFile(tfEntrypoint, "drift")
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer logFile.Close()
+	log.Info().Msgf("terraform drift output for %s is logged to %s", tfEntrypoint, logFile.Name())
+
+	_, err = runTerraform(tfEnvs, config.TerraformClientPath, tfEntrypoint, logFile, "init", "-no-color", "-input=false")
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Output, err = runTerraform(tfEnvs, config.TerraformClientPath, tfEntrypoint, logFile, "plan", "-input=false", "-lock=false", "-detailed-exitcode", "-json")
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == planDriftExitCode {
+			result.Drifted = true
+			return result
+		}
+		result.Err = err
+	}
+
+	return result
+}
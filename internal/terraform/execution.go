@@ -0,0 +1,202 @@
+package terraform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+
+	"github.com/kubefirst/kubefirst/pkg"
+)
+
+// Diagnostic is an error or warning reported by terraform
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+	Address  string `json:"address"`
+	Range    *struct {
+		Filename string `json:"filename"`
+		Start    struct {
+			Line int `json:"line"`
+		} `json:"start"`
+	} `json:"range"`
+}
+
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.Address != "" {
+		fmt.Fprintf(&b, "%s: ", d.Address)
+	}
+	b.WriteString(d.Summary)
+	if d.Range != nil && d.Range.Filename != "" {
+		fmt.Fprintf(&b, " (%s line %d)", d.Range.Filename, d.Range.Start.Line)
+	}
+	if detail := strings.TrimSpace(d.Detail); detail != "" {
+		fmt.Fprintf(&b, ": %s", detail)
+	}
+	return b.String()
+}
+
+// PlannedChange is a resource change reported by terraform plan
+type PlannedChange struct {
+	Address string
+	Action  string
+}
+
+// ChangeSummary counts the resource changes of a plan or apply
+type ChangeSummary struct {
+	Add    int `json:"add"`
+	Change int `json:"change"`
+	Remove int `json:"remove"`
+}
+
+// Output is what kubefirst keeps from terraform -json output
+type Output struct {
+	Diagnostics []Diagnostic
+	Changes     []PlannedChange
+	Summary     ChangeSummary
+}
+
+// uiMessage is a single line of terraform -json machine readable output
+type uiMessage struct {
+	Level      string      `json:"@level"`
+	Message    string      `json:"@message"`
+	Type       string      `json:"type"`
+	Diagnostic *Diagnostic `json:"diagnostic"`
+	Hook       struct {
+		Resource struct {
+			Addr string `json:"addr"`
+		} `json:"resource"`
+	} `json:"hook"`
+	Change struct {
+		Resource struct {
+			Addr string `json:"addr"`
+		} `json:"resource"`
+		Action string `json:"action"`
+	} `json:"change"`
+	Changes ChangeSummary `json:"changes"`
+}
+
+// ExecutionError is returned when a terraform command fails for an entrypoint
+type ExecutionError struct {
+	Entrypoint  string
+	Action      string
+	LogFile     string
+	Diagnostics []Diagnostic
+	Err         error
+}
+
+func (e *ExecutionError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "terraform %s failed for %s", e.Action, e.Entrypoint)
+	if len(e.Diagnostics) == 0 {
+		fmt.Fprintf(&b, ": %s", e.Err)
+	}
+	for _, d := range e.Diagnostics {
+		fmt.Fprintf(&b, "\n  %s", d)
+	}
+	if e.LogFile != "" {
+		fmt.Fprintf(&b, "\n  see %s for the full terraform output", e.LogFile)
+	}
+	return b.String()
+}
+
+func (e *ExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// ParseJSONOutput reads terraform -json output, copying every line to logWriter, and returns the error diagnostics
+// and planned changes. Error diagnostics without an address are attributed to the last resource that failed to apply.
+func ParseJSONOutput(r io.Reader, logWriter io.Writer) (*Output, error) {
+	output := &Output{Diagnostics: []Diagnostic{}, Changes: []PlannedChange{}}
+	failedResource := ""
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		fmt.Fprintf(logWriter, "%s\n", line)
+
+		message := uiMessage{}
+		if err := json.Unmarshal(line, &message); err != nil {
+			continue
+		}
+		log.Info().Msgf("terraform: %s", message.Message)
+
+		switch message.Type {
+		case "planned_change":
+			output.Changes = append(output.Changes, PlannedChange{message.Change.Resource.Addr, message.Change.Action})
+		case "change_summary":
+			output.Summary = message.Changes
+		case "apply_errored":
+			failedResource = message.Hook.Resource.Addr
+		case "diagnostic":
+			if message.Diagnostic == nil || message.Diagnostic.Severity != "error" {
+				continue
+			}
+			diagnostic := *message.Diagnostic
+			if diagnostic.Address == "" {
+				diagnostic.Address = failedResource
+			}
+			output.Diagnostics = append(output.Diagnostics, diagnostic)
+		}
+	}
+
+	return output, scanner.Err()
+}
+
+// entrypointLogFile creates the log file for a terraform entrypoint next to the kubefirst logs
+func entrypointLogFile(tfEntrypoint, tfAction string) (*os.File, error) {
+	logsDir := viper.GetString("k1-paths.logs-dir")
+	if logsDir == "" {
+		logsDir = os.TempDir()
+	}
+	name := fmt.Sprintf("terraform_%s_%s_%d.log", filepath.Base(tfEntrypoint), tfAction, time.Now().Unix())
+	return os.OpenFile(filepath.Join(logsDir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+}
+
+// execTerraform runs terraform in the current directory writing its output to logFile, -json output is parsed for diagnostics
+// and changes and human readable errors on stderr are reported as a single diagnostic
+func execTerraform(tfEnvs map[string]string, terraformClientPath string, logFile io.Writer, args ...string) (*Output, error) {
+	logFile = pkg.NewRedactWriter(logFile)
+	stdoutReader, stdoutWriter := io.Pipe()
+	var stderr bytes.Buffer
+
+	type parseResult struct {
+		output *Output
+		err    error
+	}
+	parsed := make(chan parseResult)
+	go func() {
+		output, err := ParseJSONOutput(stdoutReader, logFile)
+		// drain the pipe so terraform never blocks on a parse failure
+		io.Copy(io.Discard, stdoutReader)
+		parsed <- parseResult{output, err}
+	}()
+
+	err := pkg.ExecShellWithVarsStreams(tfEnvs, stdoutWriter, io.MultiWriter(logFile, &stderr), terraformClientPath, args...)
+	stdoutWriter.Close()
+	result := <-parsed
+	if result.err != nil {
+		log.Warn().Msgf("error reading terraform output: %s", result.err)
+	}
+
+	if err != nil && len(result.output.Diagnostics) == 0 && strings.TrimSpace(stderr.String()) != "" {
+		result.output.Diagnostics = append(result.output.Diagnostics, Diagnostic{
+			Severity: "error",
+			Summary:  fmt.Sprintf("terraform %s failed", args[0]),
+			Detail:   strings.TrimSpace(stderr.String()),
+		})
+	}
+
+	return result.output, err
+}
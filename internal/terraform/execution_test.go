@@ -0,0 +1,69 @@
+package terraform
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const applyOutput = `{"@level":"info","@message":"Terraform 1.3.8","type":"version"}
+{"@level":"info","@message":"github_repository.gitops: Creating...","type":"apply_start","hook":{"resource":{"addr":"github_repository.gitops"}}}
+{"@level":"error","@message":"github_repository.gitops: Creation errored after 1s","type":"apply_errored","hook":{"resource":{"addr":"github_repository.gitops"}}}
+{"@level":"warn","@message":"Warning: Argument is deprecated","type":"diagnostic","diagnostic":{"severity":"warning","summary":"Argument is deprecated","detail":""}}
+{"@level":"error","@message":"Error: POST https://api.github.com/orgs/acme/repos: 422","type":"diagnostic","diagnostic":{"severity":"error","summary":"POST https://api.github.com/orgs/acme/repos: 422","detail":"name already exists on this account","range":{"filename":"modules/repository/main.tf","start":{"line":1}}}}
+not json
+`
+
+func TestParseJSONOutput(t *testing.T) {
+	var logOutput bytes.Buffer
+	output, err := ParseJSONOutput(strings.NewReader(applyOutput), &logOutput)
+	if err != nil {
+		t.Fatalf("ParseJSONOutput() error = %v", err)
+	}
+
+	if logOutput.String() != applyOutput {
+		t.Errorf("ParseJSONOutput() did not copy the output to the log writer, got %q", logOutput.String())
+	}
+	if len(output.Diagnostics) != 1 {
+		t.Fatalf("ParseJSONOutput() diagnostics = %+v, want 1 error", output.Diagnostics)
+	}
+
+	got := output.Diagnostics[0].String()
+	want := "github_repository.gitops: POST https://api.github.com/orgs/acme/repos: 422 (modules/repository/main.tf line 1): name already exists on this account"
+	if got != want {
+		t.Errorf("Diagnostic.String() = %q, want %q", got, want)
+	}
+}
+
+func TestExecutionErrorError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *ExecutionError
+		want string
+	}{
+		{
+			name: "with diagnostics",
+			err: &ExecutionError{
+				Entrypoint:  "/k1/gitops/terraform/vault",
+				Action:      "apply",
+				LogFile:     "/k1/logs/terraform_vault_apply_1.log",
+				Diagnostics: []Diagnostic{{Severity: "error", Summary: "permission denied", Address: "vault_mount.secret"}},
+				Err:         errors.New("exit status 1"),
+			},
+			want: "terraform apply failed for /k1/gitops/terraform/vault\n  vault_mount.secret: permission denied\n  see /k1/logs/terraform_vault_apply_1.log for the full terraform output",
+		},
+		{
+			name: "without diagnostics",
+			err:  &ExecutionError{Entrypoint: "/k1/gitops/terraform/users", Action: "init", Err: errors.New("exit status 1")},
+			want: "terraform init failed for /k1/gitops/terraform/users: exit status 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package terraform
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/kubefirst/kubefirst/pkg"
+)
+
+var (
+	// retryAttempts is the number of times a terraform command runs before a transient failure is returned
+	retryAttempts = 4
+	// retrySleep is the wait before the first retry, it doubles with jitter on every attempt
+	retrySleep = 15 * time.Second
+)
+
+// transientDiagnostics are lower case fragments of terraform diagnostics that a re-run is known to fix
+var transientDiagnostics = []string{
+	// github api secondary rate limits
+	"secondary rate limit",
+	"api rate limit exceeded",
+	"abuse detection",
+	// vault right after the port-forward opens
+	"connection refused",
+	"connection reset by peer",
+	"i/o timeout",
+	"tls handshake timeout",
+	"502 bad gateway",
+	"503 service unavailable",
+	// minio state lock contention
+	"error acquiring the state lock",
+	"error releasing the state lock",
+}
+
+// IsTransient reports whether err is a failed terraform execution with a diagnostic a re-run is known to fix
+func IsTransient(err error) bool {
+	var tfErr *ExecutionError
+	if !errors.As(err, &tfErr) {
+		return false
+	}
+	for _, diagnostic := range tfErr.Diagnostics {
+		message := strings.ToLower(diagnostic.Summary + " " + diagnostic.Detail)
+		for _, fragment := range transientDiagnostics {
+			if strings.Contains(message, fragment) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runTerraform runs a terraform command for an entrypoint, retrying transient failures with backoff.
+// Failures are returned as an *ExecutionError.
+func runTerraform(tfEnvs map[string]string, terraformClientPath, tfEntrypoint string, logFile *os.File, args ...string) (*Output, error) {
+	var output *Output
+	attempt := 0
+
+	err := pkg.Retry(retryAttempts, retrySleep, "terraform "+args[0], func() error {
+		attempt++
+		var err error
+		output, err = execTerraform(tfEnvs, terraformClientPath, logFile, args...)
+		if err == nil {
+			return nil
+		}
+
+		tfErr := &ExecutionError{Entrypoint: tfEntrypoint, Action: args[0], LogFile: logFile.Name(), Diagnostics: output.Diagnostics, Err: err}
+		if !IsTransient(tfErr) {
+			return pkg.StopRetry(tfErr)
+		}
+		if attempt < retryAttempts {
+			log.Warn().Msgf("terraform %s for %s failed with a transient error, retrying (attempt %d of %d): %s", args[0], tfEntrypoint, attempt, retryAttempts, tfErr)
+		}
+		return tfErr
+	})
+
+	return output, err
+}
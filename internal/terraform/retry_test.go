@@ -0,0 +1,133 @@
+package terraform
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "github secondary rate limit",
+			err: &ExecutionError{Diagnostics: []Diagnostic{
+				{Severity: "error", Summary: "POST https://api.github.com/orgs/acme/repos: 403 You have exceeded a secondary rate limit."},
+			}},
+			want: true,
+		},
+		{
+			name: "vault not reachable yet",
+			err: &ExecutionError{Diagnostics: []Diagnostic{
+				{Severity: "error", Summary: "error checking mount", Detail: "Get \"http://127.0.0.1:8200/v1/sys/mounts\": dial tcp 127.0.0.1:8200: connect: connection refused"},
+			}},
+			want: true,
+		},
+		{
+			name: "minio state lock",
+			err: &ExecutionError{Diagnostics: []Diagnostic{
+				{Severity: "error", Summary: "Error acquiring the state lock"},
+			}},
+			want: true,
+		},
+		{
+			name: "wrapped transient error",
+			err:  fmt.Errorf("error creating github resources: %w", &ExecutionError{Diagnostics: []Diagnostic{{Summary: "API rate limit exceeded"}}}),
+			want: true,
+		},
+		{
+			name: "repository already exists",
+			err: &ExecutionError{Diagnostics: []Diagnostic{
+				{Severity: "error", Summary: "POST https://api.github.com/orgs/acme/repos: 422", Detail: "name already exists on this account"},
+			}},
+		},
+		{
+			name: "no diagnostics",
+			err:  &ExecutionError{Err: errors.New("exit status 1")},
+		},
+		{
+			name: "not a terraform error",
+			err:  errors.New("connection refused"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeTerraform writes a terraform stand in that prints diagnostic and exits 1 for the first failures runs
+func fakeTerraform(t *testing.T, failures int, diagnostic string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "runs")
+	script := fmt.Sprintf(`#!/bin/sh
+echo run >> %s
+if [ "$(wc -l < %s)" -le %d ]; then
+  echo '{"type":"diagnostic","diagnostic":{"severity":"error","summary":"%s"}}'
+  exit 1
+fi
+`, counter, counter, failures, diagnostic)
+	path := filepath.Join(dir, "terraform")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path, counter
+}
+
+func TestRunTerraformRetries(t *testing.T) {
+	retrySleep = time.Millisecond
+	retryAttempts = 3
+	defer func() { retrySleep, retryAttempts = 15*time.Second, 4 }()
+
+	tests := []struct {
+		name       string
+		failures   int
+		diagnostic string
+		wantRuns   int
+		wantErr    bool
+	}{
+		{name: "transient failure recovers", failures: 2, diagnostic: "Error acquiring the state lock", wantRuns: 3},
+		{name: "transient failure exhausts attempts", failures: 5, diagnostic: "Error acquiring the state lock", wantRuns: 3, wantErr: true},
+		{name: "permanent failure is not retried", failures: 5, diagnostic: "Invalid resource type", wantRuns: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terraformPath, counter := fakeTerraform(t, tt.failures, tt.diagnostic)
+			logFile, err := os.Create(filepath.Join(t.TempDir(), "terraform.log"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer logFile.Close()
+
+			_, err = runTerraform(map[string]string{}, terraformPath, "/k1/gitops/terraform/github", logFile, "apply", "-json")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runTerraform() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var tfErr *ExecutionError
+			if err != nil && !errors.As(err, &tfErr) {
+				t.Errorf("runTerraform() error = %T, want *ExecutionError", err)
+			}
+
+			runs, err := os.ReadFile(counter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Count(string(runs), "run"); got != tt.wantRuns {
+				t.Errorf("runTerraform() ran terraform %d times, want %d", got, tt.wantRuns)
+			}
+		})
+	}
+}
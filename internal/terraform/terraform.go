@@ -162,19 +162,23 @@ func ApplyBaseTerraform(dryRun bool, directory string) {
 
 		var terraformOutput bytes.Buffer
 		k := exec.Command(config.TerraformClientPath, "output", "vault_unseal_kms_key")
+		k.Env = pkg.CommandEnv(envs)
 		k.Stdout = &terraformOutput
 		k.Stderr = os.Stderr
 		errKey := k.Run()
 		if errKey != nil {
 			log.Panic().Err(err).Msg("error: terraform apply failed")
 		}
-		keyIdNoSpace := strings.TrimSpace(terraformOutput.String())
-		keyId := keyIdNoSpace[1 : len(keyIdNoSpace)-1]
+		keyId, err := unquoteOutput(terraformOutput.String())
+		if err != nil {
+			log.Panic().Err(err).Msg("error: terraform output vault_unseal_kms_key failed")
+		}
 		log.Info().Msgf("keyid is: %s", keyId)
 		viper.Set("vault.kmskeyid", keyId)
 
 		var terraformNodeArnOutput bytes.Buffer
 		k = exec.Command(config.TerraformClientPath, "output", "eks_node_role_arn")
+		k.Env = pkg.CommandEnv(envs)
 		k.Stdout = &terraformNodeArnOutput
 		k.Stderr = os.Stderr
 		errKey = k.Run()
@@ -182,8 +186,10 @@ func ApplyBaseTerraform(dryRun bool, directory string) {
 			log.Panic().Err(err).Msg("error: terraform output failed")
 		}
 		os.RemoveAll(fmt.Sprintf("%s/.terraform", directory))
-		nodeGroupArn := strings.TrimSpace(terraformNodeArnOutput.String())
-		nodeGroupArn = nodeGroupArn[1 : len(nodeGroupArn)-1]
+		nodeGroupArn, err := unquoteOutput(terraformNodeArnOutput.String())
+		if err != nil {
+			log.Panic().Err(err).Msg("error: terraform output eks_node_role_arn failed")
+		}
 		log.Info().Msgf("nodeGroupArn is: %s", nodeGroupArn)
 		viper.Set("aws.node-group-arn", nodeGroupArn)
 		viper.Set("create.terraformapplied.base", true)
@@ -366,13 +372,21 @@ func initActionAutoApprove(dryRun bool, tfAction, tfEntrypoint string, tfEnvs ma
 		log.Info().Msg("error: could not change to directory " + tfEntrypoint)
 		return err
 	}
-	err = pkg.ExecShellWithVars(tfEnvs, config.TerraformClientPath, "init")
+
+	logFile, err := entrypointLogFile(tfEntrypoint, tfAction)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	log.Info().Msgf("terraform %s output for %s is logged to %s", tfAction, tfEntrypoint, logFile.Name())
+
+	_, err = runTerraform(tfEnvs, config.TerraformClientPath, tfEntrypoint, logFile, "init", "-no-color")
 	if err != nil {
 		log.Printf("error: terraform init for %s failed: %s", tfEntrypoint, err)
 		return err
 	}
 
-	err = pkg.ExecShellWithVars(tfEnvs, config.TerraformClientPath, tfAction, "-auto-approve")
+	_, err = runTerraform(tfEnvs, config.TerraformClientPath, tfEntrypoint, logFile, tfAction, "-auto-approve", "-json")
 	if err != nil {
 		log.Printf("error: terraform %s -auto-approve for %s failed %s", tfAction, tfEntrypoint, err)
 		return err
@@ -492,14 +506,27 @@ func InitReconfigureDestroyAutoApprove(dryRun bool, tfEntrypoint string) {
 	InitAndReconfigureActionAutoApprove(dryRun, tfAction, tfEntrypoint)
 }
 
+// unquoteOutput strips the quotes terraform prints around a string output
+func unquoteOutput(output string) (string, error) {
+	value := strings.TrimSpace(output)
+	if len(value) < 2 {
+		return "", fmt.Errorf("unexpected terraform output %q", value)
+	}
+	return value[1 : len(value)-1], nil
+}
+
 // todo need to write something that outputs -json type and can get multiple values
 func OutputSingleValue(dryRun bool, directory, tfEntrypoint, outputName string) {
 
 	config := configs.ReadConfig()
 	os.Chdir(directory)
 
+	envs := map[string]string{}
+	aws.ProfileInjection(&envs)
+
 	var tfOutput bytes.Buffer
 	tfOutputCmd := exec.Command(config.TerraformClientPath, "output", outputName)
+	tfOutputCmd.Env = pkg.CommandEnv(envs)
 	tfOutputCmd.Stdout = &tfOutput
 	tfOutputCmd.Stderr = os.Stderr
 	err := tfOutputCmd.Run()
@@ -0,0 +1,22 @@
+package terraform
+
+import "testing"
+
+func TestUnquoteOutput(t *testing.T) {
+	tests := []struct {
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{output: "\"arn:aws:iam::123:role/nodes\"\n", want: "arn:aws:iam::123:role/nodes"},
+		{output: "\"\"", want: ""},
+		{output: "\n", wantErr: true},
+		{output: "x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := unquoteOutput(tt.output)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("unquoteOutput(%q) = %q, %v, want %q", tt.output, got, err, tt.want)
+		}
+	}
+}
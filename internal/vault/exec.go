@@ -2,7 +2,6 @@ package vault
 
 import (
 	"bytes"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -13,6 +12,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/internal/k8s"
 	"github.com/rs/zerolog/log"
 	v1 "k8s.io/api/core/v1"
@@ -139,11 +139,7 @@ func runUnseal(kubeConfigPath string, o VaultUnsealOptions) {
 		checkIntervalDuration := time.Duration(checkInterval) * time.Second
 
 		httpClient = http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: true,
-				},
-			},
+			Transport: httpCommon.NewTransport(true),
 		}
 
 		sigChan := make(chan os.Signal, 1)
@@ -0,0 +1,70 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// ExportKV reads the latest version of every secret of a kv v2 mount, the secrets are keyed by their path in the
+// mount. Secrets whose latest version is deleted are left out.
+func ExportKV(client *vault.Client, mount string) (map[string]map[string]interface{}, error) {
+	secrets := map[string]map[string]interface{}{}
+	err := exportKVDir(client, mount, "", secrets)
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+func exportKVDir(client *vault.Client, mount, dir string, secrets map[string]map[string]interface{}) error {
+	list, err := client.Logical().List(fmt.Sprintf("%s/metadata/%s", mount, dir))
+	if err != nil {
+		return fmt.Errorf("error listing %s/%s: %w", mount, dir, err)
+	}
+	// an empty mount or directory has no list
+	if list == nil {
+		return nil
+	}
+
+	keys, _ := list.Data["keys"].([]interface{})
+	for _, key := range keys {
+		name, ok := key.(string)
+		if !ok {
+			continue
+		}
+		if strings.HasSuffix(name, "/") {
+			err = exportKVDir(client, mount, dir+name, secrets)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		secret, err := client.KVv2(mount).Get(context.TODO(), dir+name)
+		if errors.Is(err, vault.ErrSecretNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if secret.Data != nil {
+			secrets[dir+name] = secret.Data
+		}
+	}
+	return nil
+}
+
+// ImportKV writes secrets read by ExportKV to a kv v2 mount as new versions
+func ImportKV(client *vault.Client, mount string, secrets map[string]map[string]interface{}) error {
+	for path, data := range secrets {
+		_, err := client.KVv2(mount).Put(context.TODO(), path, data)
+		if err != nil {
+			return fmt.Errorf("error writing %s/%s: %w", mount, path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
+	"github.com/rs/zerolog/log"
+)
+
+// UnsealIfSealed unseals a restarted vault with the keys of the vault-unseal-secret written when it was
+// initialized. A vault that is unsealed, or that was never initialized such as a dev mode vault, is left as is.
+func UnsealIfSealed(kubeConfigPath, vaultAPIAddress string) error {
+	httpClient = http.Client{
+		Transport: httpCommon.NewTransport(true),
+	}
+
+	var healthResponse HealthResponse
+	var err error
+	for attempt := 1; attempt <= 12; attempt++ {
+		healthResponse, err = vaultHealth(vaultAPIAddress)
+		if err == nil {
+			break
+		}
+		log.Info().Msgf("vault is not reachable yet (attempt %d of 12): %s", attempt, err)
+		time.Sleep(time.Duration(checkInterval) * time.Second)
+	}
+	if err != nil {
+		return fmt.Errorf("vault is not reachable at %s: %w", vaultAPIAddress, err)
+	}
+
+	switch {
+	case !healthResponse.Sealed:
+		log.Info().Msg("vault is unsealed")
+		return nil
+	case !healthResponse.Initialized:
+		log.Warn().Msg("vault is sealed and not initialized, skipping unseal")
+		return nil
+	}
+
+	log.Info().Msg("vault is initialized but sealed, unsealing...")
+	initResponse, err := fetchVaultExistingSecretData(kubeConfigPath)
+	if err != nil {
+		return err
+	}
+	if len(initResponse.Keys) == 0 {
+		return errors.New("vault is sealed and the vault-unseal-secret has no unseal keys, unseal vault manually")
+	}
+	return vaultUnseal(&VaultUnsealOptions{VaultAPIAddress: vaultAPIAddress}, initResponse)
+}
+
+func vaultHealth(vaultAPIAddress string) (HealthResponse, error) {
+	response, err := httpClient.Get(vaultAPIAddress + vaultHealthEndpoint)
+	if err != nil {
+		return HealthResponse{}, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return HealthResponse{}, err
+	}
+
+	// sealed and uninitialized vaults answer with an error status and a health body
+	var healthResponse HealthResponse
+	err = json.Unmarshal(body, &healthResponse)
+	if err != nil {
+		return HealthResponse{}, fmt.Errorf("unexpected vault health response %d: %s", response.StatusCode, body)
+	}
+	return healthResponse, nil
+}
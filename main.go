@@ -68,7 +68,7 @@ func main() {
 
 	// setup default logging
 	// this Go standard log is active to keep compatibility with current code base
-	stdLog.SetOutput(file)
+	stdLog.SetOutput(pkg.NewRedactWriter(file))
 	stdLog.SetPrefix("LOG: ")
 	stdLog.SetFlags(stdLog.Ldate | stdLog.Lmicroseconds | stdLog.Llongfile)
 
@@ -80,6 +80,7 @@ func main() {
 	if err = pkg.SetupViper(config); err != nil {
 		stdLog.Panic(err)
 	}
+	pkg.RegisterConfiguredSecrets()
 
 	viper.Set("k1-paths.logs-dir", logsFolder)
 	err = viper.WriteConfig()
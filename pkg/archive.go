@@ -0,0 +1,146 @@
+package pkg
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ExtractTarGz extracts a .tar.gz archive to destination. rename maps the slash separated name of an entry to its
+// path in destination and skips the entry by returning an empty string, a nil rename keeps the names. Entries are
+// rejected when they, or the symlinks they create or write through, resolve outside of destination.
+func ExtractTarGz(archivePath, destination string, rename func(name string) string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("%s is not a .tar.gz archive: %w", archivePath, err)
+	}
+	defer gzipReader.Close()
+
+	destination = filepath.Clean(destination)
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+
+		name := filepath.ToSlash(filepath.Clean(header.Name))
+		if rename != nil {
+			name = rename(name)
+		}
+		if name == "" || name == "." {
+			continue
+		}
+
+		target := filepath.Join(destination, filepath.FromSlash(name))
+		if !withinDir(destination, target) || target == destination {
+			return fmt.Errorf("archive entry %s escapes the destination directory", header.Name)
+		}
+		err = checkParentDirs(destination, target)
+		if err != nil {
+			return fmt.Errorf("archive entry %s: %w", header.Name, err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = extractTarFile(tarReader, target, os.FileMode(header.Mode).Perm())
+		case tar.TypeSymlink:
+			err = extractTarSymlink(destination, target, header.Linkname)
+		default:
+			log.Debug().Msgf("skipping unsupported archive entry %s", header.Name)
+		}
+		if err != nil {
+			return fmt.Errorf("archive entry %s: %w", header.Name, err)
+		}
+	}
+}
+
+// withinDir reports whether path is dir or inside of it, both cleaned
+func withinDir(dir, path string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator))
+}
+
+// checkParentDirs rejects a target below a symlink or a file, an earlier entry could otherwise redirect it
+func checkParentDirs(destination, target string) error {
+	rel, err := filepath.Rel(destination, filepath.Dir(target))
+	if err != nil || rel == "." {
+		return err
+	}
+
+	current := destination
+	for _, part := range strings.Split(rel, string(os.PathSeparator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink", current)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", current)
+		}
+	}
+	return nil
+}
+
+func extractTarFile(r io.Reader, target string, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+	// writing to an existing symlink would write to the file it points to
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%s is a symlink", target)
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, r)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// extractTarSymlink creates a symlink whose link resolves inside destination, relative to the directory of target
+func extractTarSymlink(destination, target, linkname string) error {
+	if filepath.IsAbs(linkname) || strings.HasPrefix(linkname, "/") {
+		return fmt.Errorf("symlink to the absolute path %s", linkname)
+	}
+	if !withinDir(destination, filepath.Join(filepath.Dir(target), filepath.FromSlash(linkname))) {
+		return fmt.Errorf("symlink to %s escapes the destination directory", linkname)
+	}
+
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(linkname, target)
+}
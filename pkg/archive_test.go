@@ -0,0 +1,134 @@
+package pkg
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testEntry is a regular file, or a symlink when link is set
+type testEntry struct {
+	name    string
+	content string
+	link    string
+}
+
+func writeTarGz(t *testing.T, entries []testEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "archive.tar.gz")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	gzipWriter := gzip.NewWriter(out)
+	defer gzipWriter.Close()
+	tarWriter := tar.NewWriter(gzipWriter)
+	defer tarWriter.Close()
+
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+		if entry.link != "" {
+			header = &tar.Header{Name: entry.name, Mode: 0777, Linkname: entry.link, Typeflag: tar.TypeSymlink}
+		}
+		err := tarWriter.WriteHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestExtractTarGz(t *testing.T) {
+	archive := writeTarGz(t, []testEntry{
+		{name: "charts/argocd/Chart.yaml", content: "name: argocd"},
+		{name: "charts/current", link: "argocd"},
+		{name: "docs/chart.yaml", link: "../charts/argocd/Chart.yaml"},
+		{name: "skipped.txt", content: "skipped"},
+	})
+	destination := t.TempDir()
+
+	err := ExtractTarGz(archive, destination, func(name string) string {
+		if name == "skipped.txt" {
+			return ""
+		}
+		return name
+	})
+	if err != nil {
+		t.Fatalf("ExtractTarGz() = %v, want symlinks inside the destination extracted", err)
+	}
+	content, err := os.ReadFile(filepath.Join(destination, "docs", "chart.yaml"))
+	if err != nil || string(content) != "name: argocd" {
+		t.Errorf("ExtractTarGz() extracted docs/chart.yaml = %q, %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(destination, "skipped.txt")); !os.IsNotExist(err) {
+		t.Error("ExtractTarGz() extracted an entry rename skipped")
+	}
+}
+
+func TestExtractTarGzEscapes(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []testEntry
+		wantErr string
+	}{
+		{
+			name:    "entry outside of the destination",
+			entries: []testEntry{{name: "../escaped", content: "x"}},
+			wantErr: "escapes the destination directory",
+		},
+		{
+			name:    "symlink to an absolute path",
+			entries: []testEntry{{name: "link", link: "/etc"}},
+			wantErr: "absolute path",
+		},
+		{
+			name:    "symlink out of the destination",
+			entries: []testEntry{{name: "dir/link", link: "../../outside"}},
+			wantErr: "escapes the destination directory",
+		},
+		{
+			name: "file written through a symlink",
+			entries: []testEntry{
+				{name: "link", link: "dir"},
+				{name: "link/file", content: "x"},
+			},
+			wantErr: "is a symlink",
+		},
+		{
+			name: "file replacing a symlink",
+			entries: []testEntry{
+				{name: "target", content: "original"},
+				{name: "link", link: "target"},
+				{name: "link", content: "x"},
+			},
+			wantErr: "is a symlink",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := t.TempDir()
+			destination := filepath.Join(parent, "destination")
+			err := os.Mkdir(destination, 0755)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = ExtractTarGz(writeTarGz(t, tt.entries), destination, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ExtractTarGz() = %v, want an error containing %q", err, tt.wantErr)
+			}
+			if _, err := os.Lstat(filepath.Join(parent, "escaped")); !os.IsNotExist(err) {
+				t.Error("ExtractTarGz() wrote outside of the destination")
+			}
+		})
+	}
+}
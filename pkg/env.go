@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// inheritedEnvVars are the variables of the kubefirst process passed on to commands run with vars, anything
+// else, including tokens exported by a previous command, has to be provided explicitly
+var inheritedEnvVars = map[string]bool{
+	// process basics
+	"HOME":    true,
+	"LANG":    true,
+	"LOGNAME": true,
+	"PATH":    true,
+	"SHELL":   true,
+	"TERM":    true,
+	"TMPDIR":  true,
+	"TZ":      true,
+	"USER":    true,
+	// windows
+	"APPDATA":      true,
+	"COMSPEC":      true,
+	"LOCALAPPDATA": true,
+	"PATHEXT":      true,
+	"SYSTEMROOT":   true,
+	"TEMP":         true,
+	"TMP":          true,
+	"USERPROFILE":  true,
+	// networking
+	"HTTP_PROXY":    true,
+	"HTTPS_PROXY":   true,
+	"NO_PROXY":      true,
+	"http_proxy":    true,
+	"https_proxy":   true,
+	"no_proxy":      true,
+	"SSL_CERT_DIR":  true,
+	"SSL_CERT_FILE": true,
+	// aws profiles and the assumed role credentials the aws flow exports
+	"AWS_ACCESS_KEY_ID":           true,
+	"AWS_CONFIG_FILE":             true,
+	"AWS_PROFILE":                 true,
+	"AWS_REGION":                  true,
+	"AWS_SDK_LOAD_CONFIG":         true,
+	"AWS_SECRET_ACCESS_KEY":       true,
+	"AWS_SESSION_TOKEN":           true,
+	"AWS_SHARED_CREDENTIALS_FILE": true,
+	// container runtime and cluster access
+	"DOCKER_CERT_PATH":  true,
+	"DOCKER_CONFIG":     true,
+	"DOCKER_HOST":       true,
+	"DOCKER_TLS_VERIFY": true,
+	"KUBECONFIG":        true,
+	// terraform cli settings
+	"TF_CLI_CONFIG_FILE":  true,
+	"TF_LOG":              true,
+	"TF_LOG_PATH":         true,
+	"TF_PLUGIN_CACHE_DIR": true,
+}
+
+// inheritedEnvPrefixes are variable name prefixes passed on to commands run with vars
+var inheritedEnvPrefixes = []string{"LC_", "XDG_"}
+
+// CommandEnv builds the environment of a single command from the allowlisted variables of the kubefirst
+// process and vars, vars take precedence. The kubefirst process environment is never modified.
+func CommandEnv(vars map[string]string) []string {
+	env := []string{}
+	for _, entry := range os.Environ() {
+		name, _, found := strings.Cut(entry, "=")
+		if !found || !isInheritedEnvVar(name) {
+			continue
+		}
+		if _, overridden := vars[name]; overridden {
+			continue
+		}
+		env = append(env, entry)
+	}
+
+	names := []string{}
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, name+"="+vars[name])
+	}
+
+	return env
+}
+
+func isInheritedEnvVar(name string) bool {
+	if inheritedEnvVars[name] {
+		return true
+	}
+	for _, prefix := range inheritedEnvPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
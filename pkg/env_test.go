@@ -0,0 +1,77 @@
+package pkg
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCommandEnv(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin")
+	t.Setenv("LC_ALL", "C")
+	t.Setenv("KUBEFIRST_TEST_UNLISTED", "leak")
+
+	env := CommandEnv(map[string]string{"GITHUB_TOKEN": "ghp_secret", "PATH": "/k1/tools"})
+
+	tests := []struct {
+		entry string
+		want  bool
+	}{
+		{"GITHUB_TOKEN=ghp_secret", true},
+		{"PATH=/k1/tools", true},
+		{"PATH=/usr/bin", false},
+		{"LC_ALL=C", true},
+		{"KUBEFIRST_TEST_UNLISTED=leak", false},
+	}
+	for _, tt := range tests {
+		if got := containsEntry(env, tt.entry); got != tt.want {
+			t.Errorf("CommandEnv() contains %q = %v, want %v", tt.entry, got, tt.want)
+		}
+	}
+}
+
+func TestExecShellWithVarsIsolatesEnvironment(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("requires /bin/sh")
+	}
+
+	var first bytes.Buffer
+	err := ExecShellWithVarsStreams(map[string]string{"KUBEFIRST_TEST_SECRET": "hunter2"}, &first, &first, "/bin/sh", "-c", "echo \"secret=$KUBEFIRST_TEST_SECRET\"")
+	if err != nil {
+		t.Fatalf("ExecShellWithVarsStreams() error = %v", err)
+	}
+	if !strings.Contains(first.String(), "secret=hunter2") {
+		t.Fatalf("first command did not see its own vars, output %q", first.String())
+	}
+
+	if value, ok := os.LookupEnv("KUBEFIRST_TEST_SECRET"); ok {
+		t.Errorf("vars leaked into the kubefirst process environment, KUBEFIRST_TEST_SECRET=%q", value)
+	}
+
+	var second bytes.Buffer
+	err = ExecShellWithVarsStreams(map[string]string{}, &second, &second, "/bin/sh", "-c", "echo \"secret=$KUBEFIRST_TEST_SECRET\"")
+	if err != nil {
+		t.Fatalf("ExecShellWithVarsStreams() error = %v", err)
+	}
+	if strings.Contains(second.String(), "hunter2") {
+		t.Errorf("vars of the first command are visible to the next, output %q", second.String())
+	}
+
+	err = ExecShellWithVars(map[string]string{"KUBEFIRST_TEST_SECRET": "hunter2"}, "/bin/sh", "-c", "true")
+	if err != nil {
+		t.Fatalf("ExecShellWithVars() error = %v", err)
+	}
+	if _, ok := os.LookupEnv("KUBEFIRST_TEST_SECRET"); ok {
+		t.Error("ExecShellWithVars() leaked vars into the kubefirst process environment")
+	}
+}
+
+func containsEntry(env []string, entry string) bool {
+	for _, e := range env {
+		if e == entry {
+			return true
+		}
+	}
+	return false
+}
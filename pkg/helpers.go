@@ -15,6 +15,8 @@ import (
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/kubefirst/kubefirst/internal/gitopsTemplate"
+	"github.com/kubefirst/kubefirst/internal/httpCommon"
 	"github.com/kubefirst/kubefirst/internal/progressPrinter"
 
 	"github.com/kubefirst/kubefirst/configs"
@@ -75,18 +77,17 @@ func DetokenizeDirectory(path string, fi os.FileInfo, err error) error {
 		return nil
 	}
 
-	matched, err := filepath.Match("*", fi.Name())
+	// symlinks are left as they are, their targets inside path are visited on their own
+	if !fi.Mode().IsRegular() {
+		return nil
+	}
 
+	read, err := os.ReadFile(path)
 	if err != nil {
 		log.Panic().Msg(err.Error())
 	}
 
-	if matched {
-		read, err := os.ReadFile(path)
-		if err != nil {
-			log.Panic().Msg(err.Error())
-		}
-
+	if gitopsTemplate.IsTextFile(fi.Name(), read) {
 		var registryAddon RegistryAddon
 		enableCheck := false
 		removeFile := false
@@ -353,7 +354,7 @@ func DetokenizeDirectory(path string, fi os.FileInfo, err error) error {
 				log.Panic().Msg(err.Error())
 			}
 		} else {
-			err = os.WriteFile(path, []byte(newContents), 0)
+			err = os.WriteFile(path, []byte(newContents), fi.Mode().Perm())
 			if err != nil {
 				log.Panic().Msg(err.Error())
 			}
@@ -546,7 +547,7 @@ func AwaitHostNTimes(url string, times int, gracePeriod time.Duration) {
 	log.Printf("AwaitHostNTimes %d called with grace period of: %d seconds", times, gracePeriod)
 	max := times
 	for i := 0; i < max; i++ {
-		resp, _ := http.Get(url)
+		resp, _ := httpCommon.NewClient().Get(url)
 		if resp != nil && resp.StatusCode == 200 {
 			log.Printf("%s resolved, %s second grace period required...", url, gracePeriod)
 			time.Sleep(time.Second * gracePeriod)
@@ -705,7 +706,7 @@ func OpenBrowser(url string) error {
 // todo: this is temporary
 func IsConsoleUIAvailable(url string) error {
 	attempts := 10
-	httpClient := http.DefaultClient
+	httpClient := httpCommon.NewClient()
 	for i := 0; i < attempts; i++ {
 
 		req, err := http.NewRequest(http.MethodGet, url, nil)
@@ -1,8 +1,10 @@
 package pkg
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -232,3 +234,35 @@ func TestGetFileContent(t *testing.T) {
 		})
 	}
 }
+
+func TestDetokenize(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "entrypoint.sh")
+	err := os.WriteFile(script, []byte("echo <CLOUD_PROVIDER>\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := []byte("\x89PNG\r\n\x1a\n\x00<CLOUD_PROVIDER>")
+	image := filepath.Join(dir, "logo.dat")
+	err = os.WriteFile(image, binary, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Detokenize(dir)
+
+	info, err := os.Stat(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("Detokenize() changed the mode of %s to %s", script, info.Mode().Perm())
+	}
+	content, err := os.ReadFile(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, binary) {
+		t.Errorf("Detokenize() rewrote the binary file %s", image)
+	}
+}
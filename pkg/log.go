@@ -10,11 +10,11 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// ZerologSetup setup Zerolog and return the configured Zerolog instance
+// ZerologSetup setup Zerolog and return the configured Zerolog instance, secrets are masked before reaching logFile
 func ZerologSetup(logFile *os.File, level zerolog.Level) zerolog.Logger {
 	zerolog.CallerMarshalFunc = shortCallerMarshalFunc
 	zerolog.SetGlobalLevel(level)
-	return log.Output(zerolog.ConsoleWriter{Out: logFile, NoColor: true, TimeFormat: "2006-01-02T15:04"}).With().Timestamp().Caller().Logger()
+	return log.Output(zerolog.ConsoleWriter{Out: NewRedactWriter(logFile), NoColor: true, TimeFormat: "2006-01-02T15:04"}).With().Timestamp().Caller().Logger()
 }
 
 // shortCallerMarshalFunc is a custom marshal function for zerolog.CallerMarshalFunc variable.
@@ -16,8 +16,8 @@ import (
 
 // RunNgrok creates a ngrok tunnel, listens for incoming connections, and starts a goroutine to handle each connection
 // with context passed to it, also it logs the errors and sets the url in viper. RunNgrok is called to run in goroutine
-// the caller needs to cancel the context to stop the tunnel.
-func RunNgrok(ctx context.Context) {
+// the caller needs to cancel the context to stop the tunnel. Connections are forwarded to forwardAddress, i.e. :80
+func RunNgrok(ctx context.Context, forwardAddress string) {
 	defer func() {
 		log.Info().Msg("RunNgrok context was cancelled, conn closed and not accepting new connections, and function exited")
 	}()
@@ -55,7 +55,7 @@ func RunNgrok(ctx context.Context) {
 		if ctx.Err() == nil {
 			log.Info().Msgf("Ngrok is accepting connections: %s", conn.RemoteAddr())
 			go func() {
-				err = handleConn(ctx, conn)
+				err = handleConn(ctx, conn, forwardAddress)
 				if err == nil {
 					return
 				}
@@ -73,8 +73,8 @@ func RunNgrok(ctx context.Context) {
 
 // handleConn handles the connection by copying the data from the connection to the destination address and vice versa
 // it also logs the errors and closes the connection when the context is cancelled
-func handleConn(ctx context.Context, conn net.Conn) error {
-	next, err := net.Dial("tcp", ":80")
+func handleConn(ctx context.Context, conn net.Conn, forwardAddress string) error {
+	next, err := net.Dial("tcp", forwardAddress)
 	if err != nil {
 		return err
 	}
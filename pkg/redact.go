@@ -0,0 +1,130 @@
+package pkg
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// RedactedValue replaces secrets in logs and command output
+const RedactedValue = "********"
+
+// minSecretLength keeps short values, which would mask unrelated text, out of the registry
+const minSecretLength = 6
+
+var (
+	secretsMu sync.RWMutex
+	secrets   = map[string]bool{}
+)
+
+// secretPatterns mask well-known secrets that were never registered
+var secretPatterns = []*regexp.Regexp{
+	// pem encoded private keys and certificates, including escaped newlines in json output
+	regexp.MustCompile(`-----BEGIN [A-Z ]+-----[\s\S]*?-----END [A-Z ]+-----`),
+	// github personal, oauth, app and refresh tokens
+	regexp.MustCompile(`\b(ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{20,}\b`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{20,}\b`),
+	// gitlab personal access tokens
+	regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`),
+	// vault service and batch tokens
+	regexp.MustCompile(`\bhv[sb]\.[A-Za-z0-9_-]{20,}\b`),
+}
+
+// kubeconfigKeyPattern masks the client key of kubeconfig output, keeping the field name
+var kubeconfigKeyPattern = regexp.MustCompile(`(client-key-data:\s*)\S+`)
+
+// secretEnvVarPattern matches the names of command vars whose values are registered as secrets
+var secretEnvVarPattern = regexp.MustCompile(`(?i)(token|secret|password|private_key|access_key)`)
+
+// RegisterSecret masks values in every log line and logged command output from now on
+func RegisterSecret(values ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) >= minSecretLength {
+			secrets[value] = true
+		}
+	}
+}
+
+// RegisterSecretVars registers the values of command vars with secret looking names, i.e. GITHUB_TOKEN or TF_VAR_vault_token
+func RegisterSecretVars(vars map[string]string) {
+	for name, value := range vars {
+		if secretEnvVarPattern.MatchString(name) {
+			RegisterSecret(value)
+		}
+	}
+}
+
+// RegisterConfiguredSecrets registers the tokens from the environment and the credentials kubefirst stores in its config
+func RegisterConfiguredSecrets() {
+	for _, name := range []string{"GITHUB_TOKEN", "GITLAB_TOKEN", "CIVO_TOKEN", "KUBEFIRST_GITHUB_AUTH_TOKEN", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"} {
+		RegisterSecret(os.Getenv(name))
+	}
+	for _, key := range []string{
+		"argocd.admin.apitoken",
+		"argocd.admin.password",
+		"botpassword",
+		"components.argocd.auth-token",
+		"components.argocd.password",
+		"github.atlantis.webhook.secret",
+		"gitlab.root.password",
+		"gitlab.runnertoken",
+		"gitlab.token",
+		"kbot.password",
+		"kbot.private-key",
+		"kubefirst.state-store-creds.secret-access-key-id",
+		"secrets.atlantis-webhook",
+		"vault.token",
+	} {
+		RegisterSecret(viper.GetString(key))
+	}
+	RegisterSecret(viper.GetStringSlice("vault.unseal-keys")...)
+}
+
+// Redact masks registered secrets and well-known secret patterns in s
+func Redact(s string) string {
+	secretsMu.RLock()
+	values := make([]string, 0, len(secrets))
+	for value := range secrets {
+		values = append(values, value)
+	}
+	secretsMu.RUnlock()
+
+	// longest first so a secret containing another one is masked whole
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	for _, value := range values {
+		s = strings.ReplaceAll(s, value, RedactedValue)
+	}
+
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, RedactedValue)
+	}
+	return kubeconfigKeyPattern.ReplaceAllString(s, "${1}"+RedactedValue)
+}
+
+// redactWriter masks secrets in every write, writes are expected to hold whole log lines
+type redactWriter struct {
+	out io.Writer
+}
+
+// NewRedactWriter returns a writer that masks secrets before writing to out
+func NewRedactWriter(out io.Writer) io.Writer {
+	return redactWriter{out: out}
+}
+
+func (w redactWriter) Write(p []byte) (int, error) {
+	_, err := w.out.Write([]byte(Redact(string(p))))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -29,3 +29,14 @@ func retry(attempts int, sleep time.Duration, action string, f func() error) err
 type stop struct {
 	error
 }
+
+// Retry calls f until it succeeds, returns an error wrapped with StopRetry or runs out of attempts,
+// doubling the sleep with jitter between attempts
+func Retry(attempts int, sleep time.Duration, action string, f func() error) error {
+	return retry(attempts, sleep, action, f)
+}
+
+// StopRetry wraps err so Retry returns it without further attempts
+func StopRetry(err error) error {
+	return stop{err}
+}
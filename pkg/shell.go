@@ -3,7 +3,7 @@ package pkg
 import (
 	"bufio"
 	"bytes"
-	"os"
+	"io"
 	"os/exec"
 	"strings"
 
@@ -23,10 +23,10 @@ func ExecShellReturnStrings(command string, args ...string) (string, string, err
 	}
 
 	if len(errb.String()) > 0 {
-		log.Error().Msgf("error executing command: %s", errb.String())
+		log.Error().Msgf("error executing command: %s", Redact(errb.String()))
 	}
 
-	log.Info().Msgf("OUT: %s", outb.String())
+	log.Info().Msgf("OUT: %s", Redact(outb.String()))
 	log.Info().Msgf("Command: %s", command)
 
 	return outb.String(), errb.String(), err
@@ -34,16 +34,17 @@ func ExecShellReturnStrings(command string, args ...string) (string, string, err
 
 // ExecShellWithVars Exec shell actions supporting:
 //   - On-the-fly logging of result
-//   - Map of Vars loaded
+//   - Map of Vars loaded into the command environment only, see CommandEnv
 func ExecShellWithVars(osvars map[string]string, command string, args ...string) error {
 
 	log.Debug().Msgf("Debug: Running %s", command)
+	RegisterSecretVars(osvars)
 	for k, v := range osvars {
-		os.Setenv(k, v)
 		suppressedValue := strings.Repeat("*", len(v))
 		log.Info().Msgf(" export %s = %s", k, suppressedValue)
 	}
 	cmd := exec.Command(command, args...)
+	cmd.Env = CommandEnv(osvars)
 	cmdReaderOut, err := cmd.StdoutPipe()
 	if err != nil {
 		log.Error().Err(err).Msgf("failed creating out pipe for: %v", command)
@@ -93,6 +94,29 @@ func ExecShellWithVars(osvars map[string]string, command string, args ...string)
 
 }
 
+// ExecShellWithVarsStreams runs a command with the vars loaded like ExecShellWithVars, writing the command
+// stdout and stderr to the provided writers instead of the log
+func ExecShellWithVarsStreams(osvars map[string]string, stdout io.Writer, stderr io.Writer, command string, args ...string) error {
+
+	log.Debug().Msgf("Debug: Running %s", command)
+	RegisterSecretVars(osvars)
+	for k, v := range osvars {
+		suppressedValue := strings.Repeat("*", len(v))
+		log.Info().Msgf(" export %s = %s", k, suppressedValue)
+	}
+	cmd := exec.Command(command, args...)
+	cmd.Env = CommandEnv(osvars)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if err != nil {
+		log.Error().Err(err).Msgf("command %q failed", command)
+		return err
+	}
+	return nil
+}
+
 // Not meant to be exported, for internal use only.
 func reader(scanner *bufio.Scanner, out chan string) {
 	defer func() {